@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/GrammaTonic/experia-v10-exporter/internal/collector"
+	"github.com/GrammaTonic/experia-v10-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Exit codes of the collect subcommand.
+const (
+	exitOK = 0
+	// exitError is an invalid configuration or a failure to write the
+	// output.
+	exitError = 1
+	// exitUsage is an invalid flag, format or module name.
+	exitUsage = 2
+	// exitLoginFailed means the router rejected the login or could not be
+	// reached.
+	exitLoginFailed = 3
+	// exitScrapeErrors means the metrics were printed but some router calls
+	// failed, so families may be missing.
+	exitScrapeErrors = 4
+)
+
+// exit ends the process with a collect exit code. Tests override it.
+var exit = os.Exit
+
+// moduleList is a repeatable, comma-separated --module flag.
+type moduleList []string
+
+func (m *moduleList) String() string { return strings.Join(*m, ",") }
+
+func (m *moduleList) Set(v string) error {
+	for _, name := range strings.Split(v, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			*m = append(*m, name)
+		}
+	}
+	return nil
+}
+
+// runCollect implements "experia-v10-exporter collect": it logs in, runs
+// one collection and prints the metrics to stdout. It returns the exit code.
+func runCollect(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("collect", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(configFile, "config.file", *configFile, "Path to the YAML configuration file (default $EXPERIA_V10_CONFIG_FILE)")
+	format := fs.String("format", "text", "Output format: text (Prometheus exposition format), json or table")
+	var modules moduleList
+	fs.Var(&modules, "module", "Optional module to collect, repeatable or comma-separated (default collector.modules; \"none\" collects only the core metrics)")
+	debug := fs.Bool("debug", false, "Log every device call and response to stderr")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: experia-v10-exporter collect [flags]\n\nLogs in to the router, collects once and prints the metrics.\n\nFlags:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(stderr, "\nExit codes: 0 success, 1 configuration or output error, 2 usage error, 3 login failed, 4 some router calls failed.\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "collect: unexpected argument %q\n", fs.Arg(0))
+		return exitUsage
+	}
+	switch *format {
+	case "text", "json", "table":
+	default:
+		fmt.Fprintf(stderr, "collect: unknown format %q (want text, json or table)\n", *format)
+		return exitUsage
+	}
+
+	cfg, err := config.Load(configPath())
+	if err != nil {
+		fmt.Fprintf(stderr, "collect: %v\n", err)
+		return exitError
+	}
+	if len(modules) > 0 {
+		cfg.Collector.Modules = modules
+		if len(modules) == 1 && modules[0] == "none" {
+			cfg.Collector.Modules = nil
+		}
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintf(stderr, "collect: --module: %v\n", err)
+			return exitUsage
+		}
+	}
+	cfg.Collector.Debug = cfg.Collector.Debug || *debug
+
+	col := collector.NewCollector(net.ParseIP(cfg.Router.IP), cfg.Router.Username, cfg.Router.Password, cfg.Router.Timeout)
+	if err := applyCollectorConfig(col, cfg); err != nil {
+		fmt.Fprintf(stderr, "collect: %v\n", err)
+		return exitError
+	}
+	if err := col.Login(); err != nil {
+		fmt.Fprintf(stderr, "collect: login to %s failed: %v\n", cfg.Router.IP, err)
+		return exitLoginFailed
+	}
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(col); err != nil {
+		fmt.Fprintf(stderr, "collect: %v\n", err)
+		return exitError
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		if len(families) == 0 {
+			fmt.Fprintf(stderr, "collect: %v\n", err)
+			return exitError
+		}
+		fmt.Fprintf(stderr, "collect: warning: %v\n", err)
+	}
+	if err := writeFamilies(stdout, families, *format); err != nil {
+		fmt.Fprintf(stderr, "collect: %v\n", err)
+		return exitError
+	}
+	if loginFailed(families) {
+		fmt.Fprintf(stderr, "collect: the session was rejected during the collection\n")
+		return exitLoginFailed
+	}
+	if n := failedCalls(families); n > 0 {
+		fmt.Fprintf(stderr, "collect: %d router calls failed; rerun with --debug for details\n", n)
+		return exitScrapeErrors
+	}
+	return exitOK
+}
+
+// failedCalls returns the scrape and permission errors counted during the
+// collection.
+func failedCalls(families []*dto.MetricFamily) int {
+	n := 0
+	for _, f := range families {
+		switch f.GetName() {
+		case "experia_v10_scrape_errors_total", "experia_v10_permission_errors_total":
+			for _, m := range f.GetMetric() {
+				n += int(m.GetCounter().GetValue())
+			}
+		}
+	}
+	return n
+}
+
+// writeFamilies prints families in format: text, json or table.
+func writeFamilies(w io.Writer, families []*dto.MetricFamily, format string) error {
+	switch format {
+	case "json":
+		return writeJSON(w, families)
+	case "table":
+		return writeTable(w, families)
+	}
+	enc := expfmt.NewEncoder(w, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, f := range families {
+		if err := enc.Encode(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonFamily is a metric family in the json output.
+type jsonFamily struct {
+	Name    string       `json:"name"`
+	Help    string       `json:"help"`
+	Type    string       `json:"type"`
+	Metrics []jsonMetric `json:"metrics"`
+}
+
+type jsonMetric struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	// Value is null for NaN and infinities, which JSON cannot represent.
+	Value *float64 `json:"value"`
+}
+
+func writeJSON(w io.Writer, families []*dto.MetricFamily) error {
+	out := make([]jsonFamily, 0, len(families))
+	for _, f := range families {
+		jf := jsonFamily{Name: f.GetName(), Help: f.GetHelp(), Type: strings.ToLower(f.GetType().String())}
+		for _, m := range f.GetMetric() {
+			jm := jsonMetric{}
+			if len(m.GetLabel()) > 0 {
+				jm.Labels = map[string]string{}
+				for _, l := range m.GetLabel() {
+					jm.Labels[l.GetName()] = l.GetValue()
+				}
+			}
+			if v := metricValue(m); !math.IsNaN(v) && !math.IsInf(v, 0) {
+				jm.Value = &v
+			}
+			jf.Metrics = append(jf.Metrics, jm)
+		}
+		out = append(out, jf)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// writeTable prints one aligned row per series.
+func writeTable(w io.Writer, families []*dto.MetricFamily) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METRIC\tLABELS\tVALUE")
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			var labels []string
+			for _, l := range m.GetLabel() {
+				if l.GetValue() != "" {
+					labels = append(labels, l.GetName()+"="+strconv.Quote(l.GetValue()))
+				}
+			}
+			sort.Strings(labels)
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", f.GetName(), strings.Join(labels, " "), strconv.FormatFloat(metricValue(m), 'g', -1, 64))
+		}
+	}
+	return tw.Flush()
+}
+
+// metricValue returns the sample value of a gauge, counter or untyped
+// metric. The collector emits no histograms or summaries.
+func metricValue(m *dto.Metric) float64 {
+	switch {
+	case m.Counter != nil:
+		return m.GetCounter().GetValue()
+	case m.Gauge != nil:
+		return m.GetGauge().GetValue()
+	}
+	return m.GetUntyped().GetValue()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func collectRegistry() *prometheus.Registry {
+	rx := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "experia_v10_wan_rx_bytes_total", Help: "Received bytes"}, []string{"ifname"})
+	rx.WithLabelValues("eth4").Add(1000)
+	up := prometheus.NewGauge(prometheus.GaugeOpts{Name: "experia_v10_netdev_up", Help: "Interface up"})
+	up.Set(1)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(rx, up)
+	return reg
+}
+
+func TestWriteFamilies(t *testing.T) {
+	families, err := collectRegistry().Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var text bytes.Buffer
+	if err := writeFamilies(&text, families, "text"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.String(), "# TYPE experia_v10_wan_rx_bytes_total counter\nexperia_v10_wan_rx_bytes_total{ifname=\"eth4\"} 1000\n") {
+		t.Fatalf("unexpected text output:\n%s", text.String())
+	}
+
+	var js bytes.Buffer
+	if err := writeFamilies(&js, families, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []jsonFamily
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, js.String())
+	}
+	if len(decoded) != 2 || decoded[1].Name != "experia_v10_wan_rx_bytes_total" || decoded[1].Type != "counter" || decoded[1].Metrics[0].Labels["ifname"] != "eth4" || *decoded[1].Metrics[0].Value != 1000 {
+		t.Fatalf("unexpected JSON output:\n%s", js.String())
+	}
+
+	var table bytes.Buffer
+	if err := writeFamilies(&table, families, "table"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "METRIC") || !strings.Contains(lines[2], `ifname="eth4"`) || !strings.HasSuffix(lines[2], " 1000") {
+		t.Fatalf("unexpected table output:\n%s", table.String())
+	}
+}
+
+func TestFailedCalls(t *testing.T) {
+	errs := prometheus.NewCounter(prometheus.CounterOpts{Name: "experia_v10_scrape_errors_total", Help: "errors"})
+	errs.Add(2)
+	perms := prometheus.NewCounter(prometheus.CounterOpts{Name: "experia_v10_permission_errors_total", Help: "errors"})
+	perms.Inc()
+	reg := collectRegistry()
+	reg.MustRegister(errs, perms)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := failedCalls(families); n != 3 {
+		t.Fatalf("expected 3 failed calls, got %d", n)
+	}
+}
+
+func TestRunCollect_ExitCodes(t *testing.T) {
+	os.Setenv("EXPERIA_V10_ROUTER_IP", "127.0.0.1")
+	os.Setenv("EXPERIA_V10_TIMEOUT", "1s")
+	defer func() {
+		_ = os.Unsetenv("EXPERIA_V10_ROUTER_IP")
+		_ = os.Unsetenv("EXPERIA_V10_TIMEOUT")
+	}()
+
+	cases := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"help", []string{"-h"}, exitOK},
+		{"unknown flag", []string{"--bogus"}, exitUsage},
+		{"extra argument", []string{"now"}, exitUsage},
+		{"format", []string{"--format=yaml"}, exitUsage},
+		{"module", []string{"--module=voice,bogus"}, exitUsage},
+		{"config", []string{"--config.file=/nonexistent/experia.yml"}, exitError},
+		// Nothing listens on 127.0.0.1:80 in the test environment.
+		{"login", []string{"--module=none", "--format=json"}, exitLoginFailed},
+	}
+	for _, tc := range cases {
+		*configFile = ""
+		var stdout, stderr bytes.Buffer
+		if got := runCollect(tc.args, &stdout, &stderr); got != tc.want {
+			t.Errorf("%s: expected exit code %d, got %d (stderr: %s)", tc.name, tc.want, got, stderr.String())
+		}
+		if n := strings.Count(stderr.String(), "available:"); n > 1 {
+			t.Errorf("%s: expected the available modules to be listed once, got %s", tc.name, stderr.String())
+		}
+		if tc.want != exitOK && stdout.Len() > 0 {
+			t.Errorf("%s: expected no output on failure, got %s", tc.name, stdout.String())
+		}
+	}
+	*configFile = ""
+}
+
+func TestMain_Collect(t *testing.T) {
+	origArgs, origExit := os.Args, exit
+	defer func() { os.Args, exit = origArgs, origExit }()
+	code := -1
+	exit = func(c int) { code = c }
+	os.Args = []string{"experia-v10-exporter", "collect", "--format=xml"}
+
+	main()
+	if code != exitUsage {
+		t.Fatalf("expected main to exit with the collect exit code, got %d", code)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// maxSharedGatherAge bounds how long the push outputs reuse one gather.
+const maxSharedGatherAge = 10 * time.Second
+
+// sharedGatherer gathers g at most once per maxAge and hands the same
+// families to every caller in between. The push outputs start together and
+// usually share an interval, so together they cost one router scrape per
+// interval instead of one each. Callers must not modify the families.
+type sharedGatherer struct {
+	g      prometheus.Gatherer
+	maxAge time.Duration
+
+	// mu is held during the gather, so concurrent callers wait for it
+	// instead of scraping the router again.
+	mu       sync.Mutex
+	at       time.Time
+	families []*dto.MetricFamily
+	err      error
+}
+
+// newSharedGatherer returns a sharedGatherer for push outputs that run on
+// intervals. The families are reused for at most half the shortest interval,
+// and never longer than maxSharedGatherAge, so no output sends the same
+// samples twice.
+func newSharedGatherer(g prometheus.Gatherer, intervals ...time.Duration) *sharedGatherer {
+	maxAge := maxSharedGatherAge
+	for _, d := range intervals {
+		if d > 0 {
+			maxAge = min(maxAge, d/2)
+		}
+	}
+	return &sharedGatherer{g: g, maxAge: maxAge}
+}
+
+// Gather returns the last gather if it is recent enough, and gathers g
+// otherwise.
+func (s *sharedGatherer) Gather() ([]*dto.MetricFamily, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.at.IsZero() && time.Since(s.at) < s.maxAge {
+		return s.families, s.err
+	}
+	s.families, s.err = s.g.Gather()
+	s.at = time.Now()
+	return s.families, s.err
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestSharedGatherer(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: "experia_v10_wan_up"}))
+	calls := 0
+	g := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		calls++
+		return reg.Gather()
+	})
+	s := newSharedGatherer(g, time.Minute, 0)
+	if s.maxAge != maxSharedGatherAge {
+		t.Fatalf("expected the maximum age for long intervals, got %s", s.maxAge)
+	}
+	for i := 0; i < 3; i++ {
+		if families, err := s.Gather(); err != nil || len(families) != 1 {
+			t.Fatalf("unexpected gather %v (%v)", families, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected the outputs to share one gather, got %d", calls)
+	}
+	s.at = time.Now().Add(-maxSharedGatherAge)
+	if _, err := s.Gather(); err != nil || calls != 2 {
+		t.Fatalf("expected an expired gather to be repeated, got %d calls (%v)", calls, err)
+	}
+
+	if s := newSharedGatherer(g, time.Minute, 4*time.Second); s.maxAge != 2*time.Second {
+		t.Fatalf("expected half the shortest interval, got %s", s.maxAge)
+	}
+}
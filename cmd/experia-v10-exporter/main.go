@@ -1,53 +1,83 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
+	"maps"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/GrammaTonic/experia-v10-exporter/internal/collector"
+	"github.com/GrammaTonic/experia-v10-exporter/internal/config"
+	"github.com/GrammaTonic/experia-v10-exporter/internal/influx"
+	"github.com/GrammaTonic/experia-v10-exporter/internal/mqtt"
+	"github.com/GrammaTonic/experia-v10-exporter/internal/otlp"
+	"github.com/GrammaTonic/experia-v10-exporter/internal/remotewrite"
+	"github.com/GrammaTonic/experia-v10-exporter/internal/webconfig"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// configFile is the --config.file flag. EXPERIA_V10_CONFIG_FILE is used
+// when the flag is not set.
+var configFile = flag.String("config.file", "", "Path to the YAML configuration file (default $EXPERIA_V10_CONFIG_FILE)")
+
+// webConfigFile is the --web.config.file flag, an exporter-toolkit web
+// configuration enabling TLS and basic auth. EXPERIA_V10_WEB_CONFIG_FILE is
+// used when the flag is not set.
+var webConfigFile = flag.String("web.config.file", "", "Path to the web configuration file for TLS and basic auth (default $EXPERIA_V10_WEB_CONFIG_FILE)")
+
+// reloadConfig re-reads the configuration file and applies it to the running
+// collector. It is set by Setup and used by the SIGHUP handler in runMain.
+var reloadConfig func() error
+
+// refreshSecrets re-reads the password files of the running configuration
+// and applies a rotated password. It is set by Setup and polled by
+// watchSecrets every secretsInterval.
+var refreshSecrets func() error
+
+// secretsInterval is how often watchSecrets re-reads the password files.
+var secretsInterval = 30 * time.Second
+
 // Setup prepares the collector and HTTP handlers and returns the listen address and the created collector.
 // It does not start the HTTP server, allowing tests to call Setup without blocking.
 func Setup() (string, *collector.Experiav10Collector, error) {
-	listenAddr := os.Getenv("EXPERIA_V10_LISTEN_ADDR")
-	if listenAddr == "" {
-		listenAddr = ":9100"
-	}
-	// EXPERIA_V10_TIMEOUT is optional; default to 5s when not provided to
-	// make the container usable in CI smoke tests and local runs without an
-	// explicit .env file. If provided, validate the duration.
-	var timeout time.Duration
-	if s := os.Getenv("EXPERIA_V10_TIMEOUT"); s == "" {
-		timeout = 5 * time.Second
-	} else {
-		var err error
-		timeout, err = time.ParseDuration(s)
-		if err != nil {
-			return "", nil, fmt.Errorf("EXPERIA_V10_TIMEOUT invalid: %w", err)
-		}
+	path := configPath()
+	// The configuration file is optional: without it every setting comes
+	// from the EXPERIA_V10_* environment variables or the defaults (5s
+	// timeout, router 127.0.0.1 for CI smoke tests and local runs). The
+	// environment overrides the file.
+	cfg, err := config.Load(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	col := collector.NewCollector(net.ParseIP(cfg.Router.IP), cfg.Router.Username, cfg.Router.Password, cfg.Router.Timeout)
+	if err := applyCollectorConfig(col, cfg); err != nil {
+		return "", nil, err
 	}
-	ipStr := os.Getenv("EXPERIA_V10_ROUTER_IP")
-	if ipStr == "" {
-		// Default to localhost for CI smoke tests and local runs where a real
-		// router IP is not provided. This avoids immediate exit and allows the
-		// container to start; real deployments should set this env var.
-		ipStr = "127.0.0.1"
+	// /probe scrapes other routers with the credentials of a probe module,
+	// and only the targets that module allows. It is served only when
+	// probe_modules is configured.
+	targets, err := collector.NewTargets(probeModules(cfg))
+	if err != nil {
+		return "", nil, err
 	}
-	ip := net.ParseIP(ipStr)
-	if ip == nil {
-		return "", nil, fmt.Errorf("EXPERIA_V10_ROUTER_IP invalid")
+	// The speedtest module runs tests in the background on speedtest.interval
+	// (default 6h, 0 disables the schedule) and accepts manual runs on POST
+	// /speedtest at most once per speedtest.min_interval (default 5m).
+	speedtest := false
+	for _, m := range col.Modules() {
+		speedtest = speedtest || m == "speedtest"
 	}
-	username := os.Getenv("EXPERIA_V10_ROUTER_USERNAME")
-	password := os.Getenv("EXPERIA_V10_ROUTER_PASSWORD")
-
-	col := collector.NewCollector(ip, username, password, timeout)
 	// Attempt to login at startup so the collector reuses cookies and the
 	// session token for subsequent scrapes. Login is best-effort here; if it
 	// fails the collector will attempt to authenticate per-scrape as a
@@ -57,17 +87,333 @@ func Setup() (string, *collector.Experiav10Collector, error) {
 		// issue and the collector will retry during the first scrape.
 		log.Printf("warning: initial login failed: %v", err)
 	}
+	// events.enabled keeps an event channel open in the background so
+	// changes between scrapes are counted in experia_v10_events_total.
+	// events.handlers optionally replaces the subscribed handlers.
+	if cfg.Events.Enabled {
+		go col.RunEvents(context.Background(), cfg.Events.Handlers...)
+	}
+	if speedtest && cfg.Speedtest.Interval > 0 {
+		go col.RunSpeedtests(context.Background(), cfg.Speedtest.Interval)
+	}
 	if err := prometheus.Register(col); err != nil {
 		return "", nil, fmt.Errorf("failed to register collector: %w", err)
 	}
+	// /influx and the push outputs gather from their own registry so only the
+	// router's metrics are sent, without the Go and process collectors.
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(col); err != nil {
+		return "", nil, fmt.Errorf("failed to register collector for push outputs: %w", err)
+	}
+	// The push outputs share their gathers, so enabling several of them does
+	// not multiply the router calls.
+	push := newSharedGatherer(reg, cfg.MQTT.Interval, cfg.Influx.Interval, cfg.OTLP.Interval, cfg.RemoteWrite.Interval)
+	// mqtt.broker publishes the router state every mqtt.interval alongside
+	// /metrics.
+	var publisher *mqtt.Publisher
+	if cfg.MQTT.Broker != "" {
+		publisher = mqtt.New(push, mqttOptions(cfg))
+		go func() {
+			if err := publisher.Run(context.Background()); err != nil {
+				log.Printf("ERROR: MQTT publisher stopped: %v", err)
+			}
+		}()
+	}
+	// influx.url pushes line protocol to the InfluxDB v2 write API every
+	// influx.interval.
+	var pusher *influx.Pusher
+	if cfg.Influx.URL != "" {
+		pusher, err = influx.NewPusher(push, influxOptions(cfg))
+		if err != nil {
+			return "", nil, err
+		}
+		go func() {
+			if err := pusher.Run(context.Background()); err != nil {
+				log.Printf("ERROR: InfluxDB pusher stopped: %v", err)
+			}
+		}()
+	}
+	// otlp.endpoint exports to an OpenTelemetry receiver every
+	// otlp.interval.
+	if cfg.OTLP.Endpoint != "" {
+		opts := otlpOptions(cfg)
+		// The resource identifies the router whether or not the firmware
+		// module is enabled.
+		opts.Device = func(ctx context.Context) (otlp.Device, error) {
+			di, err := col.DeviceInfo(ctx)
+			return otlp.Device{ID: di.SerialNumber, Model: di.ModelName, Manufacturer: di.Manufacturer}, err
+		}
+		exporter, err := otlp.New(push, opts)
+		if err != nil {
+			return "", nil, err
+		}
+		go func() {
+			if err := exporter.Run(context.Background()); err != nil {
+				log.Printf("ERROR: OTLP exporter stopped: %v", err)
+			}
+		}()
+	}
+	// remote_write.url pushes samples every remote_write.interval for
+	// routers the central Prometheus cannot scrape.
+	var writer *remotewrite.Writer
+	if cfg.RemoteWrite.URL != "" {
+		writer, err = remotewrite.New(push, remoteWriteOptions(cfg))
+		if err != nil {
+			return "", nil, err
+		}
+		go func() {
+			if err := writer.Run(context.Background()); err != nil {
+				log.Printf("ERROR: remote_write stopped: %v", err)
+			}
+		}()
+	}
+
+	var mu sync.Mutex
+	current := cfg
+	// applySecrets hands rotated output secrets to the running outputs.
+	applySecrets := func(next *config.Config) {
+		if publisher != nil && publisher.SetPassword(next.MQTT.Password) {
+			log.Printf("MQTT password changed; it is used from the next connection")
+		}
+		if pusher != nil && pusher.SetToken(next.Influx.Token) {
+			log.Printf("InfluxDB token changed")
+		}
+		if writer != nil && writer.SetCredentials(next.RemoteWrite.Password, next.RemoteWrite.BearerToken) {
+			log.Printf("remote_write credentials changed")
+		}
+	}
+	// applyConfig applies next to the probe modules, the collector and the
+	// outputs. When the collector rejects next the probe modules of current
+	// are restored, so the two never mix settings of different files.
+	applyConfig := func(next *config.Config) error {
+		if err := targets.SetModules(probeModules(next)); err != nil {
+			return err
+		}
+		if err := applyCollectorConfig(col, next); err != nil {
+			if rerr := targets.SetModules(probeModules(current)); rerr != nil {
+				log.Printf("ERROR: restoring the probe modules failed: %v", rerr)
+			}
+			return err
+		}
+		applySecrets(next)
+		return nil
+	}
+	reloadConfig = func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		next, err := config.Load(path)
+		if err != nil {
+			return err
+		}
+		if err := applyConfig(next); err != nil {
+			return err
+		}
+		warnRestartRequired(current, next)
+		current = next
+		log.Printf("configuration reloaded")
+		return nil
+	}
+	refreshSecrets = func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		next := *current
+		next.ProbeModules = maps.Clone(current.ProbeModules)
+		if err := next.ReadSecrets(); err != nil {
+			return err
+		}
+		if reflect.DeepEqual(&next, current) {
+			return nil
+		}
+		if err := applyConfig(&next); err != nil {
+			return err
+		}
+		current = &next
+		return nil
+	}
 
 	http.Handle("/metrics", promhttp.Handler())
 	http.Handle("/", http.RedirectHandler("/metrics", http.StatusFound))
+	http.Handle("/-/reload", reloadHandler())
+	http.Handle("/influx", influx.Handler(reg))
+	http.Handle("/probe", targets)
+	http.Handle("/probe/ping", col.PingProbeHandler())
+	http.Handle("/probe/traceroute", col.TracerouteProbeHandler())
+	http.Handle("/api/topology", col.TopologyHandler())
+	if speedtest {
+		http.Handle("/speedtest", col.SpeedtestHandler(cfg.Speedtest.MinInterval))
+	}
+
+	return cfg.ListenAddress, col, nil
+}
+
+// applyCollectorConfig applies the settings that can change without
+// restarting. Changed credentials make the collector log in again right away;
+// otherwise the session is kept.
+func applyCollectorConfig(col *collector.Experiav10Collector, cfg *config.Config) error {
+	if err := col.SetModules(cfg.Collector.Modules...); err != nil {
+		return fmt.Errorf("collector.modules: %w", err)
+	}
+	col.SetNetdevCandidates(cfg.Collector.Interfaces...)
+	col.SetForceWANAlias(cfg.Collector.Labels.ForceWANAlias)
+	col.SetRedactPhoneNumbers(cfg.Collector.Labels.RedactPhoneNumbers)
+	col.SetSecurityRuleInfo(cfg.Collector.SecurityRuleInfo)
+	col.SetDebug(cfg.Collector.Debug)
+	if col.SetCredentials(cfg.Router.Username, cfg.Router.Password) {
+		log.Printf("router credentials changed, logging in again")
+		if err := col.Login(); err != nil {
+			// The next scrape retries, as after a failed initial login.
+			log.Printf("warning: login with the new credentials failed: %v", err)
+		}
+	}
+	return nil
+}
 
-	return listenAddr, col, nil
+// probeModules returns the /probe modules of cfg. The router credentials of
+// /metrics are never used for /probe.
+func probeModules(cfg *config.Config) map[string]collector.ProbeModule {
+	modules := make(map[string]collector.ProbeModule, len(cfg.ProbeModules))
+	for name, m := range cfg.ProbeModules {
+		modules[name] = collector.ProbeModule{Username: m.Username, Password: m.Password, Timeout: m.Timeout, Modules: m.Modules, Targets: m.Targets}
+	}
+	return modules
+}
+
+// mqttOptions returns the MQTT publisher options of cfg.
+func mqttOptions(cfg *config.Config) mqtt.Options {
+	m := cfg.MQTT
+	opts := mqtt.Options{
+		Broker:      m.Broker,
+		ClientID:    m.ClientID,
+		Username:    m.Username,
+		Password:    m.Password,
+		TopicPrefix: m.TopicPrefix,
+		Interval:    m.Interval,
+		QoS:         m.QoS,
+		Retain:      m.Retain,
+	}
+	if m.Discovery {
+		opts.DiscoveryPrefix = m.DiscoveryPrefix
+	}
+	return opts
+}
+
+// influxOptions returns the InfluxDB pusher options of cfg.
+func influxOptions(cfg *config.Config) influx.PushOptions {
+	i := cfg.Influx
+	return influx.PushOptions{URL: i.URL, Token: i.Token, Org: i.Org, Bucket: i.Bucket, Interval: i.Interval}
+}
+
+// otlpOptions returns the OTLP exporter options of cfg.
+func otlpOptions(cfg *config.Config) otlp.Options {
+	o := cfg.OTLP
+	return otlp.Options{Endpoint: o.Endpoint, Protocol: o.Protocol, Headers: o.Headers, Interval: o.Interval}
+}
+
+// remoteWriteOptions returns the remote_write options of cfg.
+func remoteWriteOptions(cfg *config.Config) remotewrite.Options {
+	r := cfg.RemoteWrite
+	return remotewrite.Options{
+		URL:            r.URL,
+		Username:       r.Username,
+		Password:       r.Password,
+		BearerToken:    r.BearerToken,
+		ExternalLabels: r.ExternalLabels,
+		Interval:       r.Interval,
+		QueueSize:      r.Queue.MaxBatches,
+		QueueDir:       r.Queue.Directory,
+		MinBackoff:     r.Queue.MinBackoff,
+		MaxBackoff:     r.Queue.MaxBackoff,
+	}
+}
+
+// warnRestartRequired logs settings that changed on reload but only take
+// effect after a restart.
+func warnRestartRequired(old, next *config.Config) {
+	if old.ListenAddress != next.ListenAddress {
+		log.Printf("warning: listen_address changed; restart the exporter to apply it")
+	}
+	if old.Router.IP != next.Router.IP || old.Router.Timeout != next.Router.Timeout {
+		log.Printf("warning: router.ip or router.timeout changed; restart the exporter to apply it")
+	}
+	if !reflect.DeepEqual(old.Events, next.Events) || old.Speedtest != next.Speedtest {
+		log.Printf("warning: events or speedtest settings changed; restart the exporter to apply them")
+	}
+	// The MQTT password is applied without a restart.
+	oldMQTT, nextMQTT := old.MQTT, next.MQTT
+	oldMQTT.Password, oldMQTT.PasswordFile = "", ""
+	nextMQTT.Password, nextMQTT.PasswordFile = "", ""
+	if oldMQTT != nextMQTT {
+		log.Printf("warning: mqtt settings changed; restart the exporter to apply them")
+	}
+	// The InfluxDB token is applied without a restart.
+	oldInflux, nextInflux := old.Influx, next.Influx
+	oldInflux.Token, oldInflux.TokenFile = "", ""
+	nextInflux.Token, nextInflux.TokenFile = "", ""
+	if oldInflux != nextInflux {
+		log.Printf("warning: influx settings changed; restart the exporter to apply them")
+	}
+	if !reflect.DeepEqual(old.OTLP, next.OTLP) {
+		log.Printf("warning: otlp settings changed; restart the exporter to apply them")
+	}
+	// The remote_write password and bearer token are applied without a
+	// restart.
+	oldRW, nextRW := old.RemoteWrite, next.RemoteWrite
+	oldRW.Password, oldRW.PasswordFile, oldRW.BearerToken, oldRW.BearerTokenFile = "", "", "", ""
+	nextRW.Password, nextRW.PasswordFile, nextRW.BearerToken, nextRW.BearerTokenFile = "", "", "", ""
+	if !reflect.DeepEqual(oldRW, nextRW) {
+		log.Printf("warning: remote_write settings changed; restart the exporter to apply them")
+	}
+}
+
+// reloadHandler serves POST /-/reload, which reloads the configuration like
+// SIGHUP does.
+func reloadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "only POST requests allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := reloadConfig(); err != nil {
+			log.Printf("ERROR: failed to reload configuration: %v", err)
+			http.Error(w, fmt.Sprintf("failed to reload configuration: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// watchSIGHUP reloads the configuration whenever the process receives SIGHUP.
+func watchSIGHUP() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		if err := reloadConfig(); err != nil {
+			log.Printf("ERROR: failed to reload configuration: %v", err)
+		}
+	}
+}
+
+// watchSecrets re-reads the password files every secretsInterval so a
+// rotated Docker or Kubernetes secret is picked up without a restart.
+func watchSecrets() {
+	t := time.NewTicker(secretsInterval)
+	defer t.Stop()
+	for range t.C {
+		if err := refreshSecrets(); err != nil {
+			log.Printf("ERROR: failed to re-read password files: %v", err)
+		}
+	}
 }
 
 func main() {
+	flag.Parse()
+	// "collect" logs in, collects once and prints the metrics instead of
+	// serving them.
+	if flag.Arg(0) == "collect" {
+		exit(runCollect(flag.Args()[1:], os.Stdout, os.Stderr))
+		return
+	}
 	if err := runMain(); err != nil {
 		exitOnError(err)
 		return
@@ -76,10 +422,27 @@ func main() {
 
 // runMain contains the testable main logic and returns an error instead of exiting.
 func runMain() error {
+	if path := textfilePath(); path != "" {
+		interval, err := textfileEvery()
+		if err != nil {
+			return err
+		}
+		return runTextfile(path, interval)
+	}
+	// Check the web configuration before logging in to the router so a
+	// broken TLS or auth setup fails the start instead of serving in the
+	// clear.
+	if path := webConfigPath(); path != "" {
+		if _, err := webconfig.Load(path); err != nil {
+			return err
+		}
+	}
 	listenAddr, _, err := Setup()
 	if err != nil {
 		return err
 	}
+	go watchSIGHUP()
+	go watchSecrets()
 	log.Printf("Listen on %s...", listenAddr)
 	if err := listenAndServe(listenAddr, nil); err != nil {
 		return err
@@ -87,8 +450,28 @@ func runMain() error {
 	return nil
 }
 
-// listenAndServe allows tests to override the real http.ListenAndServe.
-var listenAndServe = http.ListenAndServe
+// listenAndServe serves handler on addr with the web configuration. Tests
+// override it to avoid binding ports.
+var listenAndServe = func(addr string, handler http.Handler) error {
+	return webconfig.ListenAndServe(addr, handler, webConfigPath())
+}
+
+// configPath returns the --config.file flag or EXPERIA_V10_CONFIG_FILE.
+func configPath() string {
+	if *configFile != "" {
+		return *configFile
+	}
+	return os.Getenv("EXPERIA_V10_CONFIG_FILE")
+}
+
+// webConfigPath returns the --web.config.file flag or
+// EXPERIA_V10_WEB_CONFIG_FILE.
+func webConfigPath() string {
+	if *webConfigFile != "" {
+		return *webConfigFile
+	}
+	return os.Getenv("EXPERIA_V10_WEB_CONFIG_FILE")
+}
 
 // exitOnError is called when main needs to exit due to an error. Tests may override it to avoid exiting the process.
 // exitOnError is defined in a separate file so tests can override it without
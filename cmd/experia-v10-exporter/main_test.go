@@ -5,6 +5,8 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -319,3 +321,24 @@ func TestMainExitPaths(t *testing.T) {
 	}()
 	prometheus.Unregister(col)
 }
+
+func TestRunMain_InvalidWebConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "web.yml")
+	if err := os.WriteFile(path, []byte("tls_server_config:\n  cert_file: missing.crt\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("EXPERIA_V10_WEB_CONFIG_FILE", path)
+	defer func() { _ = os.Unsetenv("EXPERIA_V10_WEB_CONFIG_FILE") }()
+
+	listened := false
+	origListen := listenAndServe
+	listenAndServe = func(addr string, handler http.Handler) error { listened = true; return nil }
+	defer func() { listenAndServe = origListen }()
+
+	if err := runMain(); err == nil || !strings.Contains(err.Error(), path) {
+		t.Fatalf("expected an error naming the web config file, got %v", err)
+	}
+	if listened {
+		t.Fatalf("expected the exporter not to listen with an invalid web config")
+	}
+}
@@ -1,9 +1,15 @@
 package main
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/GrammaTonic/experia-v10-exporter/internal/config"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -29,3 +35,251 @@ func TestSetup_WithEnv(t *testing.T) {
 	// unregister to avoid global state in other tests
 	prometheus.Unregister(col)
 }
+
+func TestSetup_InvalidModules(t *testing.T) {
+	os.Setenv("EXPERIA_V10_ROUTER_IP", "127.0.0.1")
+	os.Setenv("EXPERIA_V10_MODULES", "voice,bogus")
+	defer func() {
+		_ = os.Unsetenv("EXPERIA_V10_ROUTER_IP")
+		_ = os.Unsetenv("EXPERIA_V10_MODULES")
+	}()
+
+	if _, _, err := Setup(); err == nil {
+		t.Fatalf("expected Setup to fail for an unknown module")
+	}
+}
+
+func TestSetup_InvalidSpeedtestInterval(t *testing.T) {
+	os.Setenv("EXPERIA_V10_ROUTER_IP", "127.0.0.1")
+	os.Setenv("EXPERIA_V10_SPEEDTEST_INTERVAL", "daily")
+	defer func() {
+		_ = os.Unsetenv("EXPERIA_V10_ROUTER_IP")
+		_ = os.Unsetenv("EXPERIA_V10_SPEEDTEST_INTERVAL")
+	}()
+
+	if _, _, err := Setup(); err == nil {
+		t.Fatalf("expected Setup to fail for an invalid speedtest interval")
+	}
+}
+
+func TestSetup_InvalidProbeModules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experia.yml")
+	if err := os.WriteFile(path, []byte("probe_modules:\n  fleet:\n    modules: [bogus]\n    targets: [10.0.0.0/8]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("EXPERIA_V10_ROUTER_IP", "127.0.0.1")
+	os.Setenv("EXPERIA_V10_CONFIG_FILE", path)
+	defer func() {
+		_ = os.Unsetenv("EXPERIA_V10_ROUTER_IP")
+		_ = os.Unsetenv("EXPERIA_V10_CONFIG_FILE")
+	}()
+
+	if _, _, err := Setup(); err == nil {
+		t.Fatalf("expected Setup to fail for an unknown module in a probe module")
+	}
+}
+
+func TestSetup_ConfigFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experia.yml")
+	write := func(doc string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("router:\n  ip: 127.0.0.1\n  timeout: 1s\ncollector:\n  modules: [voice]\n")
+	os.Setenv("EXPERIA_V10_CONFIG_FILE", path)
+	defer func() { _ = os.Unsetenv("EXPERIA_V10_CONFIG_FILE") }()
+	http.DefaultServeMux = http.NewServeMux()
+
+	_, col, err := Setup()
+	if err != nil {
+		t.Fatalf("Setup returned error: %v", err)
+	}
+	defer prometheus.Unregister(col)
+	if mods := col.Modules(); len(mods) != 1 || mods[0] != "voice" {
+		t.Fatalf("expected the modules from the file, got %v", mods)
+	}
+	// Without probe_modules /probe scrapes nothing, so the router
+	// credentials cannot be sent to another host.
+	probe := func() int {
+		rec := httptest.NewRecorder()
+		http.DefaultServeMux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe?target=192.0.2.1&module=fleet", nil))
+		return rec.Code
+	}
+	if code := probe(); code != http.StatusNotFound {
+		t.Fatalf("expected 404 from /probe without probe_modules, got %d", code)
+	}
+
+	reload := func(method string) int {
+		rec := httptest.NewRecorder()
+		http.DefaultServeMux.ServeHTTP(rec, httptest.NewRequest(method, "/-/reload", nil))
+		return rec.Code
+	}
+	write("router:\n  ip: 127.0.0.1\n  timeout: 1s\ncollector:\n  modules: [dns, time]\n")
+	if code := reload(http.MethodPost); code != http.StatusOK {
+		t.Fatalf("expected reload to succeed, got %d", code)
+	}
+	if mods := col.Modules(); len(mods) != 2 || mods[0] != "dns" {
+		t.Fatalf("expected the reloaded modules, got %v", mods)
+	}
+
+	// A reload can add the first probe module.
+	write("router:\n  ip: 127.0.0.1\n  timeout: 1s\ncollector:\n  modules: [dns, time]\nprobe_modules:\n  fleet:\n    targets: [198.51.100.0/24]\n")
+	if code := reload(http.MethodPost); code != http.StatusOK {
+		t.Fatalf("expected reload to succeed, got %d", code)
+	}
+	if code := probe(); code != http.StatusForbidden {
+		t.Fatalf("expected /probe to serve the reloaded module, got %d", code)
+	}
+
+	write("collector:\n  modules: [bogus]\n")
+	if code := reload(http.MethodPost); code != http.StatusInternalServerError {
+		t.Fatalf("expected an invalid file to fail the reload, got %d", code)
+	}
+	if mods := col.Modules(); len(mods) != 2 {
+		t.Fatalf("expected a failed reload to keep the modules, got %v", mods)
+	}
+	if code := reload(http.MethodGet); code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected GET to be rejected, got %d", code)
+	}
+}
+
+func TestSetup_InvalidConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experia.yml")
+	if err := os.WriteFile(path, []byte("router:\n  ip: router.local\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("EXPERIA_V10_CONFIG_FILE", path)
+	defer func() { _ = os.Unsetenv("EXPERIA_V10_CONFIG_FILE") }()
+
+	if _, _, err := Setup(); err == nil || !strings.Contains(err.Error(), "router.ip") {
+		t.Fatalf("expected a router.ip validation error, got %v", err)
+	}
+}
+
+func TestSetup_PasswordFileRotation(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "router-password")
+	if err := os.WriteFile(secret, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("EXPERIA_V10_ROUTER_IP", "127.0.0.1")
+	os.Setenv("EXPERIA_V10_TIMEOUT", "1s")
+	os.Setenv("EXPERIA_V10_ROUTER_PASSWORD_FILE", secret)
+	defer func() {
+		_ = os.Unsetenv("EXPERIA_V10_ROUTER_IP")
+		_ = os.Unsetenv("EXPERIA_V10_TIMEOUT")
+		_ = os.Unsetenv("EXPERIA_V10_ROUTER_PASSWORD_FILE")
+	}()
+	http.DefaultServeMux = http.NewServeMux()
+
+	_, col, err := Setup()
+	if err != nil {
+		t.Fatalf("Setup returned error: %v", err)
+	}
+	defer prometheus.Unregister(col)
+	if err := refreshSecrets(); err != nil {
+		t.Fatalf("expected an unchanged file to refresh cleanly, got %v", err)
+	}
+	if err := os.WriteFile(secret, []byte("second\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := refreshSecrets(); err != nil {
+		t.Fatalf("expected a rotated password to be applied, got %v", err)
+	}
+	if err := os.WriteFile(secret, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := refreshSecrets(); err == nil || !strings.Contains(err.Error(), "router.password_file") {
+		t.Fatalf("expected an empty password file to be rejected, got %v", err)
+	}
+}
+
+func TestSetup_MissingPasswordFile(t *testing.T) {
+	os.Setenv("EXPERIA_V10_ROUTER_IP", "127.0.0.1")
+	os.Setenv("EXPERIA_V10_ROUTER_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+	defer func() {
+		_ = os.Unsetenv("EXPERIA_V10_ROUTER_IP")
+		_ = os.Unsetenv("EXPERIA_V10_ROUTER_PASSWORD_FILE")
+	}()
+
+	if _, _, err := Setup(); err == nil {
+		t.Fatalf("expected Setup to fail for a missing password file")
+	}
+}
+
+func TestProbeModules(t *testing.T) {
+	cfg := config.Default()
+	cfg.Router.Username, cfg.Router.Password = "admin", "secret"
+	if modules := probeModules(cfg); len(modules) != 0 {
+		t.Fatalf("expected no implicit probe module, got %v", modules)
+	}
+	cfg.ProbeModules = map[string]config.ProbeModule{"fleet": {Username: "monitor", Targets: []string{"10.0.0.0/8"}}}
+	if m := probeModules(cfg)["fleet"]; m.Username != "monitor" || m.Password != "" || len(m.Targets) != 1 {
+		t.Fatalf("unexpected probe module %+v", m)
+	}
+}
+
+func TestMQTTOptions(t *testing.T) {
+	cfg := config.Default()
+	cfg.MQTT.Broker = "tcp://broker:1883"
+	if opts := mqttOptions(cfg); opts.DiscoveryPrefix != "homeassistant" || opts.TopicPrefix != "experia_v10" || opts.Interval != cfg.MQTT.Interval {
+		t.Fatalf("unexpected options %+v", opts)
+	}
+	cfg.MQTT.Discovery = false
+	if opts := mqttOptions(cfg); opts.DiscoveryPrefix != "" {
+		t.Fatalf("expected discovery to be disabled, got prefix %q", opts.DiscoveryPrefix)
+	}
+}
+
+func TestSetup_InfluxEndpoint(t *testing.T) {
+	http.DefaultServeMux = http.NewServeMux()
+	os.Setenv("EXPERIA_V10_ROUTER_IP", "127.0.0.1")
+	os.Setenv("EXPERIA_V10_TIMEOUT", "1s")
+	defer func() {
+		_ = os.Unsetenv("EXPERIA_V10_ROUTER_IP")
+		_ = os.Unsetenv("EXPERIA_V10_TIMEOUT")
+	}()
+
+	_, col, err := Setup()
+	if err != nil {
+		t.Fatalf("Setup returned error: %v", err)
+	}
+	defer prometheus.Unregister(col)
+
+	rec := httptest.NewRecorder()
+	http.DefaultServeMux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/influx", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "experia_v10_wan ") {
+		t.Fatalf("expected line protocol on /influx, got %d:\n%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestInfluxOptions(t *testing.T) {
+	cfg := config.Default()
+	cfg.Influx = config.Influx{URL: "http://influxdb:8086", Token: "t", Org: "home", Bucket: "router", Interval: time.Minute}
+	opts := influxOptions(cfg)
+	if opts.URL != cfg.Influx.URL || opts.Token != "t" || opts.Org != "home" || opts.Bucket != "router" || opts.Interval != time.Minute {
+		t.Fatalf("unexpected options %+v", opts)
+	}
+}
+
+func TestOTLPOptions(t *testing.T) {
+	cfg := config.Default()
+	cfg.OTLP.Endpoint = "http://otel-collector:4318"
+	cfg.OTLP.Headers = map[string]string{"api-key": "k"}
+	opts := otlpOptions(cfg)
+	if opts.Endpoint != cfg.OTLP.Endpoint || opts.Protocol != "http/protobuf" || opts.Headers["api-key"] != "k" || opts.Interval != time.Minute {
+		t.Fatalf("unexpected options %+v", opts)
+	}
+}
+
+func TestRemoteWriteOptions(t *testing.T) {
+	cfg := config.Default()
+	cfg.RemoteWrite.URL = "https://prometheus.example.com/api/v1/write"
+	cfg.RemoteWrite.ExternalLabels = map[string]string{"site": "home"}
+	opts := remoteWriteOptions(cfg)
+	if opts.URL != cfg.RemoteWrite.URL || opts.ExternalLabels["site"] != "home" || opts.QueueSize != 1440 || opts.MinBackoff != time.Second || opts.MaxBackoff != time.Minute {
+		t.Fatalf("unexpected options %+v", opts)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	"github.com/GrammaTonic/experia-v10-exporter/internal/collector"
+	"github.com/GrammaTonic/experia-v10-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// textfileOutput is the --textfile.output flag. When set the exporter does
+// not listen; it writes the metrics to the file for node_exporter's textfile
+// collector instead. EXPERIA_V10_TEXTFILE_OUTPUT is used when the flag is
+// not set.
+var textfileOutput = flag.String("textfile.output", "", "Write the metrics to this file for node_exporter's textfile collector instead of serving them (default $EXPERIA_V10_TEXTFILE_OUTPUT)")
+
+// textfileInterval is the --textfile.interval flag. 0 writes the file once
+// and exits. EXPERIA_V10_TEXTFILE_INTERVAL is used when the flag is not set.
+var textfileInterval = flag.Duration("textfile.interval", 0, "Rewrite the textfile on this interval; 0 writes it once and exits (default $EXPERIA_V10_TEXTFILE_INTERVAL)")
+
+// errLoginFailed is returned when the collection could not log in to the
+// router, so cron and systemd see a non-zero exit status.
+var errLoginFailed = errors.New("login to the router failed")
+
+// textfilePath returns the --textfile.output flag or
+// EXPERIA_V10_TEXTFILE_OUTPUT.
+func textfilePath() string {
+	if *textfileOutput != "" {
+		return *textfileOutput
+	}
+	return os.Getenv("EXPERIA_V10_TEXTFILE_OUTPUT")
+}
+
+// textfileEvery returns the --textfile.interval flag or
+// EXPERIA_V10_TEXTFILE_INTERVAL.
+func textfileEvery() (time.Duration, error) {
+	if *textfileInterval != 0 {
+		return *textfileInterval, nil
+	}
+	v := os.Getenv("EXPERIA_V10_TEXTFILE_INTERVAL")
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("EXPERIA_V10_TEXTFILE_INTERVAL invalid: %w", err)
+	}
+	return d, nil
+}
+
+// runTextfile collects the router once, or every interval, and writes the
+// metrics to path. Only the router's metrics are written, without the Go
+// and process collectors that node_exporter already exports. A single run
+// returns its error, errLoginFailed for a failed login; when looping every
+// failure is logged and the next interval tries again, so a router reboot
+// does not stop the exporter.
+func runTextfile(path string, interval time.Duration) error {
+	if interval < 0 {
+		return fmt.Errorf("textfile interval must not be negative, got %s", interval)
+	}
+	cfg, err := config.Load(configPath())
+	if err != nil {
+		return err
+	}
+	col := collector.NewCollector(net.ParseIP(cfg.Router.IP), cfg.Router.Username, cfg.Router.Password, cfg.Router.Timeout)
+	if err := applyCollectorConfig(col, cfg); err != nil {
+		return err
+	}
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(col); err != nil {
+		return err
+	}
+	for {
+		err := writeTextfile(reg, path)
+		if interval == 0 {
+			return err
+		}
+		switch {
+		case errors.Is(err, errLoginFailed):
+			log.Printf("ERROR: %v, wrote experia_v10_up 0 to %s", err, path)
+		case err != nil:
+			log.Printf("ERROR: failed to write %s: %v", path, err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// writeTextfile gathers g once and replaces path atomically. The file is
+// also written when the login failed, so experia_v10_up 0 reaches
+// Prometheus instead of stale values.
+func writeTextfile(g prometheus.Gatherer, path string) error {
+	families, err := g.Gather()
+	if err != nil && len(families) == 0 {
+		return err
+	}
+	// WriteToTextfile writes a temporary file next to path and renames it,
+	// so node_exporter never reads a partial file.
+	gathered := prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) { return families, nil })
+	if err := prometheus.WriteToTextfile(path, gathered); err != nil {
+		return err
+	}
+	if loginFailed(families) {
+		return errLoginFailed
+	}
+	return nil
+}
+
+// loginFailed reports whether the collection emitted experia_v10_up 0,
+// which the collector only does when it cannot log in.
+func loginFailed(families []*dto.MetricFamily) bool {
+	for _, f := range families {
+		if f.GetName() != "experia_v10_up" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			if m.GetGauge().GetValue() == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
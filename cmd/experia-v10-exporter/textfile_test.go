@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func gaugeRegistry(name string, v float64) *prometheus.Registry {
+	g := prometheus.NewGauge(prometheus.GaugeOpts{Name: name, Help: name})
+	g.Set(v)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(g)
+	return reg
+}
+
+func TestWriteTextfile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "experia.prom")
+	if err := os.WriteFile(path, []byte("stale\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeTextfile(gaugeRegistry("experia_v10_wan_up", 1), path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "experia_v10_wan_up 1\n") || strings.Contains(string(data), "stale") {
+		t.Fatalf("unexpected textfile:\n%s", data)
+	}
+	// The temporary file is renamed into place.
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("expected only the textfile in %s, got %d entries", dir, len(entries))
+	}
+
+	if err := writeTextfile(gaugeRegistry("experia_v10_wan_up", 1), filepath.Join(dir, "missing", "experia.prom")); err == nil {
+		t.Fatalf("expected an error for a missing directory")
+	}
+}
+
+func TestWriteTextfile_LoginFailed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experia.prom")
+	if err := writeTextfile(gaugeRegistry("experia_v10_up", 0), path); !errors.Is(err, errLoginFailed) {
+		t.Fatalf("expected errLoginFailed, got %v", err)
+	}
+	// The file is still written so Prometheus sees the failure.
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "experia_v10_up 0") {
+		t.Fatalf("expected experia_v10_up 0 in the textfile, got:\n%s", data)
+	}
+}
+
+func TestRunMain_TextfileLoginFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experia.prom")
+	os.Setenv("EXPERIA_V10_TEXTFILE_OUTPUT", path)
+	os.Setenv("EXPERIA_V10_ROUTER_IP", "127.0.0.1")
+	os.Setenv("EXPERIA_V10_TIMEOUT", "1s")
+	defer func() {
+		_ = os.Unsetenv("EXPERIA_V10_TEXTFILE_OUTPUT")
+		_ = os.Unsetenv("EXPERIA_V10_ROUTER_IP")
+		_ = os.Unsetenv("EXPERIA_V10_TIMEOUT")
+	}()
+
+	// No listener is started in textfile mode.
+	origListen := listenAndServe
+	listenAndServe = func(string, http.Handler) error {
+		t.Fatalf("textfile mode must not listen")
+		return nil
+	}
+	defer func() { listenAndServe = origListen }()
+
+	if err := runMain(); !errors.Is(err, errLoginFailed) {
+		t.Fatalf("expected errLoginFailed without a router, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "experia_v10_auth_errors_total 1") {
+		t.Fatalf("expected the failed collection in the textfile, got:\n%s", data)
+	}
+}
+
+func TestTextfileEvery(t *testing.T) {
+	os.Setenv("EXPERIA_V10_TEXTFILE_INTERVAL", "5m")
+	defer func() { _ = os.Unsetenv("EXPERIA_V10_TEXTFILE_INTERVAL") }()
+	if d, err := textfileEvery(); err != nil || d.Minutes() != 5 {
+		t.Fatalf("expected 5m, got %s (%v)", d, err)
+	}
+	os.Setenv("EXPERIA_V10_TEXTFILE_INTERVAL", "often")
+	if _, err := textfileEvery(); err == nil {
+		t.Fatalf("expected an invalid interval to be rejected")
+	}
+	if err := runTextfile("experia.prom", -1); err == nil {
+		t.Fatalf("expected a negative interval to be rejected")
+	}
+}
@@ -1,3 +1,6 @@
+// Command print-cookiejar logs in to the router and prints the session token
+// and the cookies stored for it. To check what the exporter collects, use
+// "experia-v10-exporter collect" instead.
 package main
 
 import (
@@ -9,7 +9,6 @@ import (
 	"net"
 	"net/http"
 	"net/url"
-	"os"
 	"sync"
 
 	connectivity "github.com/GrammaTonic/experia-v10-exporter/internal/collector/connectivity"
@@ -30,10 +29,9 @@ import (
 // uppercase (for example "ETH0") but exported Prometheus labels are stable
 // lowercase names derived from the candidate index (eth1, eth2...).
 //
-// The default set may be overridden at runtime using the
-// EXPERIA_EXPECT_NETDEV_IFACES environment variable. That variable should be a
-// comma-separated list of interface identifiers (for example: "eth0,eth1");
-// entries will be upper-cased before use in service payloads.
+// The default set may be overridden with SetNetdevCandidates, which
+// config.ApplyEnv feeds from the EXPERIA_EXPECT_NETDEV_IFACES environment
+// variable; entries are upper-cased before use in service payloads.
 var defaultNetdevCandidates = []string{"ETH0", "ETH1", "ETH2", "ETH3"}
 
 // apiUrl is provided via build-tag files (production in apiurl_prod.go and test override when running
@@ -53,10 +51,45 @@ type Experiav10Collector struct {
 	scrapeErrorsMetric prometheus.Counter
 	// netdevCandidates, when non-empty, overrides the package default list of
 	// interface candidates used to construct NeMo service calls. Values should
-	// be provided as uppercase identifiers (e.g. "ETH0"). The
-	// EXPERIA_EXPECT_NETDEV_IFACES environment variable still takes
-	// precedence at runtime for overriding candidates.
+	// be provided as uppercase identifiers (e.g. "ETH0").
 	netdevCandidates []string
+	// modules lists the enabled optional modules (see modules.go), sorted by
+	// name. Empty by default.
+	modules []string
+	// redactPhoneNumbers masks directory numbers in the voice module.
+	redactPhoneNumbers bool
+	// securityRuleInfo enables the per-rule series of the security module.
+	securityRuleInfo bool
+	// forceWanAlias overwrites the alias label of the detected WAN interface
+	// with "wan" and debug enables the verbose EXPERIA_E2E logging.
+	forceWanAlias bool
+	debug         bool
+	// settingsMu protects the settings a configuration reload may change:
+	// netdevCandidates, modules, redactPhoneNumbers, securityRuleInfo,
+	// forceWanAlias and debug. Collect copies them at the start of a scrape
+	// (see snapshotSettings), so a reload applies from the next scrape
+	// without waiting for the device calls of one in progress.
+	settingsMu sync.RWMutex
+	// credMu protects username and password, which a reload may change.
+	credMu sync.RWMutex
+	// lastIPv6Prefix and ipv6PrefixChanges track the delegated prefix across
+	// scrapes for ipv6_prefix_changes_total. Protected by ipv6Mu.
+	lastIPv6Prefix    string
+	ipv6PrefixChanges float64
+	ipv6Mu            sync.Mutex
+	// events is the event stream state maintained by RunEvents. Protected
+	// by eventsMu.
+	events   eventState
+	eventsMu sync.Mutex
+	// speedtest holds the results of the speedtest module.
+	speedtest speedtestState
+	// diagnostic admits one router diagnostic (ping or traceroute) at a
+	// time; the router runs them one after another.
+	diagnostic chan struct{}
+	// tracert caches traceroute probes.
+	tracert tracerouteState
+	// dyndnsLookups caches the DynDNS hostname lookups of the dyndns module.
+	dyndnsLookups dyndnsLookupState
 	// session holds the active authentication context (token). It's set by
 	// Login() at startup and refreshed on-demand. Protect with a RWMutex.
 	session   sessionContext
@@ -81,21 +114,76 @@ func NewCollector(ip net.IP, username, password string, timeout time.Duration, c
 			Name: metrics.MetricPrefix + "scrape_errors_total",
 			Help: "Counts the number of scrape errors by this collector.",
 		}),
+		diagnostic: make(chan struct{}, 1),
+		tracert: tracerouteState{
+			cache: map[string]tracerouteResult{},
+		},
 	}
 
 	// If explicit candidates passed, normalize and store them on the collector.
-	if len(candidates) > 0 {
-		list := make([]string, 0, len(candidates))
-		for _, p := range candidates {
-			if p == "" {
-				continue
-			}
-			list = append(list, strings.ToUpper(strings.TrimSpace(p)))
+	c.netdevCandidates = normalizeCandidates(candidates)
+
+	return c
+}
+
+// normalizeCandidates uppercases interface candidates and drops empty ones,
+// returning nil when none remain.
+func normalizeCandidates(candidates []string) []string {
+	var list []string
+	for _, p := range candidates {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
 		}
-		c.netdevCandidates = list
+		list = append(list, strings.ToUpper(p))
 	}
+	return list
+}
 
-	return c
+// SetNetdevCandidates replaces the NeMo interface candidates (for example
+// "ETH0"); an empty list restores the package default.
+func (c *Experiav10Collector) SetNetdevCandidates(candidates ...string) {
+	c.settingsMu.Lock()
+	defer c.settingsMu.Unlock()
+	c.netdevCandidates = normalizeCandidates(candidates)
+}
+
+// SetForceWANAlias controls whether the alias label of the detected WAN
+// interface is overwritten with "wan".
+func (c *Experiav10Collector) SetForceWANAlias(force bool) {
+	c.settingsMu.Lock()
+	defer c.settingsMu.Unlock()
+	c.forceWanAlias = force
+}
+
+// SetDebug controls the verbose per-call logging also enabled by
+// EXPERIA_E2E=1.
+func (c *Experiav10Collector) SetDebug(debug bool) {
+	c.settingsMu.Lock()
+	defer c.settingsMu.Unlock()
+	c.debug = debug
+}
+
+// SetCredentials replaces the router credentials. When they differ from the
+// current ones the session is dropped so the next call authenticates again;
+// it reports whether they changed.
+func (c *Experiav10Collector) SetCredentials(username, password string) bool {
+	c.credMu.Lock()
+	changed := username != c.username || password != c.password
+	c.username, c.password = username, password
+	c.credMu.Unlock()
+	if changed {
+		c.sessionMu.Lock()
+		c.session = sessionContext{}
+		c.sessionMu.Unlock()
+	}
+	return changed
+}
+
+// credentials returns the current router username and password.
+func (c *Experiav10Collector) credentials() (string, string) {
+	c.credMu.RLock()
+	defer c.credMu.RUnlock()
+	return c.username, c.password
 }
 
 // Login performs authentication and stores the session token on the collector.
@@ -104,7 +192,8 @@ func NewCollector(ip net.IP, username, password string, timeout time.Duration, c
 // authentication fails.
 func (c *Experiav10Collector) Login() error {
 	apiURL := fmt.Sprintf(apiUrl, c.ip.String())
-	token, err := connectivity.Authenticate(c.client, apiURL, c.username, c.password, newRequest, jsonMarshal)
+	username, password := c.credentials()
+	token, err := connectivity.Authenticate(c.client, apiURL, username, password, newRequest, jsonMarshal)
 	if err != nil {
 		return err
 	}
@@ -115,6 +204,9 @@ func (c *Experiav10Collector) Login() error {
 }
 
 func (c *Experiav10Collector) Collect(ch chan<- prometheus.Metric) {
+	// state collects data from the core calls that optional modules reuse.
+	state := &scrapeState{settings: c.snapshotSettings()}
+
 	// Use the pre-established session if available. If there is no session
 	// (empty token) attempt to authenticate on-demand; this provides a
 	// fallback for tests or runs where Login() was not invoked.
@@ -124,7 +216,8 @@ func (c *Experiav10Collector) Collect(ch chan<- prometheus.Metric) {
 	if sess.Token == "" {
 		// Try to establish a session for this scrape
 		apiURL := fmt.Sprintf(apiUrl, c.ip.String())
-		token, err := connectivity.Authenticate(c.client, apiURL, c.username, c.password, newRequest, jsonMarshal)
+		username, password := c.credentials()
+		token, err := connectivity.Authenticate(c.client, apiURL, username, password, newRequest, jsonMarshal)
 		if err != nil {
 			c.authErrorsMetric.Inc()
 			c.upMetric.Set(0)
@@ -155,78 +248,23 @@ func (c *Experiav10Collector) Collect(ch chan<- prometheus.Metric) {
 	}
 
 	// whether we're running E2E/debug mode
-	e2e := os.Getenv("EXPERIA_E2E") == "1"
+	e2e := state.settings.debug
 	// whether to force overwrite alias with "wan" when WAN MAC match detected
-	// Default: false. Only enable when EXPERIA_FORCE_WAN_ALIAS is explicitly
-	// set to "1" or "true" (case-insensitive).
-	fa := os.Getenv("EXPERIA_FORCE_WAN_ALIAS")
-	forceWanAlias := strings.EqualFold(fa, "1") || strings.EqualFold(fa, "true")
+	forceWanAlias := state.settings.forceWanAlias
 	debugLog := func(format string, args ...interface{}) {
 		if e2e {
 			log.Printf(format, args...)
 		}
 	}
 
-	// Helper for POST fetches, returns response as string. Always re-read the
-	// collector's stored session under the mutex so that Login() performed at
-	// startup is respected by subsequent scrapes.
-	postFetch := func(body string) string {
-		url := fmt.Sprintf(apiUrl, c.ip.String())
-
-		// Read the (possibly updated) session token under a read lock.
-		c.sessionMu.RLock()
-		token := c.session.Token
-		c.sessionMu.RUnlock()
-
-		headers := map[string]string{
-			"accept":          "*/*",
-			"accept-language": "en-US,en;q=0.7",
-			"content-type":    "application/x-sah-ws-4-call+json",
-			"sec-gpc":         "1",
-			"Authorization":   "X-Sah " + token,
-			"x-context":       token,
-			// Add browser-matching headers for POSTs
-			"Origin":  "http://192.168.2.254",
-			"Referer": "http://192.168.2.254/",
-		}
-
-		// Per-request context with the client's configured timeout so a
-		// single slow request does not block indefinitely.
-		reqCtx, cancel := context.WithTimeout(context.Background(), c.client.Timeout)
-		defer cancel()
-
-		resp, err := connectivity.FetchURL(c.client, reqCtx, "POST", url, headers, []byte(body))
-		if err != nil {
-			log.Printf("ERROR: failed to fetch %s: %v", body, err)
-			c.scrapeErrorsMetric.Inc()
-			return ""
-		}
-		return string(resp)
-	}
-
 	// Fetch getWANStatus and export metrics
-	wanStatusResp := postFetch(nmc.RequestBody())
+	wanStatusResp := c.postFetch(nmc.RequestBody())
 	debugLog("DEBUG: getWANStatus response length=%d", len(wanStatusResp))
 	// When running an E2E invocation, print the raw WAN JSON so we can
 	// diagnose firmware variations in the JSON schema. debugLog already
 	// checks EXPERIA_E2E, so call it directly.
 	debugLog("DEBUG RAW getWANStatus: %s", wanStatusResp)
-	var wanStatus struct {
-		Status bool `json:"status"`
-		Data   struct {
-			LinkType        string `json:"LinkType"`
-			LinkState       string `json:"LinkState"`
-			MACAddress      string `json:"MACAddress"`
-			Protocol        string `json:"Protocol"`
-			ConnectionState string `json:"ConnectionState"`
-			IPAddress       string `json:"IPAddress"`
-		} `json:"data"`
-		Errors []struct {
-			Error       int    `json:"error"`
-			Description string `json:"description"`
-			Info        string `json:"info"`
-		} `json:"errors"`
-	}
+	var wanStatus nmc.WANStatus
 	emittedWAN := false
 	if wanStatusResp != "" {
 		if err := json.Unmarshal([]byte(wanStatusResp), &wanStatus); err == nil {
@@ -257,6 +295,8 @@ func (c *Experiav10Collector) Collect(ch chan<- prometheus.Metric) {
 				wanStatus.Data.MACAddress,
 			)
 			emittedWAN = true
+			state.wanStatus = wanStatus
+			state.wanStatusOK = true
 		}
 	}
 
@@ -276,27 +316,16 @@ func (c *Experiav10Collector) Collect(ch chan<- prometheus.Metric) {
 	// metrics with lowercase ifname labels (eth0) so that consumers see the
 	// expected Linux-like names.
 
-	// Candidate interfaces (uppercase for service name); allow override via
-	// EXPERIA_EXPECT_NETDEV_IFACES (comma-separated). By default we use the
-	// collector's configured netdevCandidates (if provided) otherwise the
+	// Candidate interfaces (uppercase for service name). By default we use
+	// the collector's configured netdevCandidates (if provided) otherwise the
 	// package-level defaultNetdevCandidates.
 	var candidates []string
-	if len(c.netdevCandidates) > 0 {
-		candidates = make([]string, len(c.netdevCandidates))
-		copy(candidates, c.netdevCandidates)
+	if len(state.settings.netdevCandidates) > 0 {
+		candidates = state.settings.netdevCandidates
 	} else {
 		candidates = make([]string, len(defaultNetdevCandidates))
 		copy(candidates, defaultNetdevCandidates)
 	}
-	if env := os.Getenv("EXPERIA_EXPECT_NETDEV_IFACES"); env != "" {
-		candidates = nil
-		for _, p := range strings.Split(env, ",") {
-			pp := strings.TrimSpace(p)
-			if pp != "" {
-				candidates = append(candidates, strings.ToUpper(pp))
-			}
-		}
-	}
 
 	// ...existing code...
 
@@ -313,7 +342,7 @@ func (c *Experiav10Collector) Collect(ch chan<- prometheus.Metric) {
 		// canonical label: eth1..ethN (1-based)
 		labelName := fmt.Sprintf("eth%d", idx+1)
 		body := nemo.RequestBody(cand)
-		resp := postFetch(body)
+		resp := c.postFetch(body)
 		debugLog("DEBUG: getMIBs service=%s response length=%d", cand, len(resp))
 		// debugLog is gated by EXPERIA_E2E; print raw JSON when enabled.
 		debugLog("DEBUG RAW getMIBs service=%s: %s", cand, resp)
@@ -328,7 +357,7 @@ func (c *Experiav10Collector) Collect(ch chan<- prometheus.Metric) {
 			ch <- prometheus.MustNewConstMetric(metrics.NetdevTxQueueLen, prometheus.GaugeValue, 0.0, labelName)
 			ch <- prometheus.MustNewConstMetric(metrics.NetdevSpeedMbps, prometheus.GaugeValue, 0.0, labelName)
 			ch <- prometheus.MustNewConstMetric(metrics.NetdevLastChange, prometheus.GaugeValue, 0.0, labelName)
-			ch <- prometheus.MustNewConstMetric(metrics.NetdevInfo, prometheus.GaugeValue, 1.0, labelName, "", "", "", "")
+			ch <- prometheus.MustNewConstMetric(metrics.NetdevInfo, prometheus.GaugeValue, 1.0, labelName, "", "", "", "", "")
 			continue
 		}
 		// Use typed helpers to parse MIBs responses into a stable structure.
@@ -345,7 +374,7 @@ func (c *Experiav10Collector) Collect(ch chan<- prometheus.Metric) {
 			ch <- prometheus.MustNewConstMetric(metrics.NetdevTxQueueLen, prometheus.GaugeValue, 0.0, labelName)
 			ch <- prometheus.MustNewConstMetric(metrics.NetdevSpeedMbps, prometheus.GaugeValue, 0.0, labelName)
 			ch <- prometheus.MustNewConstMetric(metrics.NetdevLastChange, prometheus.GaugeValue, 0.0, labelName)
-			ch <- prometheus.MustNewConstMetric(metrics.NetdevInfo, prometheus.GaugeValue, 1.0, labelName, "", "", "", "")
+			ch <- prometheus.MustNewConstMetric(metrics.NetdevInfo, prometheus.GaugeValue, 1.0, labelName, "", "", "", "", "")
 			continue
 		}
 
@@ -364,6 +393,8 @@ func (c *Experiav10Collector) Collect(ch chan<- prometheus.Metric) {
 		}
 
 		flags := mi.Flags
+		flagSet := nemo.ParseFlags(mi.Flags, mi.NetDevFlags)
+		role := flagSet.Role()
 		mtu := mi.MTU
 		tx := mi.TxQueueLen
 		speed := mi.CurrentBitRate
@@ -384,7 +415,8 @@ func (c *Experiav10Collector) Collect(ch chan<- prometheus.Metric) {
 		}
 
 		// Try to detect WAN-facing interface by comparing the getWANStatus MAC
-		// to the MIBs LLAddress or by finding 'wan' substrings in aliases.
+		// to the MIBs LLAddress or by the NeMo "wan" flag. Firmware that does
+		// not report Flags falls back to finding 'wan' substrings in aliases.
 		if wanStatusResp != "" && wanLabelName == "" {
 			norm := func(mac string) string {
 				if mac == "" {
@@ -405,7 +437,10 @@ func (c *Experiav10Collector) Collect(ch chan<- prometheus.Metric) {
 			if wanMac != "" && ll != "" && wanMac == ll {
 				matched = true
 			}
-			if !matched && s != nil {
+			if !matched && role == nemo.RoleWAN {
+				matched = true
+			}
+			if !matched && mi.Flags == "" && s != nil {
 				if am, ok := s["alias"].(map[string]any); ok {
 					for _, v := range am {
 						if entry, ok := v.(map[string]any); ok {
@@ -422,7 +457,7 @@ func (c *Experiav10Collector) Collect(ch chan<- prometheus.Metric) {
 					}
 				}
 			}
-			if !matched {
+			if !matched && mi.Flags == "" {
 				if strings.Contains(strings.ToLower(mi.Alias), "wan") {
 					matched = true
 				}
@@ -447,10 +482,13 @@ func (c *Experiav10Collector) Collect(ch chan<- prometheus.Metric) {
 		ch <- prometheus.MustNewConstMetric(metrics.NetdevTxQueueLen, prometheus.GaugeValue, tx, labelName)
 		ch <- prometheus.MustNewConstMetric(metrics.NetdevSpeedMbps, prometheus.GaugeValue, speed, labelName)
 		ch <- prometheus.MustNewConstMetric(metrics.NetdevLastChange, prometheus.GaugeValue, lct, labelName)
-		ch <- prometheus.MustNewConstMetric(metrics.NetdevInfo, prometheus.GaugeValue, 1.0, labelName, alias, flags, lladdr, dtype)
+		ch <- prometheus.MustNewConstMetric(metrics.NetdevInfo, prometheus.GaugeValue, 1.0, labelName, alias, flags, lladdr, dtype, role)
+		for _, f := range nemo.ReportedFlags {
+			ch <- prometheus.MustNewConstMetric(metrics.NetdevFlag, prometheus.GaugeValue, boolToFloat(flagSet[f]), labelName, f)
+		}
 		// If this is the WAN candidate, also emit WAN-specific info and MTU
 		if wanLabelName != "" && wanLabelName == labelName {
-			ch <- prometheus.MustNewConstMetric(metrics.WanInfo, prometheus.GaugeValue, 1.0, labelName, alias, flags, lladdr, dtype)
+			ch <- prometheus.MustNewConstMetric(metrics.WanInfo, prometheus.GaugeValue, 1.0, labelName, alias, flags, lladdr, dtype, role)
 			ch <- prometheus.MustNewConstMetric(metrics.WanMtu, prometheus.GaugeValue, mtu, labelName)
 		}
 
@@ -481,7 +519,7 @@ func (c *Experiav10Collector) Collect(ch chan<- prometheus.Metric) {
 		// This mirrors the device API call:
 		// {"service":"NeMo.Intf.ETH0","method":"getNetDevStats","parameters":{}}
 		statsBody := nemo.RequestBodyStats(cand)
-		statsResp := postFetch(statsBody)
+		statsResp := c.postFetch(statsBody)
 		debugLog("DEBUG: getNetDevStats service=%s response length=%d", cand, len(statsResp))
 		debugLog("DEBUG RAW getNetDevStats service=%s: %s", cand, statsResp)
 		if statsResp == "" {
@@ -553,6 +591,55 @@ func (c *Experiav10Collector) Collect(ch chan<- prometheus.Metric) {
 		ch <- prometheus.MustNewConstMetric(metrics.WanIfname, prometheus.GaugeValue, 0.0, "")
 	}
 
+	// Optional modules run last so their calls never delay the core families.
+	c.collectModules(ch, state)
+	c.collectEvents(ch)
+}
+
+// postFetch performs an authenticated POST of body against the device API and
+// returns the response as a string. It always re-reads the collector's stored
+// session under the mutex so that Login() performed at startup is respected
+// by subsequent scrapes. Failures are logged, counted in scrape_errors_total
+// and reported as an empty string.
+func (c *Experiav10Collector) postFetch(body string) string {
+	// Per-request context with the client's configured timeout so a
+	// single slow request does not block indefinitely.
+	reqCtx, cancel := context.WithTimeout(context.Background(), c.client.Timeout)
+	defer cancel()
+
+	resp, err := c.post(reqCtx, c.client, body)
+	if err != nil {
+		log.Printf("ERROR: failed to fetch %s: %v", body, err)
+		c.scrapeErrorsMetric.Inc()
+		return ""
+	}
+	return string(resp)
+}
+
+// post sends body to the device API with the session headers using client.
+// Callers that need a different timeout than the scrape client (the event
+// long-poll) pass their own client sharing the same cookie jar.
+func (c *Experiav10Collector) post(ctx context.Context, client *http.Client, body string) ([]byte, error) {
+	url := fmt.Sprintf(apiUrl, c.ip.String())
+
+	// Read the (possibly updated) session token under a read lock.
+	c.sessionMu.RLock()
+	token := c.session.Token
+	c.sessionMu.RUnlock()
+
+	headers := map[string]string{
+		"accept":          "*/*",
+		"accept-language": "en-US,en;q=0.7",
+		"content-type":    "application/x-sah-ws-4-call+json",
+		"sec-gpc":         "1",
+		"Authorization":   "X-Sah " + token,
+		"x-context":       token,
+		// Add browser-matching headers for POSTs
+		"Origin":  "http://192.168.2.254",
+		"Referer": "http://192.168.2.254/",
+	}
+
+	return connectivity.FetchURL(client, ctx, "POST", url, headers, []byte(body))
 }
 
 // CookiesForHost returns the cookies stored in the client's jar for the
@@ -582,7 +669,8 @@ func (c *Experiav10Collector) SessionToken() string {
 // connectivity.Authenticate and returns the sessionContext on success.
 func (c *Experiav10Collector) authenticate() (sessionContext, error) {
 	apiURL := fmt.Sprintf(apiUrl, c.ip.String())
-	token, err := connectivity.Authenticate(c.client, apiURL, c.username, c.password, newRequest, jsonMarshal)
+	username, password := c.credentials()
+	token, err := connectivity.Authenticate(c.client, apiURL, username, password, newRequest, jsonMarshal)
 	if err != nil {
 		return sessionContext{}, err
 	}
@@ -592,3 +680,16 @@ func (c *Experiav10Collector) authenticate() (sessionContext, error) {
 	c.sessionMu.Unlock()
 	return sessionContext{Token: token}, nil
 }
+
+// ensureSession authenticates when there is no session yet, so on-demand
+// calls outside Collect do not send an empty token.
+func (c *Experiav10Collector) ensureSession() error {
+	if c.SessionToken() != "" {
+		return nil
+	}
+	if _, err := c.authenticate(); err != nil {
+		c.authErrorsMetric.Inc()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package collector
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GrammaTonic/experia-v10-exporter/internal/testutil"
+)
+
+func TestSetCredentials_ReauthenticatesOnChange(t *testing.T) {
+	var mu sync.Mutex
+	var passwords []string
+	c := NewCollector(net.ParseIP("127.0.0.1"), "u", "old", time.Second)
+	c.client.Transport = testutil.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Body)
+		if bytes.Contains(b, []byte("createContext")) {
+			mu.Lock()
+			switch {
+			case bytes.Contains(b, []byte(`"old"`)):
+				passwords = append(passwords, "old")
+			case bytes.Contains(b, []byte(`"new"`)):
+				passwords = append(passwords, "new")
+			}
+			mu.Unlock()
+			return testutil.MakeResp(`{"data":{"contextID":"CTX-TEST"}}`), nil
+		}
+		return testutil.MakeResp(`{"status":true}`), nil
+	})
+	if err := c.Login(); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if c.SetCredentials("u", "old") {
+		t.Fatalf("expected unchanged credentials to report no change")
+	}
+	if c.SessionToken() == "" {
+		t.Fatalf("expected the session to survive unchanged credentials")
+	}
+	if !c.SetCredentials("u", "new") || c.SessionToken() != "" {
+		t.Fatalf("expected a credential change to drop the session")
+	}
+	gatherFamilies(t, c)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(passwords) != 2 || passwords[1] != "new" {
+		t.Fatalf("expected a login with the new password, got %v", passwords)
+	}
+}
+
+func TestSettings_ConcurrentWithCollect(t *testing.T) {
+	c := newModuleTestCollector(nil)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			_ = c.SetModules("time")
+			c.SetNetdevCandidates("ETH1", "ETH2")
+			c.SetForceWANAlias(i%2 == 0)
+			c.SetRedactPhoneNumbers(true)
+			c.SetDebug(false)
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		gatherFamilies(t, c)
+	}
+	wg.Wait()
+	if mods := c.Modules(); len(mods) != 1 || mods[0] != "time" {
+		t.Fatalf("unexpected modules %v", mods)
+	}
+}
+
+func TestSetNetdevCandidates(t *testing.T) {
+	c := newModuleTestCollector(nil)
+	c.SetNetdevCandidates(" eth2 ", "", "eth3")
+	mfs := gatherFamilies(t, c)
+	if n := len(mfs["netdev_up"].GetMetric()); n != 2 {
+		t.Fatalf("expected two interfaces after SetNetdevCandidates, got %d", n)
+	}
+	c.SetNetdevCandidates()
+	mfs = gatherFamilies(t, c)
+	if n := len(mfs["netdev_up"].GetMetric()); n != len(defaultNetdevCandidates) {
+		t.Fatalf("expected the default candidates, got %d", n)
+	}
+}
+
+func TestSettings_ReloadDoesNotWaitForScrape(t *testing.T) {
+	c := NewCollector(net.ParseIP("127.0.0.1"), "u", "p", 5*time.Second)
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	c.client.Transport = testutil.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Body)
+		if bytes.Contains(b, []byte("createContext")) {
+			return testutil.MakeResp(`{"data":{"contextID":"CTX-TEST"}}`), nil
+		}
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return testutil.MakeResp(`{"status":true}`), nil
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		gatherFamilies(t, c)
+	}()
+	<-started
+
+	// The scrape is blocked in a device call; a reload must not wait for it.
+	set := make(chan error, 1)
+	go func() { set <- c.SetModules("voice") }()
+	select {
+	case err := <-set:
+		if err != nil {
+			t.Fatalf("SetModules failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("SetModules blocked on the scrape in progress")
+	}
+	close(release)
+	<-done
+}
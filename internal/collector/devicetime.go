@@ -0,0 +1,30 @@
+package collector
+
+import (
+	"time"
+
+	clockmetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/clock"
+	clock "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/clock"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// timeNow is the exporter host clock; tests replace it to get a stable skew.
+var timeNow = time.Now
+
+// collectTime exports the router clock and its skew against the host clock.
+func (c *Experiav10Collector) collectTime(_ *scrapeState, ch chan<- prometheus.Metric) {
+	resp := c.moduleFetch(clock.RequestBody())
+	if resp == nil {
+		return
+	}
+	// Sample the host clock right after the response arrived so request
+	// latency does not show up as skew.
+	now := timeNow()
+	deviceTime, err := clock.ParseTime(resp)
+	if err != nil {
+		c.moduleParseError("getTime", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(clockmetrics.DeviceTime, prometheus.GaugeValue, float64(deviceTime.Unix()))
+	ch <- prometheus.MustNewConstMetric(clockmetrics.DeviceClockSkew, prometheus.GaugeValue, deviceTime.Sub(now).Seconds())
+}
@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GrammaTonic/experia-v10-exporter/internal/testutil"
+)
+
+func TestCollectTime_DeviceTimeAndSkew(t *testing.T) {
+	orig := timeNow
+	timeNow = func() time.Time { return time.Date(2024, 5, 27, 15, 51, 27, 0, time.UTC) }
+	defer func() { timeNow = orig }()
+
+	c := newModuleTestCollector(map[string]string{
+		"getTime": `{"status":true,"data":{"time":"Mon, 27 May 2024 17:51:57 GMT+0200"}}`,
+	})
+	if err := c.SetModules("time"); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+	mfs := gatherFamilies(t, c)
+
+	dt := mfs["device_time_seconds"]
+	if dt == nil {
+		t.Fatalf("expected device_time_seconds family")
+	}
+	if got, want := dt.GetMetric()[0].GetGauge().GetValue(), float64(time.Date(2024, 5, 27, 15, 51, 57, 0, time.UTC).Unix()); got != want {
+		t.Fatalf("device_time_seconds = %v, want %v", got, want)
+	}
+	skew := mfs["device_clock_skew_seconds"]
+	if skew == nil || skew.GetMetric()[0].GetGauge().GetValue() != 30 {
+		t.Fatalf("expected device_clock_skew_seconds 30, got %v", skew)
+	}
+}
+
+func TestCollectTime_UnparseableTime(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{"getTime": `{"status":true,"data":{"time":"garbage"}}`})
+	_ = c.SetModules("time")
+	mfs := gatherFamilies(t, c)
+	if _, ok := mfs["device_time_seconds"]; ok {
+		t.Fatalf("expected no device_time_seconds for an unparseable time")
+	}
+	if testutil.ReadCounterValue(c.scrapeErrorsMetric) < 1 {
+		t.Fatalf("expected scrape_errors_total to be incremented")
+	}
+}
@@ -0,0 +1,60 @@
+package collector
+
+import (
+	dnsmetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/dns"
+	dns "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/dns"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectDNS exports the DNS mode, the upstream forwarding routes and the DNS
+// servers advertised to LAN clients over DHCP and DHCPv6.
+func (c *Experiav10Collector) collectDNS(st *scrapeState, ch chan<- prometheus.Metric) {
+	if resp := c.moduleFetch(dns.RequestBodyMode()); resp != nil {
+		if mode, err := dns.ParseMode(resp); err != nil {
+			c.moduleParseError("DNS.get", err)
+		} else {
+			ch <- prometheus.MustNewConstMetric(dnsmetrics.ModeInfo, prometheus.GaugeValue, 1.0, mode)
+		}
+	}
+
+	if resp := c.moduleFetch(dns.RequestBodyRoutes()); resp != nil {
+		if routes, err := dns.ParseRoutes(resp); err != nil {
+			c.moduleParseError("DNS.Server.Route.get", err)
+		} else {
+			for _, r := range routes {
+				ch <- prometheus.MustNewConstMetric(dnsmetrics.UpstreamServerInfo, prometheus.GaugeValue, 1.0,
+					r.Name, r.Server, dns.IPVersion(r.Server), r.Status)
+			}
+			ch <- prometheus.MustNewConstMetric(dnsmetrics.Routes, prometheus.GaugeValue, float64(len(routes)))
+		}
+	}
+
+	// The LAN bridge reports IPv4 and IPv6 DNS servers through separate calls;
+	// a server listed by both is only emitted once.
+	// getIPv6Configuration is shared with the ipv6 module.
+	seen := map[string]bool{}
+	for _, call := range []struct {
+		name  string
+		fetch func() []byte
+	}{
+		{"getIPv4", func() []byte { return c.moduleFetch(dns.RequestBodyLANIPv4()) }},
+		{"getIPv6Configuration", func() []byte { return st.lanIPv6Settings(c) }},
+	} {
+		resp := call.fetch()
+		if resp == nil {
+			continue
+		}
+		servers, err := dns.ParseLANServers(resp)
+		if err != nil {
+			c.moduleParseError(call.name, err)
+			continue
+		}
+		for _, s := range servers {
+			if seen[s] {
+				continue
+			}
+			seen[s] = true
+			ch <- prometheus.MustNewConstMetric(dnsmetrics.LANServerInfo, prometheus.GaugeValue, 1.0, s, dns.IPVersion(s))
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/GrammaTonic/experia-v10-exporter/internal/testutil"
+)
+
+func TestCollectDNS(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{
+		`"DNS","method":"get"`: `{"status":{"Mode":"Dynamic"}}`,
+		"DNS.Server.Route":     `{"status":{"dataV6_1":{"Status":"Enabled","DNS":"2a02:a47f:e000::53"},"dataV4_1":{"Status":"Enabled","DNS":"195.121.1.34"}}}`,
+		"getIPv4":              `{"status":null,"data":{"DNSServers":"192.168.2.254"}}`,
+		"getIPv6Configuration": `{"status":null,"data":{"DNSServers":"fe80::1,192.168.2.254"}}`,
+	})
+	if err := c.SetModules("dns"); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+
+	mfs := gatherFamilies(t, c)
+	if mf := mfs["dns_mode_info"]; mf == nil || labelValue(mf.GetMetric()[0], "mode") != "Dynamic" {
+		t.Fatalf("expected dns_mode_info{mode=\"Dynamic\"}")
+	}
+	if mf := mfs["dns_routes"]; mf == nil || mf.GetMetric()[0].GetGauge().GetValue() != 2 {
+		t.Fatalf("expected dns_routes 2")
+	}
+	upstream := mfs["dns_upstream_server_info"]
+	if upstream == nil || len(upstream.GetMetric()) != 2 {
+		t.Fatalf("expected two upstream servers")
+	}
+	versions := map[string]string{}
+	for _, m := range upstream.GetMetric() {
+		versions[labelValue(m, "route")] = labelValue(m, "ip_version")
+	}
+	if versions["dataV4_1"] != "ipv4" || versions["dataV6_1"] != "ipv6" {
+		t.Fatalf("unexpected upstream ip versions: %v", versions)
+	}
+	lan := mfs["dns_lan_server_info"]
+	if lan == nil || len(lan.GetMetric()) != 2 {
+		t.Fatalf("expected two distinct LAN DNS servers, got %v", lan)
+	}
+}
+
+func TestCollectDNS_ParseError(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{
+		`"DNS","method":"get"`: `{"status":null}`,
+		"DNS.Server.Route":     `not json`,
+	})
+	if err := c.SetModules("dns"); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+	mfs := gatherFamilies(t, c)
+	if mfs["dns_mode_info"] != nil || mfs["dns_routes"] != nil {
+		t.Fatalf("expected no mode or route metrics on parse errors")
+	}
+	// getIPv4 and getIPv6Configuration fall back to {"status":true}, which has
+	// no data either.
+	if got := testutil.ReadCounterValue(c.scrapeErrorsMetric); got != 4 {
+		t.Fatalf("expected 4 scrape errors, got %v", got)
+	}
+}
+
+func TestCollectDNS_SharesLANSettingsWithIPv6(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{
+		"getIPv6Configuration": `{"status":null,"data":{"Enable":true,"DNSServers":"fe80::1"}}`,
+	})
+	if err := c.SetModules("dns", "ipv6"); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+	calls := 0
+	next := c.client.Transport
+	c.client.Transport = testutil.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		if bytes.Contains(b, []byte("getIPv6Configuration")) {
+			calls++
+		}
+		return next.RoundTrip(req)
+	})
+
+	mfs := gatherFamilies(t, c)
+	if calls != 1 {
+		t.Fatalf("expected one getIPv6Configuration call per scrape, got %d", calls)
+	}
+	if mfs["ipv6_lan_enabled"].GetMetric()[0].GetGauge().GetValue() != 1 || labelValue(mfs["dns_lan_server_info"].GetMetric()[0], "server") != "fe80::1" {
+		t.Fatalf("expected both modules to read the shared response")
+	}
+}
@@ -0,0 +1,116 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	dyndnsmetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/dyndns"
+	dyndns "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/dyndns"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// lookupHost resolves a DynDNS hostname to the addresses registered for it;
+// tests replace it to avoid real DNS queries.
+var lookupHost = net.DefaultResolver.LookupHost
+
+// dyndnsLookupTTL is how long a resolved DynDNS hostname, or the failure to
+// resolve it, is reused before it is looked up again. Providers publish
+// their records with TTLs of minutes, so resolving on every scrape only adds
+// external queries.
+var dyndnsLookupTTL = 5 * time.Minute
+
+// dyndnsLookupState caches the DynDNS hostname lookups across scrapes.
+type dyndnsLookupState struct {
+	mu      sync.Mutex
+	entries map[string]dyndnsLookup
+}
+
+type dyndnsLookup struct {
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+// collectDynDNS exports the configured dynamic DNS hosts. The registered
+// address is compared with the WAN IPv4 address from getWANStatus: the router
+// reports it on firmware that includes it, otherwise the hostname is
+// resolved.
+func (c *Experiav10Collector) collectDynDNS(st *scrapeState, ch chan<- prometheus.Metric) {
+	resp := c.moduleFetch(dyndns.RequestBodyHosts())
+	if resp == nil {
+		return
+	}
+	hosts, err := dyndns.ParseHosts(resp)
+	if err != nil {
+		c.moduleParseError("DynDNS.getHosts", err)
+		return
+	}
+	for _, h := range hosts {
+		ch <- prometheus.MustNewConstMetric(dyndnsmetrics.HostStatus, prometheus.GaugeValue, boolToFloat(h.Updated()), h.Service, h.Hostname)
+		ch <- prometheus.MustNewConstMetric(dyndnsmetrics.HostInfo, prometheus.GaugeValue, 1.0,
+			h.Service, h.Hostname, h.Status, strconv.FormatBool(h.Enabled()))
+		if t := h.LastUpdateTime(); !t.IsZero() {
+			ch <- prometheus.MustNewConstMetric(dyndnsmetrics.HostLastUpdate, prometheus.GaugeValue, float64(t.Unix()), h.Service, h.Hostname)
+		}
+		if !st.wanStatusOK || st.wanStatus.Data.IPAddress == "" || !h.Enabled() {
+			continue
+		}
+		registered, err := c.registeredAddresses(h)
+		if err != nil {
+			log.Printf("WARN: could not resolve DynDNS host %s: %v", h.Hostname, err)
+			continue
+		}
+		mismatch := true
+		for _, addr := range registered {
+			if addr == st.wanStatus.Data.IPAddress {
+				mismatch = false
+				break
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(dyndnsmetrics.HostIPMismatch, prometheus.GaugeValue, boolToFloat(mismatch), h.Service, h.Hostname)
+	}
+}
+
+// registeredAddresses returns the addresses currently registered for h: the
+// address the router reports, or the resolved hostname, cached for
+// dyndnsLookupTTL.
+func (c *Experiav10Collector) registeredAddresses(h dyndns.Host) ([]string, error) {
+	if h.IPAddress != "" {
+		return []string{h.IPAddress}, nil
+	}
+	s := &c.dyndnsLookups
+	now := timeNow()
+	s.mu.Lock()
+	e, ok := s.entries[h.Hostname]
+	s.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.addrs, e.err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.client.Timeout)
+	defer cancel()
+	addrs, err := lookupHost(ctx, h.Hostname)
+	if err == nil && len(addrs) == 0 {
+		err = fmt.Errorf("no addresses")
+	}
+	if err != nil {
+		addrs = nil
+	}
+	s.mu.Lock()
+	if s.entries == nil {
+		s.entries = map[string]dyndnsLookup{}
+	}
+	for host, old := range s.entries {
+		if !now.Before(old.expires) {
+			delete(s.entries, host)
+		}
+	}
+	s.entries[h.Hostname] = dyndnsLookup{addrs: addrs, err: err, expires: now.Add(dyndnsLookupTTL)}
+	s.mu.Unlock()
+	return addrs, err
+}
@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCollectDynDNS(t *testing.T) {
+	orig := lookupHost
+	lookupHost = func(_ context.Context, host string) ([]string, error) {
+		switch host {
+		case "home.example.org":
+			return []string{"203.0.113.7"}, nil
+		case "stale.example.org":
+			return []string{"198.51.100.1"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	defer func() { lookupHost = orig }()
+
+	c := newModuleTestCollector(map[string]string{
+		"getWANStatus": `{"status":true,"data":{"ConnectionState":"Connected","IPAddress":"203.0.113.7"}}`,
+		"getHosts": `{"status":[
+			{"service":"dyndns","hostname":"home.example.org","status":"UPDATED","last_update":"2024-05-27T13:51:27Z"},
+			{"service":"No-IP","hostname":"stale.example.org","status":"ERROR","last_update":"0001-01-01T00:00:00Z"},
+			{"service":"No-IP","hostname":"gone.example.org","status":"UPDATED"}
+		]}`,
+	})
+	if err := c.SetModules("dyndns"); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+
+	mfs := gatherFamilies(t, c)
+	byHost := func(name string) map[string]float64 {
+		t.Helper()
+		mf := mfs[name]
+		if mf == nil {
+			t.Fatalf("expected %s family", name)
+		}
+		out := map[string]float64{}
+		for _, m := range mf.GetMetric() {
+			out[labelValue(m, "hostname")] = m.GetGauge().GetValue()
+		}
+		return out
+	}
+	status := byHost("dyndns_host_status")
+	if status["home.example.org"] != 1 || status["stale.example.org"] != 0 {
+		t.Fatalf("unexpected host status: %v", status)
+	}
+	last := byHost("dyndns_host_last_update_timestamp_seconds")
+	if len(last) != 1 || last["home.example.org"] != 1716817887 {
+		t.Fatalf("unexpected last update timestamps: %v", last)
+	}
+	mismatch := byHost("dyndns_host_ip_mismatch")
+	if len(mismatch) != 2 || mismatch["home.example.org"] != 0 || mismatch["stale.example.org"] != 1 {
+		t.Fatalf("unexpected mismatch values: %v", mismatch)
+	}
+}
+
+func TestCollectDynDNS_NoHosts(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{"getHosts": `{"status":[]}`})
+	if err := c.SetModules("dyndns"); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+	mfs := gatherFamilies(t, c)
+	if mfs["dyndns_host_status"] != nil || mfs["dyndns_host_ip_mismatch"] != nil {
+		t.Fatalf("expected no DynDNS series without configured hosts")
+	}
+}
+
+func TestCollectDynDNS_CachesLookups(t *testing.T) {
+	lookups := 0
+	orig := lookupHost
+	lookupHost = func(_ context.Context, host string) ([]string, error) {
+		lookups++
+		return []string{"203.0.113.7"}, nil
+	}
+	defer func() { lookupHost = orig }()
+	now := time.Date(2024, 5, 27, 15, 0, 0, 0, time.UTC)
+	origNow := timeNow
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = origNow }()
+
+	c := newModuleTestCollector(map[string]string{
+		"getWANStatus": `{"status":true,"data":{"ConnectionState":"Connected","IPAddress":"203.0.113.7"}}`,
+		"getHosts":     `{"status":[{"service":"dyndns","hostname":"home.example.org","status":"UPDATED"}]}`,
+	})
+	if err := c.SetModules("dyndns"); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+	gatherFamilies(t, c)
+	gatherFamilies(t, c)
+	if lookups != 1 {
+		t.Fatalf("expected the lookup to be reused by the second scrape, got %d lookups", lookups)
+	}
+	now = now.Add(dyndnsLookupTTL)
+	if mfs := gatherFamilies(t, c); lookups != 2 || mfs["dyndns_host_ip_mismatch"] == nil {
+		t.Fatalf("expected an expired lookup to be repeated, got %d lookups", lookups)
+	}
+}
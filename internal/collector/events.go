@@ -0,0 +1,181 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	eventmetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/events"
+	events "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/events"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Event stream timing. The device holds get_events open until events are
+// queued, so the poll timeout is independent of the scrape timeout. Tests
+// shorten the retry delays.
+var (
+	eventPollTimeout = 60 * time.Second
+	eventRetryMin    = time.Second
+	eventRetryMax    = time.Minute
+)
+
+type eventKey struct{ service, event string }
+
+// eventState is the event stream bookkeeping exported on every scrape once
+// RunEvents was started. Protected by eventsMu.
+type eventState struct {
+	running    bool
+	up         bool
+	reconnects float64
+	counts     map[eventKey]float64
+}
+
+// RunEvents keeps an eventmanager channel open for handlers (default
+// events.DefaultHandlers) until ctx is cancelled. Events are counted in
+// events_total and logged. When the channel fails it is reopened with
+// exponential backoff, re-authenticating first if the device rejected the
+// session.
+func (c *Experiav10Collector) RunEvents(ctx context.Context, handlers ...string) {
+	if len(handlers) == 0 {
+		handlers = events.DefaultHandlers
+	}
+	c.eventsMu.Lock()
+	c.events.running = true
+	if c.events.counts == nil {
+		c.events.counts = map[eventKey]float64{}
+	}
+	c.eventsMu.Unlock()
+
+	// The poll client shares transport and cookie jar with the scrape client
+	// but not its timeout.
+	poll := *c.client
+	poll.Timeout = 0
+
+	delay := eventRetryMin
+	first := true
+	for ctx.Err() == nil {
+		if !first {
+			c.eventsMu.Lock()
+			c.events.reconnects++
+			c.eventsMu.Unlock()
+		}
+		first = false
+		err := c.streamEvents(ctx, &poll, handlers, func() { delay = eventRetryMin })
+		c.setEventStreamUp(false)
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, events.ErrPermissionDenied) {
+			log.Printf("WARN: event channel rejected the session, re-authenticating")
+			if _, aerr := c.authenticate(); aerr != nil {
+				c.authErrorsMetric.Inc()
+				log.Printf("WARN: event channel re-authentication failed: %v", aerr)
+			}
+		} else {
+			log.Printf("WARN: event channel closed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > eventRetryMax {
+			delay = eventRetryMax
+		}
+	}
+}
+
+// streamEvents opens a channel and polls it until an error occurs. A poll
+// that reaches eventPollTimeout is repeated on the same channel. opened is
+// called once the channel is established.
+func (c *Experiav10Collector) streamEvents(ctx context.Context, client *http.Client, handlers []string, opened func()) error {
+	openCtx, cancel := context.WithTimeout(ctx, c.client.Timeout)
+	resp, err := c.post(openCtx, client, events.RequestBodyOpenChannel(handlers))
+	cancel()
+	if err != nil {
+		return err
+	}
+	countPermissionErrors(string(resp))
+	channelID, err := events.ParseChannelID(resp)
+	if err != nil {
+		return err
+	}
+	log.Printf("INFO: event channel %d opened for %v", channelID, handlers)
+	c.setEventStreamUp(true)
+	opened()
+
+	for {
+		pollCtx, cancel := context.WithTimeout(ctx, eventPollTimeout)
+		resp, err := c.post(pollCtx, client, events.RequestBodyGetEvents(channelID))
+		timedOut := errors.Is(pollCtx.Err(), context.DeadlineExceeded)
+		cancel()
+		if err != nil && timedOut && ctx.Err() == nil {
+			// No events were queued before the poll timeout; the channel
+			// is still open.
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		countPermissionErrors(string(resp))
+		evs, err := events.ParseEvents(resp)
+		if err != nil {
+			return err
+		}
+		for _, e := range evs {
+			c.recordEvent(e)
+		}
+	}
+}
+
+// recordEvent counts e and writes it as a key=value log line.
+func (c *Experiav10Collector) recordEvent(e events.Event) {
+	service, event := events.Classify(e)
+	c.eventsMu.Lock()
+	c.events.counts[eventKey{service, event}]++
+	c.eventsMu.Unlock()
+	if event == events.EventOther {
+		c.settingsMu.RLock()
+		debug := c.debug
+		c.settingsMu.RUnlock()
+		if debug {
+			log.Printf("DEBUG: unclassified event handler=%q reason=%q attributes=%v", e.Handler, e.Reason, e.Attributes)
+		}
+	}
+	log.Printf("EVENT: service=%q event=%q handler=%q reason=%q", service, event, e.Handler, e.Reason)
+}
+
+func (c *Experiav10Collector) setEventStreamUp(up bool) {
+	c.eventsMu.Lock()
+	c.events.up = up
+	c.eventsMu.Unlock()
+}
+
+// collectEvents exports the event stream state; nothing is emitted unless
+// RunEvents was started.
+func (c *Experiav10Collector) collectEvents(ch chan<- prometheus.Metric) {
+	c.eventsMu.Lock()
+	defer c.eventsMu.Unlock()
+	if !c.events.running {
+		return
+	}
+	keys := make([]eventKey, 0, len(c.events.counts))
+	for k := range c.events.counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].service != keys[j].service {
+			return keys[i].service < keys[j].service
+		}
+		return keys[i].event < keys[j].event
+	})
+	for _, k := range keys {
+		ch <- prometheus.MustNewConstMetric(eventmetrics.EventsTotal, prometheus.CounterValue, c.events.counts[k], k.service, k.event)
+	}
+	ch <- prometheus.MustNewConstMetric(eventmetrics.StreamUp, prometheus.GaugeValue, boolToFloat(c.events.up))
+	ch <- prometheus.MustNewConstMetric(eventmetrics.StreamReconnects, prometheus.CounterValue, c.events.reconnects)
+}
@@ -0,0 +1,185 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GrammaTonic/experia-v10-exporter/internal/testutil"
+)
+
+func TestRunEvents_CountsAndReauth(t *testing.T) {
+	origMin, origMax := eventRetryMin, eventRetryMax
+	eventRetryMin, eventRetryMax = time.Millisecond, 5*time.Millisecond
+	defer func() { eventRetryMin, eventRetryMax = origMin, origMax }()
+
+	var mu sync.Mutex
+	opens, logins, polls := 0, 0, 0
+	c := NewCollector(net.ParseIP("127.0.0.1"), "u", "p", time.Second, "ETH0")
+	c.client.Transport = testutil.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case bytes.Contains(b, []byte("createContext")):
+			logins++
+			return testutil.MakeResp(`{"data":{"contextID":"CTX-TEST"}}`), nil
+		case bytes.Contains(b, []byte("open_channel")):
+			opens++
+			if opens == 1 {
+				return testutil.MakeResp(`{"status":null,"errors":[{"error":13,"description":"Permission denied"}]}`), nil
+			}
+			return testutil.MakeResp(`{"status":{"channelid":7}}`), nil
+		case bytes.Contains(b, []byte("get_events")):
+			polls++
+			switch polls {
+			case 1:
+				return testutil.MakeResp(`{"status":[
+					{"data":{"handler":"NeMo.Intf.eth1","object":{"reason":"changed","attributes":{"Status":false}}}},
+					{"data":{"handler":"NeMo.Intf.eth1","object":{"reason":"changed","attributes":{"Status":true}}}},
+					{"data":{"handler":"NeMo.Intf.eth1","object":{"reason":"changed","attributes":{"Status":false}}}}
+				]}`), nil
+			case 2:
+				return testutil.MakeResp(`{"status":[]}`), nil
+			}
+			// Hold the long-poll until the stream is stopped.
+			mu.Unlock()
+			<-req.Context().Done()
+			mu.Lock()
+			return nil, req.Context().Err()
+		}
+		return testutil.MakeResp(`{"status":true}`), nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.RunEvents(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		p := polls
+		mu.Unlock()
+		if p >= 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("event stream did not reach the third poll")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	mfs := gatherFamilies(t, c)
+	counts := map[string]float64{}
+	for _, m := range mfs["events_total"].GetMetric() {
+		counts[labelValue(m, "service")+"/"+labelValue(m, "event")] = m.GetCounter().GetValue()
+	}
+	if counts["NeMo/link_down"] != 2 || counts["NeMo/link_up"] != 1 {
+		t.Fatalf("unexpected event counts: %v", counts)
+	}
+	if mfs["event_stream_up"].GetMetric()[0].GetGauge().GetValue() != 1 {
+		t.Fatalf("expected event stream up")
+	}
+	if mfs["event_stream_reconnects_total"].GetMetric()[0].GetCounter().GetValue() != 1 {
+		t.Fatalf("expected one reconnect after the rejected session")
+	}
+	mu.Lock()
+	if logins < 1 {
+		t.Fatalf("expected re-authentication after permission denied")
+	}
+	mu.Unlock()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("RunEvents did not stop after cancel")
+	}
+}
+
+func TestRunEvents_PollTimeoutKeepsChannel(t *testing.T) {
+	origTimeout := eventPollTimeout
+	eventPollTimeout = 10 * time.Millisecond
+	defer func() { eventPollTimeout = origTimeout }()
+
+	var mu sync.Mutex
+	opens, polls := 0, 0
+	c := NewCollector(net.ParseIP("127.0.0.1"), "u", "p", time.Second, "ETH0")
+	c.client.Transport = testutil.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case bytes.Contains(b, []byte("createContext")):
+			return testutil.MakeResp(`{"data":{"contextID":"CTX-TEST"}}`), nil
+		case bytes.Contains(b, []byte("open_channel")):
+			opens++
+			return testutil.MakeResp(`{"status":{"channelid":7}}`), nil
+		case bytes.Contains(b, []byte("get_events")):
+			polls++
+			// No events are queued: hold every poll until it times out.
+			mu.Unlock()
+			<-req.Context().Done()
+			mu.Lock()
+			return nil, req.Context().Err()
+		}
+		return testutil.MakeResp(`{"status":true}`), nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.RunEvents(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		p := polls
+		mu.Unlock()
+		if p >= 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("event stream did not reach the third poll")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	mfs := gatherFamilies(t, c)
+	if mfs["event_stream_up"].GetMetric()[0].GetGauge().GetValue() != 1 {
+		t.Fatalf("expected the event stream to stay up across empty polls")
+	}
+	if mfs["event_stream_reconnects_total"].GetMetric()[0].GetCounter().GetValue() != 0 {
+		t.Fatalf("expected no reconnect after an empty poll")
+	}
+	mu.Lock()
+	if opens != 1 {
+		t.Fatalf("expected the channel to be opened once, got %d", opens)
+	}
+	mu.Unlock()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("RunEvents did not stop after cancel")
+	}
+}
+
+func TestCollectEvents_NotStarted(t *testing.T) {
+	c := newModuleTestCollector(nil)
+	mfs := gatherFamilies(t, c)
+	if mfs["events_total"] != nil || mfs["event_stream_up"] != nil {
+		t.Fatalf("expected no event series when the stream is not running")
+	}
+}
@@ -0,0 +1,45 @@
+package collector
+
+import (
+	"context"
+
+	firmwaremetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/firmware"
+	firmware "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/firmware"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectFirmware exports the device identification and the result of the
+// router's firmware update check.
+func (c *Experiav10Collector) collectFirmware(_ *scrapeState, ch chan<- prometheus.Metric) {
+	if resp := c.moduleFetch(firmware.RequestBodyDeviceInfo()); resp != nil {
+		if di, err := firmware.ParseDeviceInfo(resp); err != nil {
+			c.moduleParseError("DeviceInfo.get", err)
+		} else {
+			ch <- prometheus.MustNewConstMetric(firmwaremetrics.DeviceInfo, prometheus.GaugeValue, 1.0,
+				di.Manufacturer, di.ModelName, di.SerialNumber, di.HardwareVersion, di.SoftwareVersion)
+			ch <- prometheus.MustNewConstMetric(firmwaremetrics.DeviceUptime, prometheus.GaugeValue, di.UpTime)
+		}
+	}
+
+	if resp := c.moduleFetch(firmware.RequestBodyLatestVersion()); resp != nil {
+		if lv, err := firmware.ParseLatestVersion(resp); err != nil {
+			c.moduleParseError("getLatestVersion", err)
+		} else {
+			ch <- prometheus.MustNewConstMetric(firmwaremetrics.UpdateAvailable, prometheus.GaugeValue, boolToFloat(lv.UpdateAvailable))
+			ch <- prometheus.MustNewConstMetric(firmwaremetrics.FirmwareInfo, prometheus.GaugeValue, 1.0, lv.CurrentVersion, lv.NewVersion)
+		}
+	}
+}
+
+// DeviceInfo reads the router's identification with DeviceInfo.get,
+// independently of the enabled modules, for outputs that describe the
+// device such as the OTLP resource.
+func (c *Experiav10Collector) DeviceInfo(ctx context.Context) (firmware.DeviceInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.client.Timeout)
+	defer cancel()
+	resp, err := c.post(ctx, c.client, firmware.RequestBodyDeviceInfo())
+	if err != nil {
+		return firmware.DeviceInfo{}, err
+	}
+	return firmware.ParseDeviceInfo(resp)
+}
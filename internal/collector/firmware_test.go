@@ -0,0 +1,51 @@
+package collector
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCollectFirmware_UpdateAndDeviceInfo(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{
+		"getLatestVersion": `{"status":true,"data":{"updateAvailable":true,"currentVersion":"24.02.04.0","newVersion":"24.10.01.0"}}`,
+		"DeviceInfo":       `{"status":{"Manufacturer":"ZTE","ModelName":"H369A","SerialNumber":"ZTEEG8GF3H15881","SoftwareVersion":"V10.C.24.01.00-D","UpTime":120}}`,
+	})
+	if err := c.SetModules("firmware"); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+	mfs := gatherFamilies(t, c)
+
+	if mf := mfs["firmware_update_available"]; mf == nil || mf.GetMetric()[0].GetGauge().GetValue() != 1 {
+		t.Fatalf("expected firmware_update_available 1")
+	}
+	info := mfs["firmware_info"]
+	if info == nil || labelValue(info.GetMetric()[0], "current") != "24.02.04.0" || labelValue(info.GetMetric()[0], "latest") != "24.10.01.0" {
+		t.Fatalf("unexpected firmware_info: %v", info)
+	}
+	dev := mfs["device_info"]
+	if dev == nil || labelValue(dev.GetMetric()[0], "software_version") != "V10.C.24.01.00-D" || labelValue(dev.GetMetric()[0], "model") != "H369A" {
+		t.Fatalf("unexpected device_info: %v", dev)
+	}
+	if mf := mfs["device_uptime_seconds"]; mf == nil || mf.GetMetric()[0].GetGauge().GetValue() != 120 {
+		t.Fatalf("expected device_uptime_seconds 120")
+	}
+}
+
+func TestCollectFirmware_StatusFalse(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{"getLatestVersion": `{"status":false,"data":{}}`})
+	_ = c.SetModules("firmware")
+	mfs := gatherFamilies(t, c)
+	if _, ok := mfs["firmware_update_available"]; ok {
+		t.Fatalf("expected no firmware_update_available when the update check fails")
+	}
+}
+
+func TestDeviceInfo_WithoutFirmwareModule(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{
+		"DeviceInfo": `{"status":{"Manufacturer":"ZTE","ModelName":"H369A","SerialNumber":"ZTEEG8GF3H15881"}}`,
+	})
+	di, err := c.DeviceInfo(context.Background())
+	if err != nil || di.SerialNumber != "ZTEEG8GF3H15881" || di.ModelName != "H369A" {
+		t.Fatalf("unexpected device info %+v (%v)", di, err)
+	}
+}
@@ -0,0 +1,69 @@
+package collector
+
+import (
+	ipv6metrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/ipv6"
+	ipv6 "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/ipv6"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectIPv6 exports IPv6 enablement, prefix delegation and LAN DHCPv6
+// state. The delegated prefix and WAN address come from the getWANStatus
+// response the core collector already fetched.
+func (c *Experiav10Collector) collectIPv6(st *scrapeState, ch chan<- prometheus.Metric) {
+	if resp := c.moduleFetch(ipv6.RequestBodyNetMaster()); resp != nil {
+		if s, err := ipv6.ParseStatus(resp); err != nil {
+			c.moduleParseError("NetMaster.get", err)
+		} else {
+			ch <- prometheus.MustNewConstMetric(ipv6metrics.Enabled, prometheus.GaugeValue, boolToFloat(s.EnableIPv6))
+			ch <- prometheus.MustNewConstMetric(ipv6metrics.ModeInfo, prometheus.GaugeValue, 1.0, s.WANMode, s.IPv6PrefixMode)
+		}
+	}
+
+	if st.wanStatusOK {
+		prefix := st.wanStatus.Data.IPv6DelegatedPrefix
+		length, ok := ipv6.PrefixLength(prefix)
+		if !ok {
+			prefix = ""
+		}
+		ch <- prometheus.MustNewConstMetric(ipv6metrics.WANAddressPresent, prometheus.GaugeValue, boolToFloat(st.wanStatus.Data.IPv6Address != ""))
+		ch <- prometheus.MustNewConstMetric(ipv6metrics.PrefixPresent, prometheus.GaugeValue, boolToFloat(ok))
+		ch <- prometheus.MustNewConstMetric(ipv6metrics.PrefixLength, prometheus.GaugeValue, float64(length))
+		ch <- prometheus.MustNewConstMetric(ipv6metrics.PrefixChanges, prometheus.CounterValue, c.observeIPv6Prefix(prefix))
+	}
+
+	if resp := c.moduleFetch(ipv6.RequestBodyPrefixDelegation()); resp != nil {
+		if pd, err := ipv6.ParsePrefixDelegation(resp); err != nil {
+			c.moduleParseError("dhcpv6_pdata get", err)
+		} else {
+			ch <- prometheus.MustNewConstMetric(ipv6metrics.PrefixDelegationBound, prometheus.GaugeValue, boolToFloat(pd.Bound()))
+			ch <- prometheus.MustNewConstMetric(ipv6metrics.PrefixDelegationUptime, prometheus.GaugeValue, pd.Uptime)
+		}
+	}
+
+	if resp := st.lanIPv6Settings(c); resp != nil {
+		if ls, err := ipv6.ParseLANSettings(resp); err != nil {
+			c.moduleParseError("getIPv6Configuration", err)
+		} else {
+			ch <- prometheus.MustNewConstMetric(ipv6metrics.LANEnabled, prometheus.GaugeValue, boolToFloat(ls.Enable))
+			ch <- prometheus.MustNewConstMetric(ipv6metrics.LANDHCPEnabled, prometheus.GaugeValue, boolToFloat(ls.DHCPEnable))
+			ch <- prometheus.MustNewConstMetric(ipv6metrics.LANDHCPIAPDEnabled, prometheus.GaugeValue, boolToFloat(ls.DHCPIAPDEnable))
+			ch <- prometheus.MustNewConstMetric(ipv6metrics.LANDHCPIANAEnabled, prometheus.GaugeValue, boolToFloat(ls.DHCPIANAEnable))
+		}
+	}
+}
+
+// observeIPv6Prefix records the delegated prefix seen in this scrape and
+// returns the number of changes observed so far. A prefix that disappears
+// and comes back unchanged is not counted; only a different non-empty prefix
+// following an earlier one is.
+func (c *Experiav10Collector) observeIPv6Prefix(prefix string) float64 {
+	c.ipv6Mu.Lock()
+	defer c.ipv6Mu.Unlock()
+	if prefix != "" {
+		if c.lastIPv6Prefix != "" && c.lastIPv6Prefix != prefix {
+			c.ipv6PrefixChanges++
+		}
+		c.lastIPv6Prefix = prefix
+	}
+	return c.ipv6PrefixChanges
+}
@@ -0,0 +1,60 @@
+package collector
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCollectIPv6_PrefixAndChanges(t *testing.T) {
+	wan := `{"status":true,"data":{"ConnectionState":"Connected","IPv6Address":"2a02:a470:c275:0:6e99:61ff:feec:1a80","IPv6DelegatedPrefix":"%s"}}`
+	responses := map[string]string{
+		"getWANStatus":              fmt.Sprintf(wan, "2a02:a470:c275::/48"),
+		`NetMaster","method":"get"`: `{"status":{"EnableIPv6":true,"IPv6PrefixMode":"DHCPv6","WANMode":"VDSL_PPP"}}`,
+		"dhcpv6_pdata":              `{"status":{"Status":true,"DHCPStatus":"Bound","Uptime":60}}`,
+		"getIPv6Configuration":      `{"status":null,"data":{"Enable":true,"DHCPEnable":true,"DHCPIAPDEnable":true,"DHCPIANAEnable":false}}`,
+	}
+	c := newModuleTestCollector(responses)
+	if err := c.SetModules("ipv6"); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+
+	mfs := gatherFamilies(t, c)
+	gauge := func(name string) float64 {
+		t.Helper()
+		mf := mfs[name]
+		if mf == nil {
+			t.Fatalf("expected %s family", name)
+		}
+		m := mf.GetMetric()[0]
+		if m.GetCounter() != nil {
+			return m.GetCounter().GetValue()
+		}
+		return m.GetGauge().GetValue()
+	}
+	if gauge("ipv6_enabled") != 1 || gauge("ipv6_prefix_present") != 1 || gauge("ipv6_prefix_length") != 48 {
+		t.Fatalf("unexpected IPv6 state")
+	}
+	if labelValue(mfs["ipv6_mode_info"].GetMetric()[0], "prefix_mode") != "DHCPv6" {
+		t.Fatalf("unexpected ipv6_mode_info labels")
+	}
+	if gauge("ipv6_prefix_delegation_bound") != 1 || gauge("ipv6_lan_dhcp_ia_na_enabled") != 0 {
+		t.Fatalf("unexpected DHCPv6 state")
+	}
+	if gauge("ipv6_prefix_changes_total") != 0 {
+		t.Fatalf("expected no prefix changes on first scrape")
+	}
+
+	// Prefix lost: no change is counted, presence drops to 0.
+	responses["getWANStatus"] = fmt.Sprintf(wan, "")
+	mfs = gatherFamilies(t, c)
+	if gauge("ipv6_prefix_present") != 0 || gauge("ipv6_prefix_changes_total") != 0 {
+		t.Fatalf("expected prefix absent without a counted change")
+	}
+
+	// A different prefix is a change.
+	responses["getWANStatus"] = fmt.Sprintf(wan, "2a02:a470:dead::/56")
+	mfs = gatherFamilies(t, c)
+	if gauge("ipv6_prefix_changes_total") != 1 || gauge("ipv6_prefix_length") != 56 {
+		t.Fatalf("expected one prefix change and length 56")
+	}
+}
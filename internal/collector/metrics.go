@@ -2,6 +2,7 @@ package collector
 
 import (
 	metrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	eventmetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/events"
 	nemo "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/nemo"
 	nmc "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/nmc"
 	"github.com/prometheus/client_golang/prometheus"
@@ -34,6 +35,7 @@ func (c *Experiav10Collector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- nemo.NetdevSpeedMbps
 	ch <- nemo.NetdevLastChange
 	ch <- nemo.NetdevInfo
+	ch <- nemo.NetdevFlag
 	// describe netdev stats
 	ch <- nemo.NetdevRxPackets
 	ch <- nemo.NetdevTxPackets
@@ -57,4 +59,8 @@ func (c *Experiav10Collector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- nemo.NetdevTxHeartbeatErrors
 	ch <- nemo.NetdevTxWindowErrors
 	metrics.PermissionErrors.Describe(ch)
+	// describe optional modules
+	describeModules(ch)
+	// describe the event stream
+	eventmetrics.Describe(ch)
 }
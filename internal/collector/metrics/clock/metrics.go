@@ -0,0 +1,25 @@
+package clock
+
+import (
+	base "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	DeviceTime = prometheus.NewDesc(
+		base.MetricPrefix+"device_time_seconds",
+		"Current time of the router clock as a Unix timestamp",
+		nil, nil)
+	// DeviceClockSkew is positive when the router clock is ahead of the
+	// exporter host. The device reports whole seconds only.
+	DeviceClockSkew = prometheus.NewDesc(
+		base.MetricPrefix+"device_clock_skew_seconds",
+		"Difference between the router clock and the exporter host clock in seconds",
+		nil, nil)
+)
+
+// Describe sends the clock descriptors to ch.
+func Describe(ch chan<- *prometheus.Desc) {
+	ch <- DeviceTime
+	ch <- DeviceClockSkew
+}
@@ -0,0 +1,28 @@
+package devices
+
+import (
+	base "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	STBUp = prometheus.NewDesc(
+		base.MetricPrefix+"stb_up",
+		"1 if the IPTV receiver is active in the device table, labels: mac, name, interface",
+		[]string{"mac", "name", "interface"}, nil)
+	STBInfo = prometheus.NewDesc(
+		base.MetricPrefix+"stb_info",
+		"IPTV receiver details (value is always 1), labels: mac, ip_address, vendor_class_id",
+		[]string{"mac", "ip_address", "vendor_class_id"}, nil)
+	STBActive = prometheus.NewDesc(
+		base.MetricPrefix+"stb_active",
+		"Number of active IPTV receivers",
+		nil, nil)
+)
+
+// Describe sends the device descriptors to ch.
+func Describe(ch chan<- *prometheus.Desc) {
+	ch <- STBUp
+	ch <- STBInfo
+	ch <- STBActive
+}
@@ -0,0 +1,34 @@
+package dns
+
+import (
+	base "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	ModeInfo = prometheus.NewDesc(
+		base.MetricPrefix+"dns_mode_info",
+		"DNS upstream mode (value is always 1), label: mode",
+		[]string{"mode"}, nil)
+	// UpstreamServerInfo has one series per DNS forwarding route.
+	UpstreamServerInfo = prometheus.NewDesc(
+		base.MetricPrefix+"dns_upstream_server_info",
+		"Upstream DNS server of a forwarding route (value is always 1), labels: route, server, ip_version, status",
+		[]string{"route", "server", "ip_version", "status"}, nil)
+	Routes = prometheus.NewDesc(
+		base.MetricPrefix+"dns_routes",
+		"Number of DNS forwarding routes",
+		nil, nil)
+	LANServerInfo = prometheus.NewDesc(
+		base.MetricPrefix+"dns_lan_server_info",
+		"DNS server advertised to LAN clients (value is always 1), labels: server, ip_version",
+		[]string{"server", "ip_version"}, nil)
+)
+
+// Describe sends the DNS descriptors to ch.
+func Describe(ch chan<- *prometheus.Desc) {
+	ch <- ModeInfo
+	ch <- UpstreamServerInfo
+	ch <- Routes
+	ch <- LANServerInfo
+}
@@ -0,0 +1,33 @@
+package dyndns
+
+import (
+	base "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	HostStatus = prometheus.NewDesc(
+		base.MetricPrefix+"dyndns_host_status",
+		"1 if the last dynamic DNS update of the host succeeded, labels: service, hostname",
+		[]string{"service", "hostname"}, nil)
+	HostInfo = prometheus.NewDesc(
+		base.MetricPrefix+"dyndns_host_info",
+		"Dynamic DNS host state as reported by the router (value is always 1), labels: service, hostname, status, enabled",
+		[]string{"service", "hostname", "status", "enabled"}, nil)
+	HostLastUpdate = prometheus.NewDesc(
+		base.MetricPrefix+"dyndns_host_last_update_timestamp_seconds",
+		"Unix time of the last dynamic DNS update of the host, labels: service, hostname",
+		[]string{"service", "hostname"}, nil)
+	HostIPMismatch = prometheus.NewDesc(
+		base.MetricPrefix+"dyndns_host_ip_mismatch",
+		"1 if the address registered for the host differs from the WAN IPv4 address, labels: service, hostname",
+		[]string{"service", "hostname"}, nil)
+)
+
+// Describe sends the DynDNS descriptors to ch.
+func Describe(ch chan<- *prometheus.Desc) {
+	ch <- HostStatus
+	ch <- HostInfo
+	ch <- HostLastUpdate
+	ch <- HostIPMismatch
+}
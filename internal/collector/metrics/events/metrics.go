@@ -0,0 +1,28 @@
+package events
+
+import (
+	base "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	EventsTotal = prometheus.NewDesc(
+		base.MetricPrefix+"events_total",
+		"Device events received on the event channel, labels: service, event",
+		[]string{"service", "event"}, nil)
+	StreamUp = prometheus.NewDesc(
+		base.MetricPrefix+"event_stream_up",
+		"1 if the event channel is open",
+		nil, nil)
+	StreamReconnects = prometheus.NewDesc(
+		base.MetricPrefix+"event_stream_reconnects_total",
+		"Number of times the event channel was reopened after an error",
+		nil, nil)
+)
+
+// Describe sends the event descriptors to ch.
+func Describe(ch chan<- *prometheus.Desc) {
+	ch <- EventsTotal
+	ch <- StreamUp
+	ch <- StreamReconnects
+}
@@ -0,0 +1,35 @@
+package firmware
+
+import (
+	base "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	UpdateAvailable = prometheus.NewDesc(
+		base.MetricPrefix+"firmware_update_available",
+		"1 if the router reports that a firmware update is available",
+		nil, nil)
+	FirmwareInfo = prometheus.NewDesc(
+		base.MetricPrefix+"firmware_info",
+		"Firmware versions reported by the update check (value is always 1), labels: current, latest",
+		[]string{"current", "latest"}, nil)
+	// DeviceInfo exposes the identification of the router so firmware
+	// versions can be compared across a fleet.
+	DeviceInfo = prometheus.NewDesc(
+		base.MetricPrefix+"device_info",
+		"Static info about the router (value is always 1), labels: manufacturer, model, serial, hardware_version, software_version",
+		[]string{"manufacturer", "model", "serial", "hardware_version", "software_version"}, nil)
+	DeviceUptime = prometheus.NewDesc(
+		base.MetricPrefix+"device_uptime_seconds",
+		"Router uptime in seconds",
+		nil, nil)
+)
+
+// Describe sends the firmware descriptors to ch.
+func Describe(ch chan<- *prometheus.Desc) {
+	ch <- UpdateAvailable
+	ch <- FirmwareInfo
+	ch <- DeviceInfo
+	ch <- DeviceUptime
+}
@@ -38,8 +38,13 @@ var (
 		[]string{"ifname"}, nil)
 	NetdevInfo = prometheus.NewDesc(
 		MetricPrefix+"netdev_info",
-		"Static info about the netdev (value is always 1), labels: alias, flags, lladdr, type",
-		[]string{"ifname", "alias", "flags", "lladdr", "type"}, nil)
+		"Static info about the netdev (value is always 1), labels: alias, flags, lladdr, type, role",
+		[]string{"ifname", "alias", "flags", "lladdr", "type", "role"}, nil)
+	// Per-interface state flags parsed from the NeMo Flags/NetDevFlags strings
+	NetdevFlag = prometheus.NewDesc(
+		MetricPrefix+"netdev_flag",
+		"1 if the NeMo flag is set on the network device, labels: flag (up, ipv4-up, ipv6-up, netdev-bound)",
+		[]string{"ifname", "flag"}, nil)
 
 	// Per-interface port parameters extracted from MIBs (current/max bitrates, duplex)
 	NetdevPortCurrentBitrate = prometheus.NewDesc(
@@ -113,11 +118,11 @@ var (
 		"1 if the WAN interface is up (value=1)",
 		[]string{"ifname"}, nil)
 
-	// WAN info similar to netdev_info (value=1), labels: alias, flags, lladdr, type
+	// WAN info similar to netdev_info (value=1), labels: alias, flags, lladdr, type, role
 	WanInfo = prometheus.NewDesc(
 		MetricPrefix+"wan_info",
-		"Static info about the WAN interface (value is always 1), labels: alias, flags, lladdr, type",
-		[]string{"ifname", "alias", "flags", "lladdr", "type"}, nil)
+		"Static info about the WAN interface (value is always 1), labels: alias, flags, lladdr, type, role",
+		[]string{"ifname", "alias", "flags", "lladdr", "type", "role"}, nil)
 
 	// WAN MTU (separate family)
 	WanMtu = prometheus.NewDesc(
@@ -0,0 +1,75 @@
+package ipv6
+
+import (
+	base "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	Enabled = prometheus.NewDesc(
+		base.MetricPrefix+"ipv6_enabled",
+		"1 if IPv6 is enabled on the router",
+		nil, nil)
+	ModeInfo = prometheus.NewDesc(
+		base.MetricPrefix+"ipv6_mode_info",
+		"WAN and IPv6 prefix modes (value is always 1), labels: wan_mode, prefix_mode",
+		[]string{"wan_mode", "prefix_mode"}, nil)
+	WANAddressPresent = prometheus.NewDesc(
+		base.MetricPrefix+"ipv6_wan_address_present",
+		"1 if the WAN interface has a global IPv6 address",
+		nil, nil)
+	PrefixPresent = prometheus.NewDesc(
+		base.MetricPrefix+"ipv6_prefix_present",
+		"1 if the ISP delegated an IPv6 prefix",
+		nil, nil)
+	PrefixLength = prometheus.NewDesc(
+		base.MetricPrefix+"ipv6_prefix_length",
+		"Length of the delegated IPv6 prefix (0 when none)",
+		nil, nil)
+	// PrefixChanges counts changes of the delegated prefix observed between
+	// scrapes of this exporter process.
+	PrefixChanges = prometheus.NewDesc(
+		base.MetricPrefix+"ipv6_prefix_changes_total",
+		"Number of delegated IPv6 prefix changes observed across scrapes",
+		nil, nil)
+	PrefixDelegationBound = prometheus.NewDesc(
+		base.MetricPrefix+"ipv6_prefix_delegation_bound",
+		"1 if the DHCPv6 prefix delegation client holds a lease",
+		nil, nil)
+	PrefixDelegationUptime = prometheus.NewDesc(
+		base.MetricPrefix+"ipv6_prefix_delegation_uptime_seconds",
+		"Uptime of the DHCPv6 prefix delegation client in seconds",
+		nil, nil)
+	LANEnabled = prometheus.NewDesc(
+		base.MetricPrefix+"ipv6_lan_enabled",
+		"1 if IPv6 is enabled on the LAN bridge",
+		nil, nil)
+	LANDHCPEnabled = prometheus.NewDesc(
+		base.MetricPrefix+"ipv6_lan_dhcp_enabled",
+		"1 if the LAN DHCPv6 server is enabled",
+		nil, nil)
+	LANDHCPIAPDEnabled = prometheus.NewDesc(
+		base.MetricPrefix+"ipv6_lan_dhcp_ia_pd_enabled",
+		"1 if the LAN DHCPv6 server hands out prefixes (IA_PD)",
+		nil, nil)
+	LANDHCPIANAEnabled = prometheus.NewDesc(
+		base.MetricPrefix+"ipv6_lan_dhcp_ia_na_enabled",
+		"1 if the LAN DHCPv6 server hands out addresses (IA_NA)",
+		nil, nil)
+)
+
+// Describe sends the IPv6 descriptors to ch.
+func Describe(ch chan<- *prometheus.Desc) {
+	ch <- Enabled
+	ch <- ModeInfo
+	ch <- WANAddressPresent
+	ch <- PrefixPresent
+	ch <- PrefixLength
+	ch <- PrefixChanges
+	ch <- PrefixDelegationBound
+	ch <- PrefixDelegationUptime
+	ch <- LANEnabled
+	ch <- LANDHCPEnabled
+	ch <- LANDHCPIAPDEnabled
+	ch <- LANDHCPIANAEnabled
+}
@@ -9,6 +9,7 @@ var (
 	NetdevSpeedMbps  = base.NetdevSpeedMbps
 	NetdevLastChange = base.NetdevLastChange
 	NetdevInfo       = base.NetdevInfo
+	NetdevFlag       = base.NetdevFlag
 
 	NetdevPortCurrentBitrate      = base.NetdevPortCurrentBitrate
 	NetdevPortMaxBitRateSupported = base.NetdevPortMaxBitRateSupported
@@ -0,0 +1,59 @@
+package probe
+
+import (
+	base "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Probe descriptors are served from a fresh registry per probe request.
+// probe_success and probe_duration_seconds follow the blackbox_exporter
+// names so existing dashboards and alerts apply.
+var (
+	Success = prometheus.NewDesc(
+		"probe_success",
+		"1 if the probe succeeded",
+		nil, nil)
+	Duration = prometheus.NewDesc(
+		"probe_duration_seconds",
+		"Time the probe took to complete",
+		nil, nil)
+	PingRTT = prometheus.NewDesc(
+		base.MetricPrefix+"ping_rtt_seconds",
+		"Round-trip time measured by the router's ping diagnostic, label: stat (min, avg, max)",
+		[]string{"stat"}, nil)
+	PingPacketsSuccess = prometheus.NewDesc(
+		base.MetricPrefix+"ping_packets_success",
+		"Echo requests answered during the ping diagnostic",
+		nil, nil)
+	PingPacketsFailed = prometheus.NewDesc(
+		base.MetricPrefix+"ping_packets_failed",
+		"Echo requests not answered during the ping diagnostic",
+		nil, nil)
+	PingPacketSize = prometheus.NewDesc(
+		base.MetricPrefix+"ping_packet_size_bytes",
+		"Size of the echo requests sent by the ping diagnostic",
+		nil, nil)
+	PingInfo = prometheus.NewDesc(
+		base.MetricPrefix+"ping_info",
+		"Outcome of the ping diagnostic (value is always 1), labels: state, ip_host",
+		[]string{"state", "ip_host"}, nil)
+)
+
+var (
+	TracerouteHopRTT = prometheus.NewDesc(
+		base.MetricPrefix+"traceroute_hop_rtt_seconds",
+		"Average round-trip time of a traceroute hop, labels: hop, host",
+		[]string{"hop", "host"}, nil)
+	TracerouteHops = prometheus.NewDesc(
+		base.MetricPrefix+"traceroute_hops",
+		"Number of hops reported by the traceroute diagnostic",
+		nil, nil)
+	TracerouteSuccess = prometheus.NewDesc(
+		base.MetricPrefix+"traceroute_success",
+		"1 if the traceroute reached its destination",
+		nil, nil)
+	TracerouteCached = prometheus.NewDesc(
+		base.MetricPrefix+"traceroute_cached",
+		"1 if the traceroute result was served from the cache",
+		nil, nil)
+)
@@ -0,0 +1,28 @@
+package scheduler
+
+import (
+	base "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	ScheduleEnabled = prometheus.NewDesc(
+		base.MetricPrefix+"device_schedule_enabled",
+		"1 if the internet access schedule of the device is enabled, labels: mac, name",
+		[]string{"mac", "name"}, nil)
+	ScheduleBlockedNow = prometheus.NewDesc(
+		base.MetricPrefix+"device_schedule_blocked_now",
+		"1 if the device's internet access is blocked at the current router time, labels: mac, name",
+		[]string{"mac", "name"}, nil)
+	ScheduleOverride = prometheus.NewDesc(
+		base.MetricPrefix+"device_schedule_override",
+		"1 if the schedule is overridden to always block (Disable) or always allow (Enable), labels: mac, name, override",
+		[]string{"mac", "name", "override"}, nil)
+)
+
+// Describe sends the schedule descriptors to ch.
+func Describe(ch chan<- *prometheus.Desc) {
+	ch <- ScheduleEnabled
+	ch <- ScheduleBlockedNow
+	ch <- ScheduleOverride
+}
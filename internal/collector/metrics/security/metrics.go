@@ -0,0 +1,56 @@
+package security
+
+import (
+	base "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	FirewallLevelInfo = prometheus.NewDesc(
+		base.MetricPrefix+"firewall_level_info",
+		"Configured firewall level (value is always 1), label: level",
+		[]string{"level"}, nil)
+	// DMZEnabled is 1 when at least one enabled DMZ host is configured.
+	DMZEnabled = prometheus.NewDesc(
+		base.MetricPrefix+"dmz_enabled",
+		"1 if a DMZ host is enabled",
+		nil, nil)
+	PortForwardingRules = prometheus.NewDesc(
+		base.MetricPrefix+"port_forwarding_rules",
+		"Number of enabled IPv4 port-forwarding rules by protocol",
+		[]string{"protocol"}, nil)
+	IPv6Pinholes = prometheus.NewDesc(
+		base.MetricPrefix+"ipv6_pinholes",
+		"Number of enabled IPv6 pinholes (open ports) by protocol",
+		[]string{"protocol"}, nil)
+	RespondToPingEnabled = prometheus.NewDesc(
+		base.MetricPrefix+"respond_to_ping_enabled",
+		"1 if the router answers ping on the WAN side for the IP version",
+		[]string{"ip_version"}, nil)
+	MACFilteringEnabled = prometheus.NewDesc(
+		base.MetricPrefix+"mac_filtering_enabled",
+		"1 if MAC filtering is active on the wireless access point",
+		[]string{"vap", "mode"}, nil)
+	MACFilteringEntries = prometheus.NewDesc(
+		base.MetricPrefix+"mac_filtering_entries",
+		"Number of MAC addresses in the access point's filter list",
+		[]string{"vap"}, nil)
+	// FirewallRuleInfo is the opt-in per-rule series covering DMZ hosts,
+	// port forwards and pinholes.
+	FirewallRuleInfo = prometheus.NewDesc(
+		base.MetricPrefix+"firewall_rule_info",
+		"Static info about a DMZ, port-forwarding or pinhole rule (value is always 1)",
+		[]string{"type", "id", "protocol", "external_port", "internal_port", "destination", "enabled"}, nil)
+)
+
+// Describe sends the security descriptors to ch.
+func Describe(ch chan<- *prometheus.Desc) {
+	ch <- FirewallLevelInfo
+	ch <- DMZEnabled
+	ch <- PortForwardingRules
+	ch <- IPv6Pinholes
+	ch <- RespondToPingEnabled
+	ch <- MACFilteringEnabled
+	ch <- MACFilteringEntries
+	ch <- FirewallRuleInfo
+}
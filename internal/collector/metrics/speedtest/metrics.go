@@ -0,0 +1,43 @@
+package speedtest
+
+import (
+	base "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	Throughput = prometheus.NewDesc(
+		base.MetricPrefix+"speedtest_throughput_bits_per_second",
+		"Throughput measured by the last successful speed test, label: direction",
+		[]string{"direction"}, nil)
+	Latency = prometheus.NewDesc(
+		base.MetricPrefix+"speedtest_latency_seconds",
+		"Latency measured by the last successful speed test, label: direction",
+		[]string{"direction"}, nil)
+	Duration = prometheus.NewDesc(
+		base.MetricPrefix+"speedtest_duration_seconds",
+		"Duration of the last successful speed test, label: direction",
+		[]string{"direction"}, nil)
+	LastRun = prometheus.NewDesc(
+		base.MetricPrefix+"speedtest_last_run_timestamp_seconds",
+		"Unix time the last successful speed test completed, label: direction",
+		[]string{"direction"}, nil)
+	Info = prometheus.NewDesc(
+		base.MetricPrefix+"speedtest_info",
+		"Server and interface of the last successful speed test (value is always 1), labels: direction, server, interface",
+		[]string{"direction", "server", "interface"}, nil)
+	Runs = prometheus.NewDesc(
+		base.MetricPrefix+"speedtest_runs_total",
+		"Speed tests run by the exporter, labels: direction, result",
+		[]string{"direction", "result"}, nil)
+)
+
+// Describe sends the speed test descriptors to ch.
+func Describe(ch chan<- *prometheus.Desc) {
+	ch <- Throughput
+	ch <- Latency
+	ch <- Duration
+	ch <- LastRun
+	ch <- Info
+	ch <- Runs
+}
@@ -0,0 +1,18 @@
+package topology
+
+import (
+	base "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	InterfaceLinkInfo = prometheus.NewDesc(
+		base.MetricPrefix+"interface_link_info",
+		"NeMo interface stack link (value is always 1): upper runs on top of lower, labels: upper, lower",
+		[]string{"upper", "lower"}, nil)
+)
+
+// Describe sends the topology descriptors to ch.
+func Describe(ch chan<- *prometheus.Desc) {
+	ch <- InterfaceLinkInfo
+}
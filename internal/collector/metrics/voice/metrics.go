@@ -0,0 +1,37 @@
+package voice
+
+import (
+	base "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// LineUp is 1 when the SIP line is registered (status "Up").
+	LineUp = prometheus.NewDesc(
+		base.MetricPrefix+"voip_line_up",
+		"1 if the SIP line is registered with the registrar",
+		[]string{"trunk", "line"}, nil)
+	LineEnabled = prometheus.NewDesc(
+		base.MetricPrefix+"voip_line_enabled",
+		"1 if the SIP line is enabled in the device configuration",
+		[]string{"trunk", "line"}, nil)
+	// LineInfo carries the line's directory number (optionally redacted) and
+	// the raw status strings reported by the device.
+	LineInfo = prometheus.NewDesc(
+		base.MetricPrefix+"voip_line_info",
+		"Static info about the SIP line (value is always 1), labels: directory_number, status, status_info",
+		[]string{"trunk", "line", "directory_number", "status", "status_info"}, nil)
+	// TrunkInfo exposes the registrar and proxy servers configured on a trunk.
+	TrunkInfo = prometheus.NewDesc(
+		base.MetricPrefix+"voip_trunk_info",
+		"Static info about the SIP trunk (value is always 1), labels: registrar, proxy, outbound_proxy",
+		[]string{"trunk", "registrar", "proxy", "outbound_proxy"}, nil)
+)
+
+// Describe sends the voice descriptors to ch.
+func Describe(ch chan<- *prometheus.Desc) {
+	ch <- LineUp
+	ch <- LineEnabled
+	ch <- LineInfo
+	ch <- TrunkInfo
+}
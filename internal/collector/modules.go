@@ -0,0 +1,216 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+
+	metrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	clockmetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/clock"
+	devicemetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/devices"
+	dnsmetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/dns"
+	dyndnsmetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/dyndns"
+	firmwaremetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/firmware"
+	ipv6metrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/ipv6"
+	schedulermetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/scheduler"
+	securitymetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/security"
+	speedtestmetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/speedtest"
+	topologymetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/topology"
+	voicemetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/voice"
+	ipv6 "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/ipv6"
+	nmc "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/nmc"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectorModule is an optional group of device calls and the metric
+// families derived from them. Modules are disabled by default so a plain
+// deployment only performs the WAN/netdev calls; they are enabled per
+// collector with SetModules.
+type collectorModule struct {
+	describe func(ch chan<- *prometheus.Desc)
+	collect  func(c *Experiav10Collector, st *scrapeState, ch chan<- prometheus.Metric)
+}
+
+// scrapeState carries data fetched by the core collector during one scrape
+// so optional modules can reuse it instead of repeating the device call.
+type scrapeState struct {
+	// settings are the reloadable settings in effect for the scrape.
+	settings scrapeSettings
+	// wanStatus is the decoded getWANStatus response; wanStatusOK is false
+	// when the call failed or could not be decoded.
+	wanStatus   nmc.WANStatus
+	wanStatusOK bool
+	// lanIPv6 is the getIPv6Configuration response of the LAN bridge, which
+	// the dns and ipv6 modules both read; see lanIPv6Settings.
+	lanIPv6        []byte
+	lanIPv6Fetched bool
+}
+
+// lanIPv6Settings returns the getIPv6Configuration response of the LAN
+// bridge, calling the device only the first time in a scrape. nil means the
+// call failed.
+func (st *scrapeState) lanIPv6Settings(c *Experiav10Collector) []byte {
+	if !st.lanIPv6Fetched {
+		st.lanIPv6 = c.moduleFetch(ipv6.RequestBodyLANSettings())
+		st.lanIPv6Fetched = true
+	}
+	return st.lanIPv6
+}
+
+// scrapeSettings is a copy of the settings protected by settingsMu, taken at
+// the start of a scrape so the lock is not held during device calls.
+type scrapeSettings struct {
+	netdevCandidates   []string
+	modules            []string
+	redactPhoneNumbers bool
+	securityRuleInfo   bool
+	forceWanAlias      bool
+	debug              bool
+}
+
+// snapshotSettings returns a copy of the current reloadable settings.
+func (c *Experiav10Collector) snapshotSettings() scrapeSettings {
+	c.settingsMu.RLock()
+	defer c.settingsMu.RUnlock()
+	return scrapeSettings{
+		netdevCandidates:   slices.Clone(c.netdevCandidates),
+		modules:            slices.Clone(c.modules),
+		redactPhoneNumbers: c.redactPhoneNumbers,
+		securityRuleInfo:   c.securityRuleInfo,
+		forceWanAlias:      c.forceWanAlias,
+		debug:              c.debug,
+	}
+}
+
+// availableModules maps module names to their implementation.
+var availableModules = map[string]collectorModule{
+	"dns":       {describe: dnsmetrics.Describe, collect: (*Experiav10Collector).collectDNS},
+	"dyndns":    {describe: dyndnsmetrics.Describe, collect: (*Experiav10Collector).collectDynDNS},
+	"firmware":  {describe: firmwaremetrics.Describe, collect: (*Experiav10Collector).collectFirmware},
+	"ipv6":      {describe: ipv6metrics.Describe, collect: (*Experiav10Collector).collectIPv6},
+	"schedules": {describe: schedulermetrics.Describe, collect: (*Experiav10Collector).collectSchedules},
+	"security":  {describe: securitymetrics.Describe, collect: (*Experiav10Collector).collectSecurity},
+	"speedtest": {describe: speedtestmetrics.Describe, collect: (*Experiav10Collector).collectSpeedtest},
+	"stb":       {describe: devicemetrics.Describe, collect: (*Experiav10Collector).collectSTB},
+	"time":      {describe: clockmetrics.Describe, collect: (*Experiav10Collector).collectTime},
+	"topology":  {describe: topologymetrics.Describe, collect: (*Experiav10Collector).collectTopology},
+	"voice":     {describe: voicemetrics.Describe, collect: (*Experiav10Collector).collectVoice},
+}
+
+// ModuleNames returns the sorted names of all optional collector modules.
+func ModuleNames() []string {
+	names := make([]string, 0, len(availableModules))
+	for name := range availableModules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetModules enables the named optional modules, replacing any previously
+// enabled set. Names are case-insensitive; an unknown name is an error and
+// leaves the current set unchanged.
+func (c *Experiav10Collector) SetModules(names ...string) error {
+	enabled, err := normalizeModules(names)
+	if err != nil {
+		return err
+	}
+	c.settingsMu.Lock()
+	defer c.settingsMu.Unlock()
+	c.modules = enabled
+	return nil
+}
+
+// normalizeModules lowercases, deduplicates and sorts module names, returning
+// an error for an unknown name.
+func normalizeModules(names []string) ([]string, error) {
+	enabled := make([]string, 0, len(names))
+	seen := map[string]bool{}
+	for _, n := range names {
+		name := strings.ToLower(strings.TrimSpace(n))
+		if name == "" || seen[name] {
+			continue
+		}
+		if _, ok := availableModules[name]; !ok {
+			return nil, fmt.Errorf("unknown module %q (available: %s)", name, strings.Join(ModuleNames(), ", "))
+		}
+		seen[name] = true
+		enabled = append(enabled, name)
+	}
+	sort.Strings(enabled)
+	return enabled, nil
+}
+
+// Modules returns the names of the enabled optional modules.
+func (c *Experiav10Collector) Modules() []string {
+	c.settingsMu.RLock()
+	defer c.settingsMu.RUnlock()
+	out := make([]string, len(c.modules))
+	copy(out, c.modules)
+	return out
+}
+
+// describeModules sends the descriptors of every optional module. All
+// modules are described regardless of whether they are enabled so the set
+// of descriptors does not depend on runtime configuration.
+func describeModules(ch chan<- *prometheus.Desc) {
+	for _, name := range ModuleNames() {
+		availableModules[name].describe(ch)
+	}
+}
+
+// collectModules runs the enabled optional modules in name order.
+func (c *Experiav10Collector) collectModules(ch chan<- prometheus.Metric, st *scrapeState) {
+	for _, name := range st.settings.modules {
+		if m, ok := availableModules[name]; ok {
+			m.collect(c, st, ch)
+		}
+	}
+}
+
+// moduleFetch performs a module call and returns the raw response, or nil
+// when the call failed. Permission errors reported by the device are counted.
+func (c *Experiav10Collector) moduleFetch(body string) []byte {
+	resp := c.postFetch(body)
+	if resp == "" {
+		return nil
+	}
+	countPermissionErrors(resp)
+	return []byte(resp)
+}
+
+// moduleParseError logs and counts a module response that could not be
+// decoded.
+func (c *Experiav10Collector) moduleParseError(call string, err error) {
+	log.Printf("ERROR: failed to parse %s response: %v", call, err)
+	c.scrapeErrorsMetric.Inc()
+}
+
+// countPermissionErrors increments permission_errors_total for every
+// "Permission denied" entry in the errors array of a device response.
+func countPermissionErrors(resp string) {
+	var env struct {
+		Errors []struct {
+			Description string `json:"description"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(resp), &env); err != nil {
+		return
+	}
+	for _, e := range env.Errors {
+		if e.Description == "Permission denied" {
+			metrics.PermissionErrors.Inc()
+		}
+	}
+}
+
+// boolToFloat converts a boolean into the 0/1 value used by gauge metrics.
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1.0
+	}
+	return 0.0
+}
@@ -0,0 +1,144 @@
+package collector
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	probemetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/probe"
+	diagnostics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/diagnostics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// defaultProbeTimeout bounds a probe when Prometheus does not send its scrape
+// timeout. Router diagnostics send several packets and take seconds.
+var defaultProbeTimeout = 30 * time.Second
+
+// probeTargetRe matches host names and IPv4/IPv6 literals; anything else is
+// rejected before it reaches the router.
+var probeTargetRe = regexp.MustCompile(`^[A-Za-z0-9.:-]{1,253}$`)
+
+// probeIPVersions maps accepted ipversion values to the router's spelling.
+var probeIPVersions = map[string]string{"any": "Any", "ipv4": "IPv4", "ipv6": "IPv6"}
+
+// metricsCollector serves a fixed set of metrics built for one probe.
+type metricsCollector []prometheus.Metric
+
+func (m metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(m, ch)
+}
+
+func (m metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, metric := range m {
+		ch <- metric
+	}
+}
+
+// probeParams validates the target and ipversion query parameters, writing a
+// 400 response and returning ok=false when they are invalid.
+func probeParams(w http.ResponseWriter, r *http.Request) (target, ipVersion string, ok bool) {
+	target = r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "target parameter is missing", http.StatusBadRequest)
+		return "", "", false
+	}
+	if !probeTargetRe.MatchString(target) {
+		http.Error(w, "target must be a host name or IP address", http.StatusBadRequest)
+		return "", "", false
+	}
+	ipVersion = "IPv4"
+	if v := r.URL.Query().Get("ipversion"); v != "" {
+		if ipVersion, ok = probeIPVersions[strings.ToLower(v)]; !ok {
+			http.Error(w, "ipversion must be Any, IPv4 or IPv6", http.StatusBadRequest)
+			return "", "", false
+		}
+	}
+	return target, ipVersion, true
+}
+
+// probeContext returns a context bounded by the scrape timeout Prometheus
+// sends in X-Prometheus-Scrape-Timeout-Seconds, less a small margin so the
+// response arrives before Prometheus gives up.
+func probeContext(r *http.Request) (context.Context, context.CancelFunc) {
+	timeout := defaultProbeTimeout
+	if v := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); v != "" {
+		if s, err := strconv.ParseFloat(v, 64); err == nil && s > 1 {
+			timeout = time.Duration((s - 0.5) * float64(time.Second))
+		}
+	}
+	return context.WithTimeout(r.Context(), timeout)
+}
+
+// serveProbe writes metrics from a fresh registry in the exposition format
+// negotiated with the client.
+func serveProbe(w http.ResponseWriter, r *http.Request, metrics []prometheus.Metric) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(metricsCollector(metrics))
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// PingProbeHandler returns the /probe/ping handler which pings target from
+// the router (IPPingDiagnostics.execDiagnostic) using the collector's
+// session. Query parameters: target (required) and ipversion (Any, IPv4 or
+// IPv6; default IPv4). While another diagnostic runs it answers 429 Too
+// Many Requests.
+func (c *Experiav10Collector) PingProbeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target, ipVersion, ok := probeParams(w, r)
+		if !ok {
+			return
+		}
+		select {
+		case c.diagnostic <- struct{}{}:
+		default:
+			http.Error(w, "a router diagnostic is already running", http.StatusTooManyRequests)
+			return
+		}
+		defer func() { <-c.diagnostic }()
+		ctx, cancel := probeContext(r)
+		defer cancel()
+
+		start := time.Now()
+		res, err := c.ping(ctx, target, ipVersion)
+		metrics := []prometheus.Metric{}
+		if err != nil {
+			log.Printf("WARN: ping probe for %s failed: %v", target, err)
+		} else {
+			metrics = append(metrics,
+				prometheus.MustNewConstMetric(probemetrics.PingRTT, prometheus.GaugeValue, float64(res.MinimumResponseTime)/1e3, "min"),
+				prometheus.MustNewConstMetric(probemetrics.PingRTT, prometheus.GaugeValue, float64(res.AverageResponseTime)/1e3, "avg"),
+				prometheus.MustNewConstMetric(probemetrics.PingRTT, prometheus.GaugeValue, float64(res.MaximumResponseTime)/1e3, "max"),
+				prometheus.MustNewConstMetric(probemetrics.PingPacketsSuccess, prometheus.GaugeValue, float64(res.PacketsSuccess)),
+				prometheus.MustNewConstMetric(probemetrics.PingPacketsFailed, prometheus.GaugeValue, float64(res.PacketsFailed)),
+				prometheus.MustNewConstMetric(probemetrics.PingPacketSize, prometheus.GaugeValue, float64(res.PacketSize)),
+				prometheus.MustNewConstMetric(probemetrics.PingInfo, prometheus.GaugeValue, 1.0, res.DiagnosticsState, res.IPHost),
+			)
+		}
+		metrics = append(metrics,
+			prometheus.MustNewConstMetric(probemetrics.Success, prometheus.GaugeValue, boolToFloat(err == nil && res.Success())),
+			prometheus.MustNewConstMetric(probemetrics.Duration, prometheus.GaugeValue, time.Since(start).Seconds()),
+		)
+		serveProbe(w, r, metrics)
+	})
+}
+
+// ping runs the router's ping diagnostic against target.
+func (c *Experiav10Collector) ping(ctx context.Context, target, ipVersion string) (diagnostics.PingResult, error) {
+	if err := c.ensureSession(); err != nil {
+		return diagnostics.PingResult{}, err
+	}
+	// The diagnostic outlasts the scrape timeout; ctx bounds it instead.
+	client := *c.client
+	client.Timeout = 0
+	resp, err := c.post(ctx, &client, diagnostics.RequestBodyPing(target, ipVersion))
+	if err != nil {
+		return diagnostics.PingResult{}, err
+	}
+	countPermissionErrors(string(resp))
+	return diagnostics.ParsePing(resp)
+}
@@ -0,0 +1,117 @@
+package collector
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GrammaTonic/experia-v10-exporter/internal/testutil"
+)
+
+func TestPingProbeHandler(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{
+		"execDiagnostic": `{"status":{"DiagnosticsState":"Success","ipHost":"8.8.8.8","packetsSuccess":9,"packetsFailed":1,"packetSize":43,"averageResponseTime":7,"minimumResponseTime":6,"maximumResponseTime":9}}`,
+	})
+	rec := httptest.NewRecorder()
+	c.PingProbeHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe/ping?target=8.8.8.8&ipversion=ipv4", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"probe_success 1",
+		`experia_v10_ping_rtt_seconds{stat="min"} 0.006`,
+		`experia_v10_ping_rtt_seconds{stat="avg"} 0.007`,
+		`experia_v10_ping_rtt_seconds{stat="max"} 0.009`,
+		"experia_v10_ping_packets_success 9",
+		"experia_v10_ping_packets_failed 1",
+		`experia_v10_ping_info{ip_host="8.8.8.8",state="Success"} 1`,
+		"probe_duration_seconds",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in probe output:\n%s", want, body)
+		}
+	}
+}
+
+func TestPingProbeHandler_Failure(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{
+		"execDiagnostic": `{"status":{"DiagnosticsState":"Error_CannotResolveHostName","packetsSuccess":0,"packetsFailed":0}}`,
+	})
+	rec := httptest.NewRecorder()
+	c.PingProbeHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe/ping?target=nonexistent.invalid", nil))
+	if !strings.Contains(rec.Body.String(), "probe_success 0") {
+		t.Fatalf("expected probe_success 0, got:\n%s", rec.Body.String())
+	}
+
+	c = newModuleTestCollector(map[string]string{"execDiagnostic": `{"status":null}`})
+	rec = httptest.NewRecorder()
+	c.PingProbeHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe/ping?target=8.8.8.8", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, "probe_success 0") || strings.Contains(body, "experia_v10_ping_rtt_seconds") {
+		t.Fatalf("expected only probe_success 0 on a parse error, got:\n%s", body)
+	}
+}
+
+func TestPingProbeHandler_Busy(t *testing.T) {
+	c := newModuleTestCollector(nil)
+	c.diagnostic <- struct{}{} // a traceroute is running
+	defer func() { <-c.diagnostic }()
+
+	rec := httptest.NewRecorder()
+	c.PingProbeHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe/ping?target=8.8.8.8", nil))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 while a diagnostic runs, got %d", rec.Code)
+	}
+}
+
+func TestPingProbeHandler_AuthenticatesWithoutSession(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{
+		"execDiagnostic": `{"status":{"DiagnosticsState":"Success","packetsSuccess":1}}`,
+	})
+	var auth string
+	next := c.client.Transport
+	c.client.Transport = testutil.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		if bytes.Contains(b, []byte("execDiagnostic")) {
+			auth = req.Header.Get("Authorization")
+		}
+		return next.RoundTrip(req)
+	})
+
+	rec := httptest.NewRecorder()
+	c.PingProbeHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe/ping?target=8.8.8.8", nil))
+	if auth != "X-Sah CTX-TEST" {
+		t.Fatalf("expected the ping to carry a new session, got %q", auth)
+	}
+}
+
+func TestPingProbeHandler_BadRequest(t *testing.T) {
+	c := newModuleTestCollector(nil)
+	for _, q := range []string{"", "?target=", "?target=a%20b", "?target=8.8.8.8&ipversion=ipv5"} {
+		rec := httptest.NewRecorder()
+		c.PingProbeHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe/ping"+q, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %q, got %d", q, rec.Code)
+		}
+	}
+}
+
+func TestProbeContext_ScrapeTimeoutHeader(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/probe/ping?target=8.8.8.8", nil)
+	r.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "10")
+	ctx, cancel := probeContext(r)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		t.Fatalf("expected a deadline")
+	}
+	if d := deadline.Sub(timeNow()); d > 9500*time.Millisecond || d < 9*time.Second {
+		t.Fatalf("unexpected deadline in %v", d)
+	}
+}
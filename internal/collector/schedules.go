@@ -0,0 +1,47 @@
+package collector
+
+import (
+	"time"
+
+	schedulermetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/scheduler"
+	clock "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/clock"
+	scheduler "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/scheduler"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectSchedules exports the per-device internet access schedules. Whether
+// a device is blocked right now is evaluated against the router clock since
+// the schedule is defined in router local time; without it blocked_now is
+// omitted.
+func (c *Experiav10Collector) collectSchedules(_ *scrapeState, ch chan<- prometheus.Metric) {
+	resp := c.moduleFetch(scheduler.RequestBodyCompleteSchedules())
+	if resp == nil {
+		return
+	}
+	schedules, err := scheduler.ParseCompleteSchedules(resp)
+	if err != nil {
+		c.moduleParseError("getCompleteSchedules", err)
+		return
+	}
+	if len(schedules) == 0 {
+		return
+	}
+
+	var now time.Time
+	haveTime := false
+	if tresp := c.moduleFetch(clock.RequestBody()); tresp != nil {
+		if t, err := clock.ParseTime(tresp); err != nil {
+			c.moduleParseError("getTime", err)
+		} else {
+			now, haveTime = t, true
+		}
+	}
+
+	for _, s := range schedules {
+		ch <- prometheus.MustNewConstMetric(schedulermetrics.ScheduleEnabled, prometheus.GaugeValue, boolToFloat(s.Enable), s.ID, s.Name)
+		ch <- prometheus.MustNewConstMetric(schedulermetrics.ScheduleOverride, prometheus.GaugeValue, boolToFloat(s.Override != ""), s.ID, s.Name, s.Override)
+		if haveTime {
+			ch <- prometheus.MustNewConstMetric(schedulermetrics.ScheduleBlockedNow, prometheus.GaugeValue, boolToFloat(s.BlockedAt(now)), s.ID, s.Name)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package collector
+
+import "testing"
+
+func TestCollectSchedules(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{
+		// Monday 22:00 router time.
+		"getTime": `{"status":true,"data":{"time":"Mon, 27 May 2024 22:00:00 GMT+0200"}}`,
+		"getCompleteSchedules": `{"status":true,"data":{"scheduleInfo":[
+			{"ID":"AA:AA:AA:AA:AA:01","name":"tablet","enable":true,"def":"Enable","override":"","schedule":[{"state":"Disable","begin":75600,"end":111600}]},
+			{"ID":"AA:AA:AA:AA:AA:02","name":"console","enable":true,"def":"Enable","override":"Disable","schedule":[]},
+			{"ID":"AA:AA:AA:AA:AA:03","name":"laptop","enable":true,"def":"Enable","override":"Enable","schedule":[{"state":"Disable","begin":75600,"end":111600}]},
+			{"ID":"AA:AA:AA:AA:AA:04","name":"","enable":false,"def":"Enable","override":"","schedule":[]},
+			{"ID":"AA:AA:AA:AA:AA:04","name":"","enable":false,"def":"Enable","override":"","schedule":[]}
+		]}}`,
+	})
+	if err := c.SetModules("schedules"); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+
+	mfs := gatherFamilies(t, c)
+	byMAC := func(name string) map[string]float64 {
+		t.Helper()
+		mf := mfs[name]
+		if mf == nil {
+			t.Fatalf("expected %s family", name)
+		}
+		out := map[string]float64{}
+		for _, m := range mf.GetMetric() {
+			out[labelValue(m, "mac")] = m.GetGauge().GetValue()
+		}
+		return out
+	}
+	blocked := byMAC("device_schedule_blocked_now")
+	want := map[string]float64{"AA:AA:AA:AA:AA:01": 1, "AA:AA:AA:AA:AA:02": 1, "AA:AA:AA:AA:AA:03": 0, "AA:AA:AA:AA:AA:04": 0}
+	for mac, v := range want {
+		if blocked[mac] != v {
+			t.Fatalf("blocked_now for %s = %v, want %v", mac, blocked[mac], v)
+		}
+	}
+	if len(blocked) != 4 {
+		t.Fatalf("expected duplicate schedule entries to be merged, got %d series", len(blocked))
+	}
+	if enabled := byMAC("device_schedule_enabled"); enabled["AA:AA:AA:AA:AA:04"] != 0 || enabled["AA:AA:AA:AA:AA:01"] != 1 {
+		t.Fatalf("unexpected enabled values: %v", enabled)
+	}
+	override := byMAC("device_schedule_override")
+	if override["AA:AA:AA:AA:AA:02"] != 1 || override["AA:AA:AA:AA:AA:01"] != 0 {
+		t.Fatalf("unexpected override values: %v", override)
+	}
+}
+
+func TestCollectSchedules_WithoutRouterTime(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{
+		"getTime":              `{"status":false}`,
+		"getCompleteSchedules": `{"status":true,"data":{"scheduleInfo":[{"ID":"AA:AA:AA:AA:AA:01","enable":true,"override":"Disable","schedule":[]}]}}`,
+	})
+	if err := c.SetModules("schedules"); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+	mfs := gatherFamilies(t, c)
+	if mfs["device_schedule_enabled"] == nil || mfs["device_schedule_blocked_now"] != nil {
+		t.Fatalf("expected enabled without blocked_now when the router time is unavailable")
+	}
+}
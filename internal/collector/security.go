@@ -0,0 +1,126 @@
+package collector
+
+import (
+	"sort"
+	"strconv"
+
+	securitymetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/security"
+	security "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/security"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectSecurity exports the firewall posture: level, DMZ, port forwards,
+// IPv6 pinholes, WAN ping response and MAC filtering.
+func (c *Experiav10Collector) collectSecurity(st *scrapeState, ch chan<- prometheus.Metric) {
+	if resp := c.moduleFetch(security.RequestBodyFirewallLevel()); resp != nil {
+		if level, err := security.ParseFirewallLevel(resp); err != nil {
+			c.moduleParseError("getFirewallLevel", err)
+		} else if level != "" {
+			ch <- prometheus.MustNewConstMetric(securitymetrics.FirewallLevelInfo, prometheus.GaugeValue, 1.0, level)
+		}
+	}
+
+	if rules, ok := c.securityRules("getDMZ", security.RequestBodyDMZ()); ok {
+		enabled := false
+		for _, r := range rules {
+			if r.Enable {
+				enabled = true
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(securitymetrics.DMZEnabled, prometheus.GaugeValue, boolToFloat(enabled))
+		c.emitRuleInfo(st, ch, "dmz", rules)
+	}
+
+	if rules, ok := c.securityRules("getPortForwarding", security.RequestBodyPortForwarding()); ok {
+		emitRuleCounts(ch, securitymetrics.PortForwardingRules, rules)
+		c.emitRuleInfo(st, ch, "port_forwarding", rules)
+	}
+
+	if rules, ok := c.securityRules("getPinhole", security.RequestBodyPinholes()); ok {
+		emitRuleCounts(ch, securitymetrics.IPv6Pinholes, rules)
+		c.emitRuleInfo(st, ch, "pinhole", rules)
+	}
+
+	if resp := c.moduleFetch(security.RequestBodyRespondToPing()); resp != nil {
+		if rp, err := security.ParseRespondToPing(resp); err != nil {
+			c.moduleParseError("getRespondToPing", err)
+		} else {
+			ch <- prometheus.MustNewConstMetric(securitymetrics.RespondToPingEnabled, prometheus.GaugeValue, boolToFloat(rp.EnableIPv4), "ipv4")
+			ch <- prometheus.MustNewConstMetric(securitymetrics.RespondToPingEnabled, prometheus.GaugeValue, boolToFloat(rp.EnableIPv6), "ipv6")
+		}
+	}
+
+	if resp := c.moduleFetch(security.RequestBodyMACFiltering()); resp != nil {
+		filters, err := security.ParseMACFiltering(resp)
+		if err != nil {
+			c.moduleParseError("getMIBs wlanvap", err)
+			return
+		}
+		for _, f := range filters {
+			ch <- prometheus.MustNewConstMetric(securitymetrics.MACFilteringEnabled, prometheus.GaugeValue, boolToFloat(f.Enabled()), f.VAP, f.Mode)
+			ch <- prometheus.MustNewConstMetric(securitymetrics.MACFilteringEntries, prometheus.GaugeValue, float64(f.Entries), f.VAP)
+		}
+	}
+}
+
+// securityRules fetches and decodes a firewall rule list. ok is false when
+// the call failed or the response could not be decoded.
+func (c *Experiav10Collector) securityRules(call, body string) ([]security.Rule, bool) {
+	resp := c.moduleFetch(body)
+	if resp == nil {
+		return nil, false
+	}
+	rules, err := security.ParseRules(resp)
+	if err != nil {
+		c.moduleParseError(call, err)
+		return nil, false
+	}
+	return rules, true
+}
+
+// emitRuleCounts emits the number of enabled rules per protocol. tcp and udp
+// are always present so alerts can compare against zero.
+func emitRuleCounts(ch chan<- prometheus.Metric, desc *prometheus.Desc, rules []security.Rule) {
+	counts := map[string]float64{"tcp": 0, "udp": 0}
+	for _, r := range rules {
+		if !r.Enable {
+			continue
+		}
+		for _, p := range r.Protocols() {
+			counts[p]++
+		}
+	}
+	protocols := make([]string, 0, len(counts))
+	for p := range counts {
+		protocols = append(protocols, p)
+	}
+	sort.Strings(protocols)
+	for _, p := range protocols {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, counts[p], p)
+	}
+}
+
+// emitRuleInfo emits one firewall_rule_info series per rule when per-rule
+// info has been enabled with SetSecurityRuleInfo.
+func (c *Experiav10Collector) emitRuleInfo(st *scrapeState, ch chan<- prometheus.Metric, kind string, rules []security.Rule) {
+	if !st.settings.securityRuleInfo {
+		return
+	}
+	for _, r := range rules {
+		external := r.ExternalPort
+		internal := r.InternalPort
+		if external == "" {
+			external = r.DestinationPort
+		}
+		ch <- prometheus.MustNewConstMetric(securitymetrics.FirewallRuleInfo, prometheus.GaugeValue, 1.0,
+			kind, r.ID, r.Protocol, external, internal, r.DestinationIPAddress, strconv.FormatBool(r.Enable))
+	}
+}
+
+// SetSecurityRuleInfo controls whether the security module emits one
+// firewall_rule_info series per DMZ, port-forwarding and pinhole rule.
+func (c *Experiav10Collector) SetSecurityRuleInfo(enabled bool) {
+	c.settingsMu.Lock()
+	defer c.settingsMu.Unlock()
+	c.securityRuleInfo = enabled
+}
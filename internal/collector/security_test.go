@@ -0,0 +1,79 @@
+package collector
+
+import "testing"
+
+func securityResponses() map[string]string {
+	return map[string]string{
+		"getFirewallLevel": `{"status":"Custom"}`,
+		"getDMZ":           `{"status":{"webui":{"SourceInterface":"data","DestinationIPAddress":"192.168.2.10","Status":"Enabled","Enable":true}}}`,
+		"getPortForwarding": `{"status":{"webui_ssh":{"Id":"webui_ssh","Protocol":"6","ExternalPort":"2222","InternalPort":"22","DestinationIPAddress":"192.168.2.20","Enable":true},` +
+			`"webui_dns":{"Id":"webui_dns","Protocol":"6,17","ExternalPort":"53","InternalPort":"53","DestinationIPAddress":"192.168.2.21","Enable":true},` +
+			`"webui_off":{"Id":"webui_off","Protocol":"17","ExternalPort":"1000","Enable":false}}}`,
+		"getPinhole":       `{"status":{}}`,
+		"getRespondToPing": `{"status":{"enableIPv4":true,"enableIPv6":false}}`,
+		"wlanvap":          `{"status":{"wlanvap":{"vap2g0priv":{"SSID":"KPN","MACFiltering":{"Mode":"WhiteList","Entry":{"1":{},"2":{}}}}}}}`,
+	}
+}
+
+func TestCollectSecurity_Posture(t *testing.T) {
+	c := newModuleTestCollector(securityResponses())
+	if err := c.SetModules("security"); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+	mfs := gatherFamilies(t, c)
+
+	if mf := mfs["firewall_level_info"]; mf == nil || labelValue(mf.GetMetric()[0], "level") != "Custom" {
+		t.Fatalf("expected firewall_level_info{level=Custom}")
+	}
+	if mf := mfs["dmz_enabled"]; mf == nil || mf.GetMetric()[0].GetGauge().GetValue() != 1 {
+		t.Fatalf("expected dmz_enabled 1")
+	}
+
+	pf := map[string]float64{}
+	for _, m := range mfs["port_forwarding_rules"].GetMetric() {
+		pf[labelValue(m, "protocol")] = m.GetGauge().GetValue()
+	}
+	if pf["tcp"] != 2 || pf["udp"] != 1 {
+		t.Fatalf("unexpected port_forwarding_rules: %v", pf)
+	}
+	for _, m := range mfs["ipv6_pinholes"].GetMetric() {
+		if m.GetGauge().GetValue() != 0 {
+			t.Fatalf("expected zero pinholes, got %v", m)
+		}
+	}
+
+	ping := map[string]float64{}
+	for _, m := range mfs["respond_to_ping_enabled"].GetMetric() {
+		ping[labelValue(m, "ip_version")] = m.GetGauge().GetValue()
+	}
+	if ping["ipv4"] != 1 || ping["ipv6"] != 0 {
+		t.Fatalf("unexpected respond_to_ping_enabled: %v", ping)
+	}
+	if mf := mfs["mac_filtering_entries"]; mf == nil || mf.GetMetric()[0].GetGauge().GetValue() != 2 {
+		t.Fatalf("expected mac_filtering_entries 2")
+	}
+	if _, ok := mfs["firewall_rule_info"]; ok {
+		t.Fatalf("firewall_rule_info must be opt-in")
+	}
+}
+
+func TestCollectSecurity_RuleInfoOptIn(t *testing.T) {
+	c := newModuleTestCollector(securityResponses())
+	_ = c.SetModules("security")
+	c.SetSecurityRuleInfo(true)
+	mfs := gatherFamilies(t, c)
+	mf := mfs["firewall_rule_info"]
+	if mf == nil {
+		t.Fatalf("expected firewall_rule_info family")
+	}
+	types := map[string]int{}
+	for _, m := range mf.GetMetric() {
+		types[labelValue(m, "type")]++
+		if labelValue(m, "id") == "webui_ssh" && labelValue(m, "external_port") != "2222" {
+			t.Fatalf("unexpected external_port for webui_ssh: %q", labelValue(m, "external_port"))
+		}
+	}
+	if types["dmz"] != 1 || types["port_forwarding"] != 3 {
+		t.Fatalf("unexpected rule info counts: %v", types)
+	}
+}
@@ -0,0 +1,39 @@
+package clock
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// timeLayout matches the human-formatted timestamp returned by Time.getTime,
+// for example "Mon, 27 May 2024 17:51:57 GMT+0200".
+const timeLayout = "Mon, 02 Jan 2006 15:04:05 GMT-0700"
+
+// RequestBody returns the JSON body for Time.getTime.
+func RequestBody() string {
+	return `{"service":"Time","method":"getTime","parameters":{}}`
+}
+
+// ParseTime decodes a getTime response into the device's current time. It
+// returns an error when the device reports status=false or the timestamp
+// does not match the expected layout.
+func ParseTime(data []byte) (time.Time, error) {
+	var r struct {
+		Status bool `json:"status"`
+		Data   struct {
+			Time string `json:"time"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return time.Time{}, err
+	}
+	if !r.Status {
+		return time.Time{}, fmt.Errorf("getTime returned status=false")
+	}
+	t, err := time.Parse(timeLayout, r.Data.Time)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected time format %q: %w", r.Data.Time, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,38 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRequestBody(t *testing.T) {
+	if body := RequestBody(); body == "" {
+		t.Fatalf("expected non-empty request body")
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	got, err := ParseTime([]byte(`{"status":true,"data":{"time":"Mon, 27 May 2024 17:51:57 GMT+0200"}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := time.Date(2024, 5, 27, 15, 51, 57, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if _, offset := got.Zone(); offset != 2*3600 {
+		t.Fatalf("expected +0200 offset, got %d", offset)
+	}
+}
+
+func TestParseTime_Errors(t *testing.T) {
+	for _, in := range []string{
+		`not-json`,
+		`{"status":false,"data":{"time":"Mon, 27 May 2024 17:51:57 GMT+0200"}}`,
+		`{"status":true,"data":{"time":"2024-05-27T17:51:57Z"}}`,
+	} {
+		if _, err := ParseTime([]byte(in)); err == nil {
+			t.Fatalf("expected error for %s", in)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package devices
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DeviceTypeSTB is the DeviceType the device table assigns to IPTV receivers.
+const DeviceTypeSTB = "Set-top Box"
+
+// RequestBodySTBs returns the JSON body for Devices.get listing the client
+// devices, using the expression of the web UI's getAllDevices call. The
+// receivers are picked out of the response by ParseSTBs.
+func RequestBodySTBs() string {
+	return `{"service":"Devices","method":"get","parameters":{"expression":"not interface and not self and not voice"}}`
+}
+
+// Device is an entry of the device table. Only the fields used by the
+// exporter are decoded.
+type Device struct {
+	Key             string `json:"Key"`
+	Name            string `json:"Name"`
+	DeviceType      string `json:"DeviceType"`
+	Active          bool   `json:"Active"`
+	IPAddress       string `json:"IPAddress"`
+	PhysAddress     string `json:"PhysAddress"`
+	Layer2Interface string `json:"Layer2Interface"`
+	InterfaceName   string `json:"InterfaceName"`
+	VendorClassID   string `json:"VendorClassID"`
+	LastChanged     string `json:"LastChanged"`
+}
+
+// MAC returns the device's MAC address, falling back to its key.
+func (d Device) MAC() string {
+	if d.PhysAddress != "" {
+		return d.PhysAddress
+	}
+	return d.Key
+}
+
+// Interface returns the router interface the device is attached to.
+func (d Device) Interface() string {
+	if d.Layer2Interface != "" {
+		return d.Layer2Interface
+	}
+	return d.InterfaceName
+}
+
+// ParseDevices decodes a Devices.get response into devices sorted by MAC.
+func ParseDevices(data []byte) ([]Device, error) {
+	devices, err := decodeDevices(data)
+	if err != nil {
+		return nil, err
+	}
+	sortByMAC(devices)
+	return devices, nil
+}
+
+// ParseSTBs decodes a Devices.get response and keeps the IPTV receivers,
+// sorted by MAC. A MAC listed more than once is reported only for its
+// first entry.
+func ParseSTBs(data []byte) ([]Device, error) {
+	devices, err := decodeDevices(data)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	out := make([]Device, 0, len(devices))
+	for _, d := range devices {
+		if !strings.EqualFold(d.DeviceType, DeviceTypeSTB) || seen[d.MAC()] {
+			continue
+		}
+		seen[d.MAC()] = true
+		out = append(out, d)
+	}
+	sortByMAC(out)
+	return out, nil
+}
+
+func decodeDevices(data []byte) ([]Device, error) {
+	var resp struct {
+		Status json.RawMessage `json:"status"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	var devices []Device
+	if err := json.Unmarshal(resp.Status, &devices); err != nil {
+		return nil, fmt.Errorf("unexpected Devices.get status: %w", err)
+	}
+	return devices, nil
+}
+
+func sortByMAC(devices []Device) {
+	sort.Slice(devices, func(i, j int) bool { return devices[i].MAC() < devices[j].MAC() })
+}
@@ -0,0 +1,47 @@
+package devices
+
+import "testing"
+
+func TestRequestBodySTBs(t *testing.T) {
+	want := `{"service":"Devices","method":"get","parameters":{"expression":"not interface and not self and not voice"}}`
+	if got := RequestBodySTBs(); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestParseDevices(t *testing.T) {
+	devices, err := ParseDevices([]byte(`{"status":[
+		{"Key":"C4:EB:42:06:90:78","Name":"bedroom","Active":false,"InterfaceName":"wl0"},
+		{"Key":"C4:EB:42:06:90:77","PhysAddress":"C4:EB:42:06:90:77","Name":"DIW7022-69077-test","DeviceType":"Set-top Box","Active":true,"IPAddress":"192.168.2.15","Layer2Interface":"ETH2","InterfaceName":"ETH2"}
+	]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(devices) != 2 || devices[0].MAC() != "C4:EB:42:06:90:77" {
+		t.Fatalf("expected devices sorted by MAC, got %+v", devices)
+	}
+	if !devices[0].Active || devices[0].Interface() != "ETH2" || devices[1].Interface() != "wl0" || devices[1].MAC() != "C4:EB:42:06:90:78" {
+		t.Fatalf("unexpected device fields: %+v", devices)
+	}
+	if _, err := ParseDevices([]byte(`{"status":false}`)); err == nil {
+		t.Fatalf("expected error for a non-list status")
+	}
+}
+
+func TestParseSTBs(t *testing.T) {
+	stbs, err := ParseSTBs([]byte(`{"status":[
+		{"Key":"C4:EB:42:06:90:78","PhysAddress":"C4:EB:42:06:90:78","Name":"bedroom","DeviceType":"Set-top Box","Active":false},
+		{"Key":"AA:BB:CC:DD:EE:FF","Name":"laptop","DeviceType":"Laptop","Active":true},
+		{"Key":"C4:EB:42:06:90:77","PhysAddress":"C4:EB:42:06:90:77","Name":"living room","DeviceType":"Set-top Box","Active":true},
+		{"Key":"C4:EB:42:06:90:77","PhysAddress":"C4:EB:42:06:90:77","Name":"living room (stale)","DeviceType":"Set-top Box","Active":false}
+	]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stbs) != 2 || stbs[0].Name != "living room" || stbs[1].Name != "bedroom" {
+		t.Fatalf("expected the two receivers once each, sorted by MAC, got %+v", stbs)
+	}
+	if _, err := ParseSTBs([]byte(`{"status":false}`)); err == nil {
+		t.Fatalf("expected error for a non-list status")
+	}
+}
@@ -0,0 +1,59 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// RequestBodyPing returns the JSON body for IPPingDiagnostics.execDiagnostic
+// which pings host from the router. ipVersion is "Any", "IPv4" or "IPv6".
+func RequestBodyPing(host, ipVersion string) string {
+	b, _ := json.Marshal(map[string]interface{}{
+		"service": "IPPingDiagnostics",
+		"method":  "execDiagnostic",
+		"parameters": map[string]string{
+			"ipHost":          host,
+			"ProtocolVersion": ipVersion,
+		},
+	})
+	return string(b)
+}
+
+// PingResult is the outcome of a ping diagnostic. Response times are in
+// milliseconds.
+type PingResult struct {
+	DiagnosticsState    string `json:"DiagnosticsState"`
+	IPHost              string `json:"ipHost"`
+	PacketsSuccess      int    `json:"packetsSuccess"`
+	PacketsFailed       int    `json:"packetsFailed"`
+	PacketSize          int    `json:"packetSize"`
+	AverageResponseTime int    `json:"averageResponseTime"`
+	MinimumResponseTime int    `json:"minimumResponseTime"`
+	MaximumResponseTime int    `json:"maximumResponseTime"`
+}
+
+// Success reports whether the diagnostic completed and at least one echo
+// reply was received.
+func (r PingResult) Success() bool {
+	return r.DiagnosticsState == "Success" && r.PacketsSuccess > 0
+}
+
+// ParsePing decodes an execDiagnostic response.
+func ParsePing(data []byte) (PingResult, error) {
+	var resp struct {
+		Status *PingResult `json:"status"`
+		Errors []struct {
+			Description string `json:"description"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return PingResult{}, err
+	}
+	if len(resp.Errors) > 0 {
+		return PingResult{}, fmt.Errorf("ping diagnostic failed: %s", resp.Errors[0].Description)
+	}
+	if resp.Status == nil {
+		return PingResult{}, fmt.Errorf("ping diagnostic returned no status")
+	}
+	return *resp.Status, nil
+}
@@ -0,0 +1,41 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRequestBodyPing(t *testing.T) {
+	var body struct {
+		Service    string            `json:"service"`
+		Method     string            `json:"method"`
+		Parameters map[string]string `json:"parameters"`
+	}
+	if err := json.Unmarshal([]byte(RequestBodyPing(`evil"host`, "IPv4")), &body); err != nil {
+		t.Fatalf("body is not valid JSON: %v", err)
+	}
+	if body.Service != "IPPingDiagnostics" || body.Method != "execDiagnostic" ||
+		body.Parameters["ipHost"] != `evil"host` || body.Parameters["ProtocolVersion"] != "IPv4" {
+		t.Fatalf("unexpected body: %+v", body)
+	}
+}
+
+func TestParsePing(t *testing.T) {
+	r, err := ParsePing([]byte(`{"status":{"DiagnosticsState":"Success","ipHost":"34.141.213.235","packetsSuccess":10,"packetsFailed":0,"packetSize":43,"averageResponseTime":7,"minimumResponseTime":6,"maximumResponseTime":9}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !r.Success() || r.IPHost != "34.141.213.235" || r.MinimumResponseTime != 6 || r.MaximumResponseTime != 9 {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+	r, err = ParsePing([]byte(`{"status":{"DiagnosticsState":"Error_CannotResolveHostName","packetsSuccess":0,"packetsFailed":0}}`))
+	if err != nil || r.Success() {
+		t.Fatalf("expected an unsuccessful result, got %+v err=%v", r, err)
+	}
+	if _, err := ParsePing([]byte(`{"status":null}`)); err == nil {
+		t.Fatalf("expected error for null status")
+	}
+	if _, err := ParsePing([]byte(`{"status":null,"errors":[{"description":"Permission denied"}]}`)); err == nil {
+		t.Fatalf("expected error for device errors")
+	}
+}
@@ -0,0 +1,118 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Traceroute hop error codes as interpreted by the web UI.
+const (
+	HopCompleted              = 0
+	HopDestinationUnreachable = 3
+	HopTimeExceeded           = 11
+	HopTimeout                = 4294967295
+)
+
+// RequestBodyTraceroute returns the JSON body for Traceroute.start_diagnostic
+// which traces the route to host from the router. ipVersion is "Any", "IPv4"
+// or "IPv6".
+func RequestBodyTraceroute(host, ipVersion string) string {
+	b, _ := json.Marshal(map[string]interface{}{
+		"service": "Traceroute",
+		"method":  "start_diagnostic",
+		"parameters": map[string]string{
+			"host":      host,
+			"ipversion": ipVersion,
+		},
+	})
+	return string(b)
+}
+
+// Hop is a traceroute hop. RTTimes is a comma-separated list of round-trip
+// times in milliseconds, one per probe packet.
+type Hop struct {
+	Number      int    `json:"-"`
+	ErrorCode   uint32 `json:"ErrorCode"`
+	RTTimes     string `json:"RTTimes"`
+	HostAddress string `json:"HostAddress"`
+	Host        string `json:"Host"`
+}
+
+// Name returns the host name of the hop, falling back to its address.
+func (h Hop) Name() string {
+	if h.Host != "" {
+		return h.Host
+	}
+	return h.HostAddress
+}
+
+// AverageRTT returns the mean of the hop's round-trip times in milliseconds;
+// ok is false when the hop did not answer.
+func (h Hop) AverageRTT() (ms float64, ok bool) {
+	var sum float64
+	n := 0
+	for _, s := range strings.Split(h.RTTimes, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || v <= 0 {
+			continue
+		}
+		sum += v
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
+
+// ParseTraceroute decodes a start_diagnostic response into hops ordered by
+// hop number. RouteHops is either a list or an object keyed by hop number.
+func ParseTraceroute(data []byte) ([]Hop, error) {
+	var resp struct {
+		Status *struct {
+			RouteHops json.RawMessage `json:"RouteHops"`
+		} `json:"status"`
+		Errors []struct {
+			Description string `json:"description"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Errors) > 0 {
+		return nil, fmt.Errorf("traceroute diagnostic failed: %s", resp.Errors[0].Description)
+	}
+	if resp.Status == nil || len(resp.Status.RouteHops) == 0 {
+		return nil, fmt.Errorf("traceroute diagnostic returned no route hops")
+	}
+	var hops []Hop
+	if err := json.Unmarshal(resp.Status.RouteHops, &hops); err == nil {
+		for i := range hops {
+			hops[i].Number = i + 1
+		}
+		return hops, nil
+	}
+	var keyed map[string]Hop
+	if err := json.Unmarshal(resp.Status.RouteHops, &keyed); err != nil {
+		return nil, fmt.Errorf("unexpected RouteHops: %w", err)
+	}
+	for key, h := range keyed {
+		n, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected RouteHops key %q", key)
+		}
+		h.Number = n
+		hops = append(hops, h)
+	}
+	sort.Slice(hops, func(i, j int) bool { return hops[i].Number < hops[j].Number })
+	return hops, nil
+}
+
+// TracerouteSucceeded reports whether the trace reached its destination,
+// which the router signals with a completed error code on the last hop.
+func TracerouteSucceeded(hops []Hop) bool {
+	return len(hops) > 0 && hops[len(hops)-1].ErrorCode == HopCompleted
+}
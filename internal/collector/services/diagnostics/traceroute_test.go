@@ -0,0 +1,63 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRequestBodyTraceroute(t *testing.T) {
+	var body struct {
+		Service    string            `json:"service"`
+		Method     string            `json:"method"`
+		Parameters map[string]string `json:"parameters"`
+	}
+	if err := json.Unmarshal([]byte(RequestBodyTraceroute("www.google.com", "IPv6")), &body); err != nil {
+		t.Fatalf("body is not valid JSON: %v", err)
+	}
+	if body.Service != "Traceroute" || body.Method != "start_diagnostic" ||
+		body.Parameters["host"] != "www.google.com" || body.Parameters["ipversion"] != "IPv6" {
+		t.Fatalf("unexpected body: %+v", body)
+	}
+}
+
+func TestParseTraceroute_Keyed(t *testing.T) {
+	hops, err := ParseTraceroute([]byte(`{"status":{"RouteHops":{
+		"10":{"ErrorCode":0,"RTTimes":"8,9,10","HostAddress":"142.250.179.196","Host":"ams17s10-in-f4.1e100.net"},
+		"2":{"ErrorCode":4294967295,"RTTimes":"0,0,0","HostAddress":"","Host":""},
+		"1":{"ErrorCode":11,"RTTimes":"1,2,3","HostAddress":"195.190.228.1","Host":""}
+	}}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hops) != 3 || hops[0].Number != 1 || hops[1].Number != 2 || hops[2].Number != 10 {
+		t.Fatalf("expected hops ordered by number, got %+v", hops)
+	}
+	if hops[0].Name() != "195.190.228.1" || hops[2].Name() != "ams17s10-in-f4.1e100.net" {
+		t.Fatalf("unexpected hop names")
+	}
+	if avg, ok := hops[0].AverageRTT(); !ok || avg != 2 {
+		t.Fatalf("unexpected average RTT %v ok=%v", avg, ok)
+	}
+	if _, ok := hops[1].AverageRTT(); ok {
+		t.Fatalf("expected no RTT for a timed out hop")
+	}
+	if !TracerouteSucceeded(hops) {
+		t.Fatalf("expected trace to succeed")
+	}
+}
+
+func TestParseTraceroute_List(t *testing.T) {
+	hops, err := ParseTraceroute([]byte(`{"status":{"RouteHops":[{"ErrorCode":11,"RTTimes":"1"},{"ErrorCode":3,"RTTimes":"5"}]}}`))
+	if err != nil || len(hops) != 2 || hops[1].Number != 2 {
+		t.Fatalf("unexpected hops %+v err=%v", hops, err)
+	}
+	if TracerouteSucceeded(hops) {
+		t.Fatalf("expected an unreachable destination to fail")
+	}
+	if _, err := ParseTraceroute([]byte(`{"status":{}}`)); err == nil {
+		t.Fatalf("expected error without route hops")
+	}
+	if _, err := ParseTraceroute([]byte(`{"status":{"RouteHops":{"x":{}}}}`)); err == nil {
+		t.Fatalf("expected error for a non-numeric hop key")
+	}
+}
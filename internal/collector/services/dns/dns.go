@@ -0,0 +1,106 @@
+package dns
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// RequestBodyRoutes returns the JSON body for DNS.Server.Route.get which
+// lists the upstream servers DNS queries are forwarded to.
+func RequestBodyRoutes() string {
+	return `{"service":"DNS.Server.Route","method":"get","parameters":{}}`
+}
+
+// RequestBodyMode returns the JSON body for DNS.get which reports whether
+// the upstream servers are dynamic (ISP provided) or static.
+func RequestBodyMode() string {
+	return `{"service":"DNS","method":"get","parameters":{}}`
+}
+
+// RequestBodyLANIPv4 returns the JSON body reading the IPv4 configuration of
+// the LAN bridge, including the DNS servers advertised over DHCP.
+func RequestBodyLANIPv4() string {
+	return `{"service":"NetMaster.LAN.default.Bridge.lan","method":"getIPv4","parameters":{}}`
+}
+
+// Route is a DNS forwarding route.
+type Route struct {
+	Name   string
+	Server string `json:"DNS"`
+	Status string `json:"Status"`
+}
+
+// ParseRoutes decodes a DNS.Server.Route.get response into routes sorted by
+// name.
+func ParseRoutes(data []byte) ([]Route, error) {
+	var r struct {
+		Status map[string]Route `json:"status"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	routes := make([]Route, 0, len(r.Status))
+	for name, route := range r.Status {
+		route.Name = name
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Name < routes[j].Name })
+	return routes, nil
+}
+
+// ParseMode returns the DNS mode (for example "Dynamic") from a DNS.get
+// response.
+func ParseMode(data []byte) (string, error) {
+	var r struct {
+		Status *struct {
+			Mode string `json:"Mode"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return "", err
+	}
+	if r.Status == nil {
+		return "", fmt.Errorf("DNS.get returned no status")
+	}
+	return r.Status.Mode, nil
+}
+
+// ParseLANServers returns the DNS servers advertised on the LAN from a
+// getIPv4 or getIPv6Configuration response. Both return the settings under
+// "data" with a comma-separated DNSServers field.
+func ParseLANServers(data []byte) ([]string, error) {
+	var r struct {
+		Data *struct {
+			DNSServers string `json:"DNSServers"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	if r.Data == nil {
+		return nil, fmt.Errorf("response contains no data")
+	}
+	var servers []string
+	for _, s := range strings.Split(r.Data.DNSServers, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			servers = append(servers, s)
+		}
+	}
+	return servers, nil
+}
+
+// IPVersion returns "ipv4" or "ipv6" for an address, or "" when the server
+// is not an IP literal.
+func IPVersion(server string) string {
+	addr, err := netip.ParseAddr(server)
+	if err != nil {
+		return ""
+	}
+	if addr.Is4() {
+		return "ipv4"
+	}
+	return "ipv6"
+}
@@ -0,0 +1,62 @@
+package dns
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRequestBodies(t *testing.T) {
+	for _, b := range []string{RequestBodyRoutes(), RequestBodyMode(), RequestBodyLANIPv4()} {
+		if b == "" {
+			t.Fatalf("expected non-empty request body")
+		}
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes([]byte(`{"status":{"dataV6_1":{"Status":"Enabled","DNS":"2a02:a47f:e000::53"},"dataV4_1":{"Status":"Enabled","DNS":"195.121.1.34"}}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Route{
+		{Name: "dataV4_1", Server: "195.121.1.34", Status: "Enabled"},
+		{Name: "dataV6_1", Server: "2a02:a47f:e000::53", Status: "Enabled"},
+	}
+	if !reflect.DeepEqual(routes, want) {
+		t.Fatalf("got %+v, want %+v", routes, want)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	if mode, err := ParseMode([]byte(`{"status":{"Mode":"Dynamic"}}`)); err != nil || mode != "Dynamic" {
+		t.Fatalf("unexpected mode %q err=%v", mode, err)
+	}
+	if _, err := ParseMode([]byte(`{"status":null}`)); err == nil {
+		t.Fatalf("expected error for null status")
+	}
+}
+
+func TestParseLANServers(t *testing.T) {
+	servers, err := ParseLANServers([]byte(`{"status":null,"data":{"DNSServers":"2a02:a47f:ac::210, 2a02:a47f:ac::212"}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(servers, []string{"2a02:a47f:ac::210", "2a02:a47f:ac::212"}) {
+		t.Fatalf("unexpected servers: %v", servers)
+	}
+	if servers, err := ParseLANServers([]byte(`{"data":{"DNSServers":""}}`)); err != nil || len(servers) != 0 {
+		t.Fatalf("expected no servers, got %v %v", servers, err)
+	}
+	if _, err := ParseLANServers([]byte(`{"status":null}`)); err == nil {
+		t.Fatalf("expected error for missing data")
+	}
+}
+
+func TestIPVersion(t *testing.T) {
+	cases := map[string]string{"195.121.1.34": "ipv4", "2a02:a47f:e000::53": "ipv6", "dns.example": ""}
+	for in, want := range cases {
+		if got := IPVersion(in); got != want {
+			t.Fatalf("IPVersion(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package dyndns
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RequestBodyHosts returns the JSON body for DynDNS.getHosts which lists the
+// configured dynamic DNS entries.
+func RequestBodyHosts() string {
+	return `{"service":"DynDNS","method":"getHosts","parameters":{}}`
+}
+
+// Host is a configured dynamic DNS entry. The web UI only shows service,
+// hostname, username and status; the remaining fields are reported by the
+// DynDNS plugin on firmware that includes them.
+type Host struct {
+	Service    string `json:"service"`
+	Hostname   string `json:"hostname"`
+	Username   string `json:"username"`
+	Status     string `json:"status"`
+	LastUpdate string `json:"last_update"`
+	IPAddress  string `json:"ip"`
+	Enable     *bool  `json:"enable"`
+}
+
+// Updated reports whether the last update of the entry succeeded.
+func (h Host) Updated() bool {
+	switch strings.ToUpper(h.Status) {
+	case "UPDATED", "SUCCESS", "OK":
+		return true
+	}
+	return false
+}
+
+// Enabled reports whether the entry is enabled. Entries without an enable
+// field are treated as enabled since the UI only lists active hosts.
+func (h Host) Enabled() bool {
+	return h.Enable == nil || *h.Enable
+}
+
+// LastUpdateTime parses the last_update field. The zero time is returned
+// when the field is empty, unparseable or the plugin's "never" placeholder
+// (0001-01-01T00:00:00Z).
+func (h Host) LastUpdateTime() time.Time {
+	t, err := time.Parse(time.RFC3339, h.LastUpdate)
+	if err != nil || t.Year() <= 1 {
+		return time.Time{}
+	}
+	return t
+}
+
+// ParseHosts decodes a DynDNS.getHosts response into hosts sorted by
+// hostname.
+func ParseHosts(data []byte) ([]Host, error) {
+	var r struct {
+		Status json.RawMessage `json:"status"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	var hosts []Host
+	if err := json.Unmarshal(r.Status, &hosts); err != nil {
+		return nil, fmt.Errorf("unexpected DynDNS.getHosts status: %w", err)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Hostname < hosts[j].Hostname })
+	return hosts, nil
+}
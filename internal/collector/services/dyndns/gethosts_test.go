@@ -0,0 +1,45 @@
+package dyndns
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRequestBodyHosts(t *testing.T) {
+	if RequestBodyHosts() != `{"service":"DynDNS","method":"getHosts","parameters":{}}` {
+		t.Fatalf("unexpected body: %s", RequestBodyHosts())
+	}
+}
+
+func TestParseHosts(t *testing.T) {
+	hosts, err := ParseHosts([]byte(`{"status":[
+		{"service":"No-IP","hostname":"b.example.org","username":"u","status":"ERROR","last_update":"0001-01-01T00:00:00Z","enable":false},
+		{"service":"dyndns","hostname":"a.example.org","username":"u","status":"UPDATED","last_update":"2024-05-27T13:51:27Z"}
+	]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hosts) != 2 || hosts[0].Hostname != "a.example.org" {
+		t.Fatalf("expected hosts sorted by hostname, got %+v", hosts)
+	}
+	a, b := hosts[0], hosts[1]
+	if !a.Updated() || !a.Enabled() {
+		t.Fatalf("expected a.example.org updated and enabled")
+	}
+	if !a.LastUpdateTime().Equal(time.Date(2024, 5, 27, 13, 51, 27, 0, time.UTC)) {
+		t.Fatalf("unexpected last update: %v", a.LastUpdateTime())
+	}
+	if b.Updated() || b.Enabled() || !b.LastUpdateTime().IsZero() {
+		t.Fatalf("unexpected state for b.example.org: %+v", b)
+	}
+}
+
+func TestParseHosts_Empty(t *testing.T) {
+	hosts, err := ParseHosts([]byte(`{"status":[]}`))
+	if err != nil || len(hosts) != 0 {
+		t.Fatalf("expected no hosts, got %v %v", hosts, err)
+	}
+	if _, err := ParseHosts([]byte(`{"status":false}`)); err == nil {
+		t.Fatalf("expected error for non-list status")
+	}
+}
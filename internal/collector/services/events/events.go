@@ -0,0 +1,166 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPermissionDenied is returned when the device rejects the session token,
+// meaning the caller must re-authenticate before reopening the channel.
+var ErrPermissionDenied = errors.New("permission denied")
+
+// DefaultHandlers are the event sources subscribed to when none are
+// configured: interface state, the device table and the WAN connection.
+var DefaultHandlers = []string{"NeMo.Intf", "Devices.Device", "NMC"}
+
+// RequestBodyOpenChannel returns the JSON body for eventmanager.open_channel
+// subscribing to the given handlers.
+func RequestBodyOpenChannel(handlers []string) string {
+	type handler struct {
+		Handler string `json:"handler"`
+	}
+	hs := make([]handler, 0, len(handlers))
+	for _, h := range handlers {
+		hs = append(hs, handler{Handler: h})
+	}
+	b, _ := json.Marshal(map[string]interface{}{
+		"service":    "eventmanager",
+		"method":     "open_channel",
+		"parameters": map[string]interface{}{"events": hs},
+	})
+	return string(b)
+}
+
+// RequestBodyGetEvents returns the JSON body for eventmanager.get_events
+// which blocks on the device until events are queued on the channel.
+func RequestBodyGetEvents(channelID int64) string {
+	return fmt.Sprintf(`{"eventsVersionToUse":1,"service":"eventmanager","method":"get_events","parameters":{"channelid":%d,"events":[]}}`, channelID)
+}
+
+// Event is a single notification delivered on an event channel.
+type Event struct {
+	Handler    string
+	Reason     string
+	Attributes map[string]interface{}
+}
+
+type deviceError struct {
+	Error       int    `json:"error"`
+	Description string `json:"description"`
+	Info        string `json:"info"`
+}
+
+// checkErrors turns the errors array of a response into an error.
+func checkErrors(errs []deviceError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	for _, e := range errs {
+		if e.Description == "Permission denied" {
+			return ErrPermissionDenied
+		}
+	}
+	return fmt.Errorf("device error %d: %s", errs[0].Error, errs[0].Description)
+}
+
+// ParseChannelID returns the channel id from an open_channel response.
+func ParseChannelID(data []byte) (int64, error) {
+	var r struct {
+		Status *struct {
+			ChannelID int64 `json:"channelid"`
+		} `json:"status"`
+		Errors []deviceError `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return 0, err
+	}
+	if err := checkErrors(r.Errors); err != nil {
+		return 0, err
+	}
+	if r.Status == nil || r.Status.ChannelID == 0 {
+		return 0, fmt.Errorf("open_channel returned no channel id")
+	}
+	return r.Status.ChannelID, nil
+}
+
+// ParseEvents decodes a get_events response. An empty list means the
+// long-poll expired without events.
+func ParseEvents(data []byte) ([]Event, error) {
+	var r struct {
+		Status []struct {
+			Data struct {
+				Handler string `json:"handler"`
+				Object  struct {
+					Reason     string                 `json:"reason"`
+					Attributes map[string]interface{} `json:"attributes"`
+				} `json:"object"`
+			} `json:"data"`
+		} `json:"status"`
+		Errors []deviceError `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	if err := checkErrors(r.Errors); err != nil {
+		return nil, err
+	}
+	out := make([]Event, 0, len(r.Status))
+	for _, s := range r.Status {
+		out = append(out, Event{Handler: s.Data.Handler, Reason: s.Data.Object.Reason, Attributes: s.Data.Object.Attributes})
+	}
+	return out, nil
+}
+
+// EventOther is the event of everything Classify does not recognise.
+const EventOther = "other"
+
+// Classify maps an event to a bounded (service, event) pair. service is the
+// first component of the handler path ("NeMo", "Devices", "NMC"); event is
+// one of link_up, link_down, device_joined, device_left, wan_connected,
+// wan_disconnected or, for anything else, EventOther. The firmware picks
+// the reasons, so they are never used as the event.
+func Classify(e Event) (service, event string) {
+	service, _, _ = strings.Cut(e.Handler, ".")
+	if service == "" {
+		service = "unknown"
+	}
+	reason := strings.ToLower(e.Reason)
+
+	if cs, ok := e.Attributes["ConnectionState"].(string); ok {
+		if cs == "Connected" {
+			return service, "wan_connected"
+		}
+		return service, "wan_disconnected"
+	}
+	if strings.HasPrefix(e.Handler, "Devices.Device") {
+		switch reason {
+		case "add", "device_added", "object_added":
+			return service, "device_joined"
+		case "del", "device_deleted", "object_deleted":
+			return service, "device_left"
+		}
+		if active, ok := e.Attributes["Active"].(bool); ok {
+			if active {
+				return service, "device_joined"
+			}
+			return service, "device_left"
+		}
+	}
+	if strings.HasPrefix(e.Handler, "NeMo.Intf") {
+		if st, ok := e.Attributes["Status"].(bool); ok {
+			if st {
+				return service, "link_up"
+			}
+			return service, "link_down"
+		}
+		if st, ok := e.Attributes["NetDevState"].(string); ok {
+			if strings.EqualFold(st, "up") {
+				return service, "link_up"
+			}
+			return service, "link_down"
+		}
+	}
+	return service, EventOther
+}
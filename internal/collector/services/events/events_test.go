@@ -0,0 +1,79 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestRequestBodies(t *testing.T) {
+	var open struct {
+		Service    string `json:"service"`
+		Method     string `json:"method"`
+		Parameters struct {
+			Events []struct {
+				Handler string `json:"handler"`
+			} `json:"events"`
+		} `json:"parameters"`
+	}
+	if err := json.Unmarshal([]byte(RequestBodyOpenChannel([]string{"NeMo.Intf", "NMC"})), &open); err != nil {
+		t.Fatalf("open_channel body is not JSON: %v", err)
+	}
+	if open.Service != "eventmanager" || open.Method != "open_channel" || len(open.Parameters.Events) != 2 || open.Parameters.Events[1].Handler != "NMC" {
+		t.Fatalf("unexpected open_channel body: %+v", open)
+	}
+	want := `{"eventsVersionToUse":1,"service":"eventmanager","method":"get_events","parameters":{"channelid":42,"events":[]}}`
+	if got := RequestBodyGetEvents(42); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestParseChannelID(t *testing.T) {
+	if id, err := ParseChannelID([]byte(`{"status":{"channelid":1234}}`)); err != nil || id != 1234 {
+		t.Fatalf("unexpected id %d err=%v", id, err)
+	}
+	if _, err := ParseChannelID([]byte(`{"status":null,"errors":[{"error":13,"description":"Permission denied"}]}`)); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("expected ErrPermissionDenied, got %v", err)
+	}
+	if _, err := ParseChannelID([]byte(`{"status":{}}`)); err == nil {
+		t.Fatalf("expected error without channel id")
+	}
+}
+
+func TestParseEvents(t *testing.T) {
+	evs, err := ParseEvents([]byte(`{"status":[{"data":{"handler":"NeMo.Intf.eth1","object":{"reason":"changed","attributes":{"Status":false}}}}]}`))
+	if err != nil || len(evs) != 1 {
+		t.Fatalf("unexpected events %v err=%v", evs, err)
+	}
+	if evs[0].Handler != "NeMo.Intf.eth1" || evs[0].Reason != "changed" || evs[0].Attributes["Status"] != false {
+		t.Fatalf("unexpected event: %+v", evs[0])
+	}
+	if evs, err := ParseEvents([]byte(`{"status":[]}`)); err != nil || len(evs) != 0 {
+		t.Fatalf("expected empty poll, got %v %v", evs, err)
+	}
+	if _, err := ParseEvents([]byte(`{"errors":[{"error":196640,"description":"Invalid channel"}]}`)); err == nil || errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("expected a channel error, got %v", err)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		ev             Event
+		service, event string
+	}{
+		{Event{Handler: "NeMo.Intf.eth1", Reason: "changed", Attributes: map[string]interface{}{"Status": false}}, "NeMo", "link_down"},
+		{Event{Handler: "NeMo.Intf.eth1", Reason: "changed", Attributes: map[string]interface{}{"NetDevState": "up"}}, "NeMo", "link_up"},
+		{Event{Handler: "NeMo.Intf.data", Reason: "changed", Attributes: map[string]interface{}{"ConnectionState": "Connected"}}, "NeMo", "wan_connected"},
+		{Event{Handler: "NMC", Reason: "changed", Attributes: map[string]interface{}{"ConnectionState": "Connecting"}}, "NMC", "wan_disconnected"},
+		{Event{Handler: "Devices.Device", Reason: "device_added"}, "Devices", "device_joined"},
+		{Event{Handler: "Devices.Device.AA:BB", Reason: "changed", Attributes: map[string]interface{}{"Active": false}}, "Devices", "device_left"},
+		{Event{Handler: "NeMo.Intf.lan", Reason: "Changed"}, "NeMo", "other"},
+		{Event{Handler: "Devices.Device", Reason: "firmware_specific_reason"}, "Devices", "other"},
+		{Event{}, "unknown", "other"},
+	}
+	for _, tc := range cases {
+		if s, e := Classify(tc.ev); s != tc.service || e != tc.event {
+			t.Fatalf("Classify(%+v) = %s/%s, want %s/%s", tc.ev, s, e, tc.service, tc.event)
+		}
+	}
+}
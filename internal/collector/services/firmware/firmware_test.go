@@ -0,0 +1,39 @@
+package firmware
+
+import "testing"
+
+func TestRequestBodies(t *testing.T) {
+	if RequestBodyLatestVersion() == "" || RequestBodyDeviceInfo() == "" {
+		t.Fatalf("expected non-empty request bodies")
+	}
+}
+
+func TestParseLatestVersion(t *testing.T) {
+	lv, err := ParseLatestVersion([]byte(`{"status":true,"data":{"updateAvailable":true,"currentVersion":"24.02.04.0","newVersion":"24.10.01.0"}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !lv.UpdateAvailable || lv.CurrentVersion != "24.02.04.0" || lv.NewVersion != "24.10.01.0" {
+		t.Fatalf("unexpected result: %+v", lv)
+	}
+	if _, err := ParseLatestVersion([]byte(`{"status":false}`)); err == nil {
+		t.Fatalf("expected error for status=false")
+	}
+	if _, err := ParseLatestVersion([]byte(`not-json`)); err == nil {
+		t.Fatalf("expected error for invalid JSON")
+	}
+}
+
+func TestParseDeviceInfo(t *testing.T) {
+	di, err := ParseDeviceInfo([]byte(`{"status":{"Manufacturer":"ZTE","ModelName":"H369A","SerialNumber":"ZTEEG8GF3H15881",` +
+		`"HardwareVersion":"V1.00-0x2C-0xDC","SoftwareVersion":"V10.C.24.01.00-D","UpTime":4927186,"NumberOfReboots":5}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if di.ModelName != "H369A" || di.SoftwareVersion != "V10.C.24.01.00-D" || di.UpTime != 4927186 {
+		t.Fatalf("unexpected result: %+v", di)
+	}
+	if _, err := ParseDeviceInfo([]byte(`{"errors":[]}`)); err == nil {
+		t.Fatalf("expected error when status is missing")
+	}
+}
@@ -0,0 +1,38 @@
+package firmware
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// RequestBodyDeviceInfo returns the JSON body for DeviceInfo.get.
+func RequestBodyDeviceInfo() string {
+	return `{"service":"DeviceInfo","method":"get","parameters":{}}`
+}
+
+// DeviceInfo holds the identification fields of DeviceInfo.get.
+type DeviceInfo struct {
+	Manufacturer    string  `json:"Manufacturer"`
+	ModelName       string  `json:"ModelName"`
+	ProductClass    string  `json:"ProductClass"`
+	SerialNumber    string  `json:"SerialNumber"`
+	HardwareVersion string  `json:"HardwareVersion"`
+	SoftwareVersion string  `json:"SoftwareVersion"`
+	RescueVersion   string  `json:"RescueVersion"`
+	UpTime          float64 `json:"UpTime"`
+	NumberOfReboots float64 `json:"NumberOfReboots"`
+}
+
+// ParseDeviceInfo decodes a DeviceInfo.get response.
+func ParseDeviceInfo(data []byte) (DeviceInfo, error) {
+	var r struct {
+		Status *DeviceInfo `json:"status"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return DeviceInfo{}, err
+	}
+	if r.Status == nil {
+		return DeviceInfo{}, fmt.Errorf("DeviceInfo.get returned no status")
+	}
+	return *r.Status, nil
+}
@@ -0,0 +1,35 @@
+package firmware
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// RequestBodyLatestVersion returns the JSON body for the web UI's
+// getLatestVersion call which reports whether a firmware update is offered.
+func RequestBodyLatestVersion() string {
+	return `{"service":"SoftwareUpdate","method":"getLatestVersion","parameters":{}}`
+}
+
+// LatestVersion is the typed representation of the getLatestVersion data.
+type LatestVersion struct {
+	UpdateAvailable bool   `json:"updateAvailable"`
+	CurrentVersion  string `json:"currentVersion"`
+	NewVersion      string `json:"newVersion"`
+}
+
+// ParseLatestVersion decodes a getLatestVersion response. It returns an
+// error when the device reports status=false.
+func ParseLatestVersion(data []byte) (LatestVersion, error) {
+	var r struct {
+		Status bool          `json:"status"`
+		Data   LatestVersion `json:"data"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return LatestVersion{}, err
+	}
+	if !r.Status {
+		return LatestVersion{}, fmt.Errorf("getLatestVersion returned status=false")
+	}
+	return r.Data, nil
+}
@@ -0,0 +1,110 @@
+package ipv6
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+)
+
+// RequestBodyNetMaster returns the JSON body for NetMaster.get which reports
+// the global IPv6 enablement and the WAN/prefix modes.
+func RequestBodyNetMaster() string {
+	return `{"service":"NetMaster","method":"get","parameters":{}}`
+}
+
+// RequestBodyPrefixDelegation returns the JSON body reading the DHCPv6
+// prefix delegation client interface (NeMo.Intf.dhcpv6_pdata).
+func RequestBodyPrefixDelegation() string {
+	return `{"service":"NeMo.Intf.dhcpv6_pdata","method":"get","parameters":{}}`
+}
+
+// RequestBodyLANSettings returns the JSON body reading the IPv6 (DHCPv6)
+// configuration of the LAN bridge.
+func RequestBodyLANSettings() string {
+	return `{"service":"NetMaster.LAN.default.Bridge.lan","method":"getIPv6Configuration","parameters":{"Name":"lan"}}`
+}
+
+// Status is the IPv6 part of the NetMaster.get response.
+type Status struct {
+	EnableIPv6     bool   `json:"EnableIPv6"`
+	IPv6PrefixMode string `json:"IPv6PrefixMode"`
+	WANMode        string `json:"WANMode"`
+}
+
+// PrefixDelegation holds the state of the DHCPv6 prefix delegation client.
+type PrefixDelegation struct {
+	Enable              bool    `json:"Enable"`
+	Status              bool    `json:"Status"`
+	DHCPStatus          string  `json:"DHCPStatus"`
+	LastConnectionError string  `json:"LastConnectionError"`
+	Uptime              float64 `json:"Uptime"`
+}
+
+// LANSettings is the IPv6 configuration of the LAN bridge.
+type LANSettings struct {
+	Enable         bool `json:"Enable"`
+	DHCPEnable     bool `json:"DHCPEnable"`
+	DHCPIAPDEnable bool `json:"DHCPIAPDEnable"`
+	DHCPIANAEnable bool `json:"DHCPIANAEnable"`
+}
+
+// ParseStatus decodes a NetMaster.get response.
+func ParseStatus(data []byte) (Status, error) {
+	var r struct {
+		Status *Status `json:"status"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Status{}, err
+	}
+	if r.Status == nil {
+		return Status{}, fmt.Errorf("NetMaster.get returned no status")
+	}
+	return *r.Status, nil
+}
+
+// ParsePrefixDelegation decodes a NeMo.Intf.dhcpv6_pdata get response.
+func ParsePrefixDelegation(data []byte) (PrefixDelegation, error) {
+	var r struct {
+		Status *PrefixDelegation `json:"status"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return PrefixDelegation{}, err
+	}
+	if r.Status == nil {
+		return PrefixDelegation{}, fmt.Errorf("dhcpv6_pdata get returned no status")
+	}
+	return *r.Status, nil
+}
+
+// ParseLANSettings decodes a getIPv6Configuration response. The device
+// returns the settings under "data" with a null status.
+func ParseLANSettings(data []byte) (LANSettings, error) {
+	var r struct {
+		Data *LANSettings `json:"data"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return LANSettings{}, err
+	}
+	if r.Data == nil {
+		return LANSettings{}, fmt.Errorf("getIPv6Configuration returned no data")
+	}
+	return *r.Data, nil
+}
+
+// Bound reports whether the prefix delegation client holds a lease.
+func (p PrefixDelegation) Bound() bool {
+	return p.Status && p.DHCPStatus == "Bound"
+}
+
+// PrefixLength returns the length of a delegated prefix such as
+// "2a02:a470:c275::/48". ok is false for an empty or malformed prefix.
+func PrefixLength(prefix string) (int, bool) {
+	if prefix == "" {
+		return 0, false
+	}
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return 0, false
+	}
+	return p.Bits(), true
+}
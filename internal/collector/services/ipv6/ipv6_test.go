@@ -0,0 +1,61 @@
+package ipv6
+
+import "testing"
+
+func TestRequestBodies(t *testing.T) {
+	for _, b := range []string{RequestBodyNetMaster(), RequestBodyPrefixDelegation(), RequestBodyLANSettings()} {
+		if b == "" {
+			t.Fatalf("expected non-empty request body")
+		}
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	st, err := ParseStatus([]byte(`{"status":{"EnableInterfaces":true,"EnableIPv6":true,"IPv6PrefixMode":"DHCPv6","WANMode":"VDSL_PPP"}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !st.EnableIPv6 || st.IPv6PrefixMode != "DHCPv6" || st.WANMode != "VDSL_PPP" {
+		t.Fatalf("unexpected status: %+v", st)
+	}
+	if _, err := ParseStatus([]byte(`{"status":null}`)); err == nil {
+		t.Fatalf("expected error for null status")
+	}
+}
+
+func TestParsePrefixDelegation(t *testing.T) {
+	pd, err := ParsePrefixDelegation([]byte(`{"status":{"Name":"dhcpv6_pdata","Enable":true,"Status":true,"DHCPStatus":"Bound","Uptime":2420378}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !pd.Bound() || pd.Uptime != 2420378 {
+		t.Fatalf("unexpected prefix delegation: %+v", pd)
+	}
+	if (PrefixDelegation{Status: true, DHCPStatus: "Requesting"}).Bound() {
+		t.Fatalf("expected a requesting client not to be bound")
+	}
+}
+
+func TestParseLANSettings(t *testing.T) {
+	ls, err := ParseLANSettings([]byte(`{"status":null,"data":{"PrefixLength":"0","DHCPEnable":true,"DHCPIAPDEnable":true,"DHCPIANAEnable":false,"Enable":true}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ls.Enable || !ls.DHCPEnable || !ls.DHCPIAPDEnable || ls.DHCPIANAEnable {
+		t.Fatalf("unexpected LAN settings: %+v", ls)
+	}
+	if _, err := ParseLANSettings([]byte(`{"status":null}`)); err == nil {
+		t.Fatalf("expected error for missing data")
+	}
+}
+
+func TestPrefixLength(t *testing.T) {
+	if n, ok := PrefixLength("2a02:a470:c275::/48"); !ok || n != 48 {
+		t.Fatalf("expected 48, got %d ok=%v", n, ok)
+	}
+	for _, in := range []string{"", "2a02:a470:c275::", "garbage/64"} {
+		if _, ok := PrefixLength(in); ok {
+			t.Fatalf("expected %q to be rejected", in)
+		}
+	}
+}
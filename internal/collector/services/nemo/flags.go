@@ -0,0 +1,61 @@
+package nemo
+
+import "strings"
+
+// Interface roles derived from NeMo Flags by Role.
+const (
+	RoleWAN    = "wan"
+	RoleLAN    = "lan"
+	RoleGuest  = "guest"
+	RoleIPTV   = "iptv"
+	RoleVoIP   = "voip"
+	RoleWiFi   = "wifi"
+	RoleBridge = "bridge"
+)
+
+// ReportedFlags are the state flags exported as experia_v10_netdev_flag.
+var ReportedFlags = []string{"up", "ipv4-up", "ipv6-up", "netdev-bound"}
+
+// roleFlags maps flags to roles in order of precedence: an interface that is
+// both a bridge member and tagged iptv is an iptv interface.
+var roleFlags = []struct {
+	role  string
+	flags []string
+}{
+	{RoleWAN, []string{"wan"}},
+	{RoleIPTV, []string{"iptv"}},
+	{RoleVoIP, []string{"voip", "voice"}},
+	{RoleGuest, []string{"guest"}},
+	{RoleWiFi, []string{"wlanradio", "wlanvap", "wifi", "wlan"}},
+	{RoleBridge, []string{"bridge"}},
+	{RoleLAN, []string{"lan", "inbridge", "eth"}},
+}
+
+// Flags is the set of words of a NeMo Flags string such as
+// "enabled netdev eth bcmeth physical wan netdev-up ipv6-up".
+type Flags map[string]bool
+
+// ParseFlags splits one or more space-separated flag strings (Flags and
+// NetDevFlags) into a set.
+func ParseFlags(s ...string) Flags {
+	f := Flags{}
+	for _, str := range s {
+		for _, w := range strings.Fields(str) {
+			f[strings.ToLower(w)] = true
+		}
+	}
+	return f
+}
+
+// Role classifies the interface as wan, iptv, voip, guest, wifi, bridge or
+// lan, or returns "" when no role flag is set.
+func (f Flags) Role() string {
+	for _, r := range roleFlags {
+		for _, flag := range r.flags {
+			if f[flag] {
+				return r.role
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,31 @@
+package nemo
+
+import "testing"
+
+func TestFlagsRole(t *testing.T) {
+	cases := map[string]string{
+		"enabled netdev eth bcmeth physical netdev-monitor statmon ipv4 ipv6 wan netdev-bound netdev-up": RoleWAN,
+		"ptm netdev ipv4 ipv6 wan statmon enabled netdev-bound netdev-up up":                             RoleWAN,
+		"enabled netdev vlan netdev-monitor bcmvlan eth_untagged netdev-bound netdev-up inbridge up":     RoleLAN,
+		"enabled netdev eth bcmeth physical netdev-monitor statmon netdev-bound netdev-up up":            RoleLAN,
+		"enabled bridge netdev ipv4 ipv6 dns-server statmon up":                                          RoleBridge,
+		"enabled bridge netdev guest up":                                                                 RoleGuest,
+		"wlanradio bcmrad penable physical wlanradio-bound":                                              RoleWiFi,
+		"enabled netdev vlan iptv inbridge up":                                                           RoleIPTV,
+		"enabled netdev vlan VOIP up":                                                                    RoleVoIP,
+		"nat-config enabled up":                                                                          "",
+		"":                                                                                               "",
+	}
+	for flags, want := range cases {
+		if got := ParseFlags(flags).Role(); got != want {
+			t.Errorf("Role(%q) = %q, want %q", flags, got, want)
+		}
+	}
+}
+
+func TestParseFlags_MergesNetDevFlags(t *testing.T) {
+	f := ParseFlags("enabled netdev-bound ipv4-up", "up broadcast multicast")
+	if !f["up"] || !f["ipv4-up"] || !f["netdev-bound"] || f["ipv6-up"] {
+		t.Fatalf("unexpected flag set: %v", f)
+	}
+}
@@ -62,3 +62,14 @@ func TestParseNetDevStats_StatusAndTopLevel(t *testing.T) {
 		t.Fatalf("expected error for malformed JSON")
 	}
 }
+
+func TestGetPortParamsFromMIBs_LowerLayersSorted(t *testing.T) {
+	b := []byte(`{"status":{"base":{"PRIM":{"LLIntf":{"ppp_vvdata":{},"dhcpv6_pdata":{}}}}}}`)
+	pp, err := GetPortParamsFromMIBs(b, "PRIM")
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if len(pp.LowerLayers) != 2 || pp.LowerLayers[0] != "dhcpv6_pdata" || pp.SetPort != "dhcpv6_pdata" {
+		t.Fatalf("expected sorted lower layers with SetPort first, got %+v", pp)
+	}
+}
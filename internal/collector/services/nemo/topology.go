@@ -0,0 +1,127 @@
+package nemo
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// TopologyRoots are the NeMo interfaces whose "base" MIB is requested to
+// discover the interface stack. getMIBs returns the root together with every
+// interface below it, so "data" covers the WAN stack (for example
+// data→primdata→ppp_vvdata→vvlan_data→ptm0→dsl0) and "lan" the bridge ports.
+var TopologyRoots = []string{"data", "lan"}
+
+// RequestBodyBase returns the JSON body for getMIBs restricted to the "base"
+// MIB of intf and the interfaces below it.
+func RequestBodyBase(intf string) string {
+	return fmt.Sprintf(`{"service":"NeMo.Intf.%s","method":"getMIBs","parameters":{"mibs":"base"}}`, intf)
+}
+
+// Interface is a NeMo interface from the "base" MIB. Upper and Lower hold the
+// ULIntf and LLIntf names sorted.
+type Interface struct {
+	Name   string   `json:"name"`
+	Enable bool     `json:"enable"`
+	Status bool     `json:"status"`
+	Flags  string   `json:"flags"`
+	Upper  []string `json:"upper"`
+	Lower  []string `json:"lower"`
+}
+
+// Link is an edge of the interface stack: Upper runs on top of Lower.
+type Link struct {
+	Upper string `json:"upper"`
+	Lower string `json:"lower"`
+}
+
+// Topology is the interface stack as served on /api/topology.
+type Topology struct {
+	Interfaces []Interface `json:"interfaces"`
+	Links      []Link      `json:"links"`
+}
+
+// ParseBase decodes the status.base map of a getMIBs response into
+// interfaces sorted by name.
+func ParseBase(data []byte) ([]Interface, error) {
+	var resp struct {
+		Status json.RawMessage `json:"status"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	var status struct {
+		Base map[string]struct {
+			Name   string                     `json:"Name"`
+			Enable bool                       `json:"Enable"`
+			Status bool                       `json:"Status"`
+			Flags  string                     `json:"Flags"`
+			ULIntf map[string]json.RawMessage `json:"ULIntf"`
+			LLIntf map[string]json.RawMessage `json:"LLIntf"`
+		} `json:"base"`
+	}
+	if err := json.Unmarshal(resp.Status, &status); err != nil || status.Base == nil {
+		return nil, fmt.Errorf("getMIBs response has no base MIB")
+	}
+	out := make([]Interface, 0, len(status.Base))
+	for key, b := range status.Base {
+		name := b.Name
+		if name == "" {
+			name = key
+		}
+		out = append(out, Interface{
+			Name:   name,
+			Enable: b.Enable,
+			Status: b.Status,
+			Flags:  b.Flags,
+			Upper:  rawKeys(b.ULIntf),
+			Lower:  rawKeys(b.LLIntf),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// BuildTopology merges interfaces from one or more ParseBase results and
+// derives the deduplicated, sorted set of links from their LLIntf and ULIntf
+// relations. Interfaces only referenced by a relation are not added.
+func BuildTopology(sets ...[]Interface) Topology {
+	byName := map[string]Interface{}
+	links := map[Link]struct{}{}
+	for _, set := range sets {
+		for _, in := range set {
+			byName[in.Name] = in
+			for _, l := range in.Lower {
+				links[Link{Upper: in.Name, Lower: l}] = struct{}{}
+			}
+			for _, u := range in.Upper {
+				links[Link{Upper: u, Lower: in.Name}] = struct{}{}
+			}
+		}
+	}
+	t := Topology{Interfaces: make([]Interface, 0, len(byName)), Links: make([]Link, 0, len(links))}
+	for _, in := range byName {
+		t.Interfaces = append(t.Interfaces, in)
+	}
+	sort.Slice(t.Interfaces, func(i, j int) bool { return t.Interfaces[i].Name < t.Interfaces[j].Name })
+	for l := range links {
+		t.Links = append(t.Links, l)
+	}
+	sort.Slice(t.Links, func(i, j int) bool {
+		if t.Links[i].Upper != t.Links[j].Upper {
+			return t.Links[i].Upper < t.Links[j].Upper
+		}
+		return t.Links[i].Lower < t.Links[j].Lower
+	})
+	return t
+}
+
+// rawKeys returns the keys of m sorted, or an empty slice.
+func rawKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,50 @@
+package nemo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRequestBodyBase(t *testing.T) {
+	want := `{"service":"NeMo.Intf.data","method":"getMIBs","parameters":{"mibs":"base"}}`
+	if got := RequestBodyBase("data"); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestParseBaseAndBuildTopology(t *testing.T) {
+	data, err := ParseBase([]byte(`{"status":{"base":{
+		"data":{"Name":"data","Enable":true,"Status":true,"Flags":"enabled up","ULIntf":{"voip":{"Name":"voip"}},"LLIntf":{"primdata":{"Name":"primdata"}}},
+		"primdata":{"Name":"primdata","Enable":true,"ULIntf":{"data":{"Name":"data"}},"LLIntf":{"ptm0":{"Name":"ptm0"}}},
+		"ptm0":{"Name":"ptm0","Flags":"ptm netdev wan","ULIntf":{"primdata":{},"vvlan_iptv":{}},"LLIntf":{"dsl0":{}}}
+	}}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(data) != 3 || data[0].Name != "data" || !data[0].Status || !reflect.DeepEqual(data[2].Upper, []string{"primdata", "vvlan_iptv"}) {
+		t.Fatalf("unexpected interfaces: %+v", data)
+	}
+	lan, err := ParseBase([]byte(`{"status":{"base":{"lan":{"Name":"lan","LLIntf":{"eth0":{},"eth1":{}}}}}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	topo := BuildTopology(data, lan)
+	if len(topo.Interfaces) != 4 {
+		t.Fatalf("expected 4 interfaces, got %+v", topo.Interfaces)
+	}
+	want := []Link{
+		{"data", "primdata"},
+		{"lan", "eth0"},
+		{"lan", "eth1"},
+		{"primdata", "ptm0"},
+		{"ptm0", "dsl0"},
+		{"voip", "data"},
+		{"vvlan_iptv", "ptm0"},
+	}
+	if !reflect.DeepEqual(topo.Links, want) {
+		t.Fatalf("unexpected links:\n got %+v\nwant %+v", topo.Links, want)
+	}
+	if _, err := ParseBase([]byte(`{"status":true}`)); err == nil {
+		t.Fatalf("expected error for a response without base MIB")
+	}
+}
@@ -1,6 +1,7 @@
 package nemo
 
 import (
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -11,6 +12,7 @@ type MIBInfo struct {
 	Candidate           string
 	Alias               string
 	Flags               string
+	NetDevFlags         string
 	LLAddress           string
 	MTU                 float64
 	TxQueueLen          float64
@@ -51,7 +53,10 @@ type NetDevStats struct {
 // PortParams contains port parameters commonly pulled from a getMIBs response
 // for WAN/ethernet port pages (MaxBitRate*, Duplex, LLIntf mapping etc.).
 type PortParams struct {
-	LLIntf              string
+	LLIntf string
+	// LowerLayers lists every lower-layer interface of the candidate from
+	// status.base.<CANDIDATE>.LLIntf, sorted by name. SetPort is the first.
+	LowerLayers         []string
 	CurrentBitRate      float64
 	MaxBitRateSupported float64
 	MaxBitRateEnabled   float64
@@ -161,6 +166,9 @@ func GetMIBsTyped(data []byte, candidate string) (MIBInfo, map[string]any, error
 	if v, ok := readString(norm, "flags"); ok {
 		mi.Flags = v
 	}
+	if v, ok := readString(norm, "netdevflags"); ok {
+		mi.NetDevFlags = v
+	}
 	if v, ok := readString(norm, "lladdress"); ok {
 		mi.LLAddress = v
 	}
@@ -267,8 +275,9 @@ func GetPortParamsFromMIBs(data []byte, candidate string) (PortParams, error) {
 		pp.DuplexModeEnabled = b
 	}
 	// Attempt to extract SetPort from the raw status map when available. The
-	// UI code often inspects status.base.<CANDIDATE>.LLIntf and takes the
-	// first key to determine the device mapping; replicate that behavior.
+	// UI code inspects status.base.<CANDIDATE>.LLIntf and takes the first
+	// key to determine the device mapping; keys are sorted so the choice is
+	// stable when an interface has more than one lower layer.
 	if s != nil {
 		if baseRaw, ok := s["base"].(map[string]any); ok {
 			// try candidate key
@@ -276,12 +285,14 @@ func GetPortParamsFromMIBs(data []byte, candidate string) (PortParams, error) {
 				if llv, ok := candRaw["LLIntf"]; ok {
 					switch tv := llv.(type) {
 					case map[string]any:
-						for k := range tv {
-							pp.SetPort = k
-							break
-						}
+						pp.LowerLayers = sortedKeys(tv)
 					case string:
-						pp.SetPort = tv
+						if tv != "" {
+							pp.LowerLayers = []string{tv}
+						}
+					}
+					if len(pp.LowerLayers) > 0 {
+						pp.SetPort = pp.LowerLayers[0]
 					}
 				}
 			}
@@ -289,3 +300,13 @@ func GetPortParamsFromMIBs(data []byte, candidate string) (PortParams, error) {
 	}
 	return pp, nil
 }
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -11,12 +11,16 @@ func RequestBody() string {
 type WANStatus struct {
 	Status bool `json:"status"`
 	Data   struct {
-		LinkType        string `json:"LinkType"`
-		LinkState       string `json:"LinkState"`
-		MACAddress      string `json:"MACAddress"`
-		Protocol        string `json:"Protocol"`
-		ConnectionState string `json:"ConnectionState"`
-		IPAddress       string `json:"IPAddress"`
+		LinkType            string `json:"LinkType"`
+		LinkState           string `json:"LinkState"`
+		MACAddress          string `json:"MACAddress"`
+		Protocol            string `json:"Protocol"`
+		ConnectionState     string `json:"ConnectionState"`
+		IPAddress           string `json:"IPAddress"`
+		RemoteGateway       string `json:"RemoteGateway"`
+		DNSServers          string `json:"DNSServers"`
+		IPv6Address         string `json:"IPv6Address"`
+		IPv6DelegatedPrefix string `json:"IPv6DelegatedPrefix"`
 	} `json:"data"`
 	Errors []struct {
 		Error       int    `json:"error"`
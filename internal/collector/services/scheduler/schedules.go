@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RequestBodyCompleteSchedules returns the JSON body for
+// Scheduler.getCompleteSchedules which lists the internet access (time of
+// day) schedules of all devices.
+func RequestBodyCompleteSchedules() string {
+	return `{"service":"Scheduler","method":"getCompleteSchedules","parameters":{"type":"ToD"}}`
+}
+
+// Range is a part of the week in which State applies. Begin and End are
+// seconds since Monday 00:00 in router local time.
+type Range struct {
+	State string `json:"state"`
+	Begin int64  `json:"begin"`
+	End   int64  `json:"end"`
+}
+
+// Schedule is the access schedule of one device, keyed by its MAC address.
+type Schedule struct {
+	ID                string  `json:"ID"`
+	Name              string  `json:"name"`
+	Enable            bool    `json:"enable"`
+	Base              string  `json:"base"`
+	Default           string  `json:"def"`
+	Override          string  `json:"override"`
+	TemporaryOverride bool    `json:"temporaryOverride"`
+	Value             string  `json:"value"`
+	Ranges            []Range `json:"schedule"`
+}
+
+const week = 7 * 24 * 60 * 60
+
+// weekOffset returns the seconds since Monday 00:00 of t in t's location.
+func weekOffset(t time.Time) int64 {
+	day := (int64(t.Weekday()) + 6) % 7 // Monday = 0
+	return day*24*60*60 + int64(t.Hour()*3600+t.Minute()*60+t.Second())
+}
+
+// StateAt returns the access state ("Enable" or "Disable") of the schedule at
+// t, which must be in router local time. An override wins over the weekly
+// ranges; outside the ranges the default state applies.
+func (s Schedule) StateAt(t time.Time) string {
+	if s.Override != "" {
+		return s.Override
+	}
+	now := weekOffset(t)
+	for _, r := range s.Ranges {
+		begin, end := r.Begin%week, r.End%week
+		in := now >= begin && now < end
+		if end < begin { // wraps past Sunday midnight
+			in = now >= begin || now < end
+		}
+		if in {
+			return r.State
+		}
+	}
+	if s.Default == "" {
+		return "Enable"
+	}
+	return s.Default
+}
+
+// BlockedAt reports whether the device has no internet access at t. In the
+// web UI override "Disable" is "always blocked" and "Enable" is "always
+// allowed", whether or not the schedule is enabled; the weekly ranges of a
+// disabled schedule never block.
+func (s Schedule) BlockedAt(t time.Time) bool {
+	if s.Override == "" && !s.Enable {
+		return false
+	}
+	return strings.EqualFold(s.StateAt(t), "Disable")
+}
+
+// ParseCompleteSchedules decodes a getCompleteSchedules response. Entries are
+// de-duplicated by ID, keeping the first, as the device may list a MAC more
+// than once.
+func ParseCompleteSchedules(data []byte) ([]Schedule, error) {
+	var resp struct {
+		Status bool `json:"status"`
+		Data   *struct {
+			ScheduleInfo []Schedule `json:"scheduleInfo"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+	if !resp.Status || resp.Data == nil {
+		return nil, fmt.Errorf("getCompleteSchedules returned no schedules")
+	}
+	seen := map[string]bool{}
+	out := make([]Schedule, 0, len(resp.Data.ScheduleInfo))
+	for _, s := range resp.Data.ScheduleInfo {
+		if seen[s.ID] {
+			continue
+		}
+		seen[s.ID] = true
+		out = append(out, s)
+	}
+	return out, nil
+}
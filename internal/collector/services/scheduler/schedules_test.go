@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRequestBodyCompleteSchedules(t *testing.T) {
+	if RequestBodyCompleteSchedules() != `{"service":"Scheduler","method":"getCompleteSchedules","parameters":{"type":"ToD"}}` {
+		t.Fatalf("unexpected body: %s", RequestBodyCompleteSchedules())
+	}
+}
+
+func TestParseCompleteSchedules(t *testing.T) {
+	s, err := ParseCompleteSchedules([]byte(`{"status":true,"data":{"scheduleInfo":[
+		{"ID":"C4:E5:32:12:A8:62","enable":false,"def":"Enable","override":"","schedule":[]},
+		{"ID":"E8:93:63:00:1D:21","enable":true,"def":"Enable","override":"Disable","schedule":[]},
+		{"ID":"C4:E5:32:12:A8:62","enable":true,"def":"Enable","override":"","schedule":[]}
+	]}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(s) != 2 || s[0].ID != "C4:E5:32:12:A8:62" || s[0].Enable {
+		t.Fatalf("expected de-duplicated schedules keeping the first entry, got %+v", s)
+	}
+	if _, err := ParseCompleteSchedules([]byte(`{"status":false}`)); err == nil {
+		t.Fatalf("expected error for status false")
+	}
+}
+
+func TestBlockedAt(t *testing.T) {
+	cet := time.FixedZone("CET", 3600)
+	// Block weekdays 21:00-07:00 on Monday night and Sunday night (wrapping).
+	s := Schedule{Enable: true, Default: "Enable", Ranges: []Range{
+		{State: "Disable", Begin: 21 * 3600, End: 24*3600 + 7*3600},
+		{State: "Disable", Begin: 6*86400 + 22*3600, End: 1 * 3600},
+	}}
+	cases := []struct {
+		at      time.Time
+		blocked bool
+	}{
+		{time.Date(2024, 5, 27, 20, 59, 0, 0, cet), false}, // Monday 20:59
+		{time.Date(2024, 5, 27, 21, 0, 0, 0, cet), true},   // Monday 21:00
+		{time.Date(2024, 5, 28, 6, 59, 0, 0, cet), true},   // Tuesday 06:59
+		{time.Date(2024, 5, 28, 7, 0, 0, 0, cet), false},   // Tuesday 07:00
+		{time.Date(2024, 6, 2, 23, 0, 0, 0, cet), true},    // Sunday 23:00
+		{time.Date(2024, 6, 3, 0, 30, 0, 0, cet), true},    // Monday 00:30, wrapped
+		{time.Date(2024, 6, 3, 1, 0, 0, 0, cet), false},    // Monday 01:00
+	}
+	for _, tc := range cases {
+		if got := s.BlockedAt(tc.at); got != tc.blocked {
+			t.Fatalf("BlockedAt(%v) = %v, want %v", tc.at, got, tc.blocked)
+		}
+	}
+
+	monday := time.Date(2024, 5, 27, 12, 0, 0, 0, cet)
+	if !(Schedule{Enable: true, Override: "Disable"}).BlockedAt(monday) {
+		t.Fatalf("expected override Disable to block")
+	}
+	if (Schedule{Enable: true, Override: "Enable", Ranges: s.Ranges}).BlockedAt(time.Date(2024, 5, 27, 22, 0, 0, 0, cet)) {
+		t.Fatalf("expected override Enable to allow")
+	}
+	// "Block now" on a disabled schedule still blocks, as in the web UI.
+	if !(Schedule{Enable: false, Override: "Disable"}).BlockedAt(monday) {
+		t.Fatalf("expected override Disable to block a disabled schedule")
+	}
+	if (Schedule{Enable: false, Default: "Enable", Ranges: s.Ranges}).BlockedAt(time.Date(2024, 5, 27, 22, 0, 0, 0, cet)) {
+		t.Fatalf("expected the ranges of a disabled schedule not to block")
+	}
+	if (Schedule{Enable: true}).BlockedAt(monday) {
+		t.Fatalf("expected an empty schedule not to block")
+	}
+}
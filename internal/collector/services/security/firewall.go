@@ -0,0 +1,135 @@
+package security
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// RequestBodyFirewallLevel returns the JSON body for Firewall.getFirewallLevel.
+func RequestBodyFirewallLevel() string {
+	return `{"service":"Firewall","method":"getFirewallLevel","parameters":{}}`
+}
+
+// RequestBodyDMZ returns the JSON body for Firewall.getDMZ.
+func RequestBodyDMZ() string {
+	return `{"service":"Firewall","method":"getDMZ","parameters":{}}`
+}
+
+// RequestBodyPortForwarding returns the JSON body listing the IPv4
+// port-forwarding rules created through the web UI.
+func RequestBodyPortForwarding() string {
+	return `{"service":"Firewall","method":"getPortForwarding","parameters":{"origin":"webui"}}`
+}
+
+// RequestBodyPinholes returns the JSON body listing the IPv6 open ports
+// (pinholes) created through the web UI.
+func RequestBodyPinholes() string {
+	return `{"service":"Firewall","method":"getPinhole","parameters":{"origin":"webui"}}`
+}
+
+// RequestBodyRespondToPing returns the JSON body for the WAN ping response
+// settings of the data interface.
+func RequestBodyRespondToPing() string {
+	return `{"service":"Firewall","method":"getRespondToPing","parameters":{"sourceInterface":"data"}}`
+}
+
+// Rule is a firewall entry as returned by getDMZ, getPortForwarding and
+// getPinhole. Not every field is present for every rule type.
+type Rule struct {
+	ID                   string `json:"Id"`
+	Origin               string `json:"Origin"`
+	Description          string `json:"Description"`
+	Status               string `json:"Status"`
+	SourceInterface      string `json:"SourceInterface"`
+	Protocol             string `json:"Protocol"`
+	ExternalPort         string `json:"ExternalPort"`
+	InternalPort         string `json:"InternalPort"`
+	DestinationPort      string `json:"DestinationPort"`
+	DestinationIPAddress string `json:"DestinationIPAddress"`
+	Enable               bool   `json:"Enable"`
+}
+
+// RespondToPing holds the WAN ping response settings.
+type RespondToPing struct {
+	EnableIPv4 bool `json:"enableIPv4"`
+	EnableIPv6 bool `json:"enableIPv6"`
+}
+
+// ParseFirewallLevel returns the firewall level string (for example "Medium"
+// or "Custom") from a getFirewallLevel response.
+func ParseFirewallLevel(data []byte) (string, error) {
+	var r struct {
+		Status string `json:"status"`
+	}
+	if len(data) == 0 {
+		return "", nil
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return "", err
+	}
+	return r.Status, nil
+}
+
+// ParseRules decodes the map of rules keyed by Id returned by getDMZ,
+// getPortForwarding and getPinhole. Rules are returned sorted by Id; an
+// empty Id is filled from the map key.
+func ParseRules(data []byte) ([]Rule, error) {
+	var r struct {
+		Status map[string]Rule `json:"status"`
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	rules := make([]Rule, 0, len(r.Status))
+	for id, rule := range r.Status {
+		if rule.ID == "" {
+			rule.ID = id
+		}
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules, nil
+}
+
+// ParseRespondToPing decodes a getRespondToPing response.
+func ParseRespondToPing(data []byte) (RespondToPing, error) {
+	var r struct {
+		Status RespondToPing `json:"status"`
+	}
+	if len(data) == 0 {
+		return r.Status, nil
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r.Status, err
+	}
+	return r.Status, nil
+}
+
+// Protocols maps the device protocol field (IANA numbers, comma-separated,
+// for example "6,17") to lowercase names. An empty field means any protocol.
+func (r Rule) Protocols() []string {
+	if strings.TrimSpace(r.Protocol) == "" {
+		return []string{"any"}
+	}
+	var out []string
+	for _, p := range strings.Split(r.Protocol, ",") {
+		switch p = strings.TrimSpace(p); p {
+		case "6":
+			out = append(out, "tcp")
+		case "17":
+			out = append(out, "udp")
+		case "1":
+			out = append(out, "icmp")
+		case "58":
+			out = append(out, "icmpv6")
+		case "":
+		default:
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package security
+
+import (
+	"reflect"
+	"testing"
+)
+
+const samplePinholes = `{"status":{"webui_1":{"Id":"webui_1","Origin":"webui","Description":"HTTPS","Status":"Disabled",` +
+	`"SourceInterface":"data","Protocol":"17","IPVersion":6,"DestinationPort":"443",` +
+	`"DestinationIPAddress":"2001:db8:3333:4444:5555:6666:7777:8888","Enable":false},` +
+	`"a_rule":{"Protocol":"6,17","DestinationPort":"22","Enable":true}}}`
+
+func TestRequestBodies(t *testing.T) {
+	for _, b := range []string{RequestBodyFirewallLevel(), RequestBodyDMZ(), RequestBodyPortForwarding(),
+		RequestBodyPinholes(), RequestBodyRespondToPing(), RequestBodyMACFiltering()} {
+		if b == "" {
+			t.Fatalf("expected non-empty request body")
+		}
+	}
+}
+
+func TestParseFirewallLevel(t *testing.T) {
+	level, err := ParseFirewallLevel([]byte(`{"status":"Custom"}`))
+	if err != nil || level != "Custom" {
+		t.Fatalf("unexpected level %q err=%v", level, err)
+	}
+	if _, err := ParseFirewallLevel([]byte("not-json")); err == nil {
+		t.Fatalf("expected error for invalid JSON")
+	}
+}
+
+func TestParseRules_SortedWithIDFromKey(t *testing.T) {
+	rules, err := ParseRules([]byte(samplePinholes))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 2 || rules[0].ID != "a_rule" || rules[1].ID != "webui_1" {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+	if rules[1].Enable || rules[1].DestinationPort != "443" {
+		t.Fatalf("unexpected webui_1 rule: %+v", rules[1])
+	}
+	if empty, err := ParseRules([]byte(`{"status":{}}`)); err != nil || len(empty) != 0 {
+		t.Fatalf("expected no rules, got %+v %v", empty, err)
+	}
+}
+
+func TestRuleProtocols(t *testing.T) {
+	cases := map[string][]string{
+		"6":     {"tcp"},
+		"6,17":  {"tcp", "udp"},
+		"":      {"any"},
+		"47":    {"47"},
+		"1, 58": {"icmp", "icmpv6"},
+	}
+	for in, want := range cases {
+		if got := (Rule{Protocol: in}).Protocols(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Protocols(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestParseRespondToPing(t *testing.T) {
+	rp, err := ParseRespondToPing([]byte(`{"status":{"enableIPv4":true,"enableIPv6":false}}`))
+	if err != nil || !rp.EnableIPv4 || rp.EnableIPv6 {
+		t.Fatalf("unexpected result %+v err=%v", rp, err)
+	}
+}
+
+func TestParseMACFiltering(t *testing.T) {
+	data := `{"status":{"wlanvap":{` +
+		`"vap5g0priv":{"SSID":"KPN","MACFiltering":{"Mode":"WhiteList","Entry":{"1":{"MACAddress":"C0:D7:AA:25:AA:0F"},"2":{"MACAddress":"C0:D7:AA:89:7A:A8"}}}},` +
+		`"vap2g0backhaul":{"SSID":"BH","MACFiltering":{"Mode":"Off","Entry":{"1":{"MACAddress":"C0:D7:AA:25:AA:0F"}}}}}}}`
+	filters, err := ParseMACFiltering([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(filters) != 2 || filters[0].VAP != "vap2g0backhaul" {
+		t.Fatalf("unexpected filters: %+v", filters)
+	}
+	if filters[0].Enabled() || !filters[1].Enabled() || filters[1].Entries != 2 {
+		t.Fatalf("unexpected filter state: %+v", filters)
+	}
+}
@@ -0,0 +1,58 @@
+package security
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// RequestBodyMACFiltering returns the JSON body reading the wireless access
+// points (wlanvap MIB) including their MAC filtering configuration.
+func RequestBodyMACFiltering() string {
+	return `{"service":"NeMo.Intf.lan","method":"getMIBs","parameters":{"mibs":"wlanvap"}}`
+}
+
+// MACFilter is the MAC filtering configuration of one wireless access point.
+type MACFilter struct {
+	VAP     string
+	SSID    string
+	Mode    string
+	Entries int
+}
+
+// ParseMACFiltering extracts the MAC filtering mode and entry count of each
+// access point from a wlanvap getMIBs response, sorted by access point name.
+func ParseMACFiltering(data []byte) ([]MACFilter, error) {
+	var r struct {
+		Status struct {
+			WLANVAP map[string]struct {
+				SSID         string `json:"SSID"`
+				MACFiltering struct {
+					Mode  string         `json:"Mode"`
+					Entry map[string]any `json:"Entry"`
+				} `json:"MACFiltering"`
+			} `json:"wlanvap"`
+		} `json:"status"`
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	out := make([]MACFilter, 0, len(r.Status.WLANVAP))
+	for name, vap := range r.Status.WLANVAP {
+		out = append(out, MACFilter{
+			VAP:     name,
+			SSID:    vap.SSID,
+			Mode:    vap.MACFiltering.Mode,
+			Entries: len(vap.MACFiltering.Entry),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].VAP < out[j].VAP })
+	return out, nil
+}
+
+// Enabled reports whether MAC filtering is active on the access point.
+func (f MACFilter) Enabled() bool {
+	return f.Mode != "" && f.Mode != "Off"
+}
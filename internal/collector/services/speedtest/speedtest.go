@@ -0,0 +1,99 @@
+package speedtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Directions supported by the SpeedTest.Diagnostics services, in the order a
+// run executes them.
+var Directions = []string{"download", "upload"}
+
+func service(direction string) string {
+	if direction == "upload" {
+		return "SpeedTest.Diagnostics.Upload"
+	}
+	return "SpeedTest.Diagnostics.Download"
+}
+
+// RequestBodyRun returns the JSON body starting a speed test in direction
+// ("download" or "upload").
+func RequestBodyRun(direction string) string {
+	return fmt.Sprintf(`{"service":"%s","method":"runDiagnostics","parameters":""}`, service(direction))
+}
+
+// RequestBodyGet returns the JSON body reading the last result of direction.
+func RequestBodyGet(direction string) string {
+	return fmt.Sprintf(`{"service":"%s","method":"get","parameters":{}}`, service(direction))
+}
+
+// Result is a speed test result. Throughput is reported in kbit/s, Latency
+// in microseconds and Duration in milliseconds.
+type Result struct {
+	StartTS    string `json:"RetrievedStartTS"`
+	EndTS      string `json:"RetrievedTS"`
+	TestServer string `json:"testserver"`
+	Interface  string `json:"interface"`
+	Suite      string `json:"suite"`
+	Latency    int64  `json:"latency"`
+	Duration   int64  `json:"duration"`
+	Bytes      int64  `json:"rxbytes"`
+	Throughput int64  `json:"throughput"`
+}
+
+// Start returns the start time of the test, or the zero time when the
+// device did not report one.
+func (r Result) Start() time.Time { return parseTS(r.StartTS) }
+
+// End returns the completion time of the test, or the zero time while the
+// test is still running.
+func (r Result) End() time.Time { return parseTS(r.EndTS) }
+
+func parseTS(s string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// BitsPerSecond returns the throughput in bit/s.
+func (r Result) BitsPerSecond() float64 { return float64(r.Throughput) * 1000 }
+
+// LatencySeconds returns the latency in seconds.
+func (r Result) LatencySeconds() float64 { return float64(r.Latency) / 1e6 }
+
+// DurationSeconds returns the test duration in seconds.
+func (r Result) DurationSeconds() float64 { return float64(r.Duration) / 1e3 }
+
+// ParseResult decodes a runDiagnostics or get response. complete is false
+// when the device accepted the request but has no finished result yet, or
+// when the result started before since: the device keeps the previous
+// result until the new test finishes.
+func ParseResult(data []byte, since time.Time) (r Result, complete bool, err error) {
+	var resp struct {
+		Status json.RawMessage `json:"status"`
+		Errors []struct {
+			Description string `json:"description"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return Result{}, false, err
+	}
+	if len(resp.Errors) > 0 {
+		return Result{}, false, fmt.Errorf("speed test failed: %s", resp.Errors[0].Description)
+	}
+	// runDiagnostics may answer with a bare true/false while the test runs.
+	var started bool
+	if json.Unmarshal(resp.Status, &started) == nil {
+		if !started {
+			return Result{}, false, fmt.Errorf("speed test was not started")
+		}
+		return Result{}, false, nil
+	}
+	if err := json.Unmarshal(resp.Status, &r); err != nil {
+		return Result{}, false, err
+	}
+	return r, !r.End().IsZero() && !r.Start().Before(since), nil
+}
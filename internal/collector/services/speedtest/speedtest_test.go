@@ -0,0 +1,52 @@
+package speedtest
+
+import (
+	"testing"
+	"time"
+)
+
+const mockResult = `{"status":{"RetrievedStartTS":"2024-01-24T12:03:38.106036Z","RetrievedTS":"2024-01-24T12:03:49.100036Z","testserver":"192.168.2.103","interface":"data","latency":9401,"suite":"BCMSpeedSvc","duration":10994,"rxbytes":101764920,"throughput":74051}}`
+
+func TestRequestBodies(t *testing.T) {
+	if got := RequestBodyRun("upload"); got != `{"service":"SpeedTest.Diagnostics.Upload","method":"runDiagnostics","parameters":""}` {
+		t.Fatalf("unexpected upload body: %s", got)
+	}
+	if got := RequestBodyGet("download"); got != `{"service":"SpeedTest.Diagnostics.Download","method":"get","parameters":{}}` {
+		t.Fatalf("unexpected download body: %s", got)
+	}
+}
+
+func TestParseResult(t *testing.T) {
+	r, complete, err := ParseResult([]byte(mockResult), time.Date(2024, 1, 24, 12, 3, 38, 0, time.UTC))
+	if err != nil || !complete {
+		t.Fatalf("expected a complete result, got complete=%v err=%v", complete, err)
+	}
+	if r.BitsPerSecond() != 74051000 || r.LatencySeconds() != 0.009401 || r.DurationSeconds() != 10.994 {
+		t.Fatalf("unexpected conversions: %+v", r)
+	}
+	if !r.End().Equal(time.Date(2024, 1, 24, 12, 3, 49, 100036000, time.UTC)) {
+		t.Fatalf("unexpected end time: %v", r.End())
+	}
+}
+
+func TestParseResult_Previous(t *testing.T) {
+	// A result of an earlier run is not the result of the test started at 12:05.
+	if _, complete, err := ParseResult([]byte(mockResult), time.Date(2024, 1, 24, 12, 5, 0, 0, time.UTC)); err != nil || complete {
+		t.Fatalf("expected the previous result to be incomplete, got complete=%v err=%v", complete, err)
+	}
+}
+
+func TestParseResult_Pending(t *testing.T) {
+	if _, complete, err := ParseResult([]byte(`{"status":true}`), time.Time{}); err != nil || complete {
+		t.Fatalf("expected a pending result, got complete=%v err=%v", complete, err)
+	}
+	if _, complete, err := ParseResult([]byte(`{"status":{"throughput":0}}`), time.Time{}); err != nil || complete {
+		t.Fatalf("expected an incomplete result, got complete=%v err=%v", complete, err)
+	}
+	if _, _, err := ParseResult([]byte(`{"status":false}`), time.Time{}); err == nil {
+		t.Fatalf("expected error when the test was not started")
+	}
+	if _, _, err := ParseResult([]byte(`{"status":null,"errors":[{"description":"Busy"}]}`), time.Time{}); err == nil {
+		t.Fatalf("expected error from device errors")
+	}
+}
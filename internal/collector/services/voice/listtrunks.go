@@ -0,0 +1,67 @@
+package voice
+
+import "encoding/json"
+
+// RequestBody returns the JSON body used to list the SIP trunks and their
+// lines (the web UI's getPhone call).
+func RequestBody() string {
+	return `{"service":"VoiceService.VoiceApplication","method":"listTrunks","parameters":{}}`
+}
+
+// SIPServers holds the registrar and proxy settings of a SIP trunk.
+type SIPServers struct {
+	ProxyServer             string  `json:"proxyServer"`
+	ProxyServerPort         float64 `json:"proxyServerPort"`
+	RegistrarServer         string  `json:"registrarServer"`
+	RegistrarServerPort     float64 `json:"registrarServerPort"`
+	OutboundProxyServer     string  `json:"outboundProxyServer"`
+	OutboundProxyServerPort float64 `json:"outboundProxyServerPort"`
+	UserAgentDomain         string  `json:"userAgentDomain"`
+}
+
+// Line is a single telephone line configured on a SIP trunk.
+type Line struct {
+	Name            string `json:"name"`
+	Enable          string `json:"enable"`
+	Status          string `json:"status"`
+	StatusInfo      string `json:"statusInfo"`
+	DirectoryNumber string `json:"directoryNumber"`
+	FriendlyName    string `json:"friendlyName"`
+}
+
+// Trunk is a SIP trunk as returned by listTrunks.
+type Trunk struct {
+	Name              string     `json:"name"`
+	TrunkName         string     `json:"trunkName"`
+	SignalingProtocol string     `json:"signalingProtocol"`
+	Enable            string     `json:"enable"`
+	Lines             []Line     `json:"trunk_lines"`
+	SIP               SIPServers `json:"sip"`
+}
+
+// Trunks is the typed representation of the listTrunks response.
+type Trunks struct {
+	Status []Trunk `json:"status"`
+}
+
+// ParseTrunks unmarshals the raw listTrunks response into the typed struct.
+func ParseTrunks(data []byte) (Trunks, error) {
+	var t Trunks
+	if len(data) == 0 {
+		return t, nil
+	}
+	if err := json.Unmarshal(data, &t); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// Enabled reports whether the line is administratively enabled.
+func (l Line) Enabled() bool {
+	return l.Enable == "Enabled"
+}
+
+// Up reports whether the line is registered with the SIP registrar.
+func (l Line) Up() bool {
+	return l.Status == "Up"
+}
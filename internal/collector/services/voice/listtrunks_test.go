@@ -0,0 +1,47 @@
+package voice
+
+import "testing"
+
+const sampleTrunks = `{"status":[` +
+	`{"name":"SIP-Trunk1","trunkName":"SIP-Trunk1","signalingProtocol":"SIP","enable":"Enabled",` +
+	`"trunk_lines":[{"name":"LINE11","enable":"Enabled","status":"Up","statusInfo":"ResetTimer","directoryNumber":"+31793608718"}],` +
+	`"sip":{"proxyServer":"ims.imscore.net","proxyServerPort":5060,"registrarServer":"ims.imscore.net","registrarServerPort":5060,"outboundProxyServer":"145.7.97.60","outboundProxyServerPort":5060}},` +
+	`{"name":"SIP-Trunk2","trunkName":"webui_2","signalingProtocol":"SIP","enable":"Disabled",` +
+	`"trunk_lines":[{"name":"LINE21","enable":"Disabled","status":"Disabled","statusInfo":"NoResponse","directoryNumber":""}],` +
+	`"sip":{"proxyServer":"","registrarServer":""}}]}`
+
+func TestRequestBody(t *testing.T) {
+	if body := RequestBody(); body == "" {
+		t.Fatalf("expected non-empty request body")
+	}
+}
+
+func TestParseTrunks(t *testing.T) {
+	tr, err := ParseTrunks([]byte(sampleTrunks))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tr.Status) != 2 {
+		t.Fatalf("expected 2 trunks, got %d", len(tr.Status))
+	}
+	t1 := tr.Status[0]
+	if t1.SIP.RegistrarServer != "ims.imscore.net" || t1.SIP.OutboundProxyServer != "145.7.97.60" {
+		t.Fatalf("unexpected sip servers: %+v", t1.SIP)
+	}
+	if len(t1.Lines) != 1 || !t1.Lines[0].Up() || !t1.Lines[0].Enabled() {
+		t.Fatalf("expected LINE11 up and enabled: %+v", t1.Lines)
+	}
+	l2 := tr.Status[1].Lines[0]
+	if l2.Up() || l2.Enabled() {
+		t.Fatalf("expected LINE21 down and disabled: %+v", l2)
+	}
+}
+
+func TestParseTrunks_EmptyAndInvalid(t *testing.T) {
+	if tr, err := ParseTrunks(nil); err != nil || len(tr.Status) != 0 {
+		t.Fatalf("expected empty result for empty input, got %+v %v", tr, err)
+	}
+	if _, err := ParseTrunks([]byte("not-json")); err == nil {
+		t.Fatalf("expected error for invalid JSON")
+	}
+}
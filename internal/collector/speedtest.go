@@ -0,0 +1,216 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	speedtestmetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/speedtest"
+	clock "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/clock"
+	speedtest "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/speedtest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Speed test timing; speedtestTimeout bounds each direction. Tests shorten
+// both.
+var (
+	speedtestTimeout      = 2 * time.Minute
+	speedtestPollInterval = 2 * time.Second
+)
+
+// errSpeedtestRunning is returned when a speed test is requested while one
+// is in progress.
+var errSpeedtestRunning = errors.New("speed test already running")
+
+// speedtestState holds the last results and run counters of the speedtest
+// module. Protected by mu.
+type speedtestState struct {
+	mu        sync.Mutex
+	running   bool
+	lastStart time.Time
+	results   map[string]speedtest.Result
+	runs      map[[2]string]float64
+}
+
+// collectSpeedtest exports the cached speed test results. Tests never run
+// during a scrape; see RunSpeedtests and SpeedtestHandler.
+func (c *Experiav10Collector) collectSpeedtest(_ *scrapeState, ch chan<- prometheus.Metric) {
+	s := &c.speedtest
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, dir := range speedtest.Directions {
+		r, ok := s.results[dir]
+		if ok {
+			ch <- prometheus.MustNewConstMetric(speedtestmetrics.Throughput, prometheus.GaugeValue, r.BitsPerSecond(), dir)
+			ch <- prometheus.MustNewConstMetric(speedtestmetrics.Latency, prometheus.GaugeValue, r.LatencySeconds(), dir)
+			ch <- prometheus.MustNewConstMetric(speedtestmetrics.Duration, prometheus.GaugeValue, r.DurationSeconds(), dir)
+			ch <- prometheus.MustNewConstMetric(speedtestmetrics.LastRun, prometheus.GaugeValue, float64(r.End().Unix()), dir)
+			ch <- prometheus.MustNewConstMetric(speedtestmetrics.Info, prometheus.GaugeValue, 1.0, dir, r.TestServer, r.Interface)
+		}
+		for _, result := range []string{"success", "failure"} {
+			ch <- prometheus.MustNewConstMetric(speedtestmetrics.Runs, prometheus.CounterValue, s.runs[[2]string{dir, result}], dir, result)
+		}
+	}
+}
+
+// RunSpeedtest runs a download and then an upload test and stores the
+// results. It returns errSpeedtestRunning if a test is already in progress.
+func (c *Experiav10Collector) RunSpeedtest(ctx context.Context) error {
+	if _, ok := c.beginSpeedtest(0); !ok {
+		return errSpeedtestRunning
+	}
+	return c.runSpeedtest(ctx)
+}
+
+// beginSpeedtest marks a test as running unless one is in progress or the
+// last one started less than minInterval ago, in which case it returns how
+// long the caller should wait.
+func (c *Experiav10Collector) beginSpeedtest(minInterval time.Duration) (time.Duration, bool) {
+	s := &c.speedtest
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := timeNow()
+	if s.running {
+		return minInterval, false
+	}
+	if wait := minInterval - now.Sub(s.lastStart); !s.lastStart.IsZero() && wait > 0 {
+		return wait, false
+	}
+	s.running = true
+	s.lastStart = now
+	if s.results == nil {
+		s.results = map[string]speedtest.Result{}
+		s.runs = map[[2]string]float64{}
+	}
+	return 0, true
+}
+
+// runSpeedtest performs a test started by beginSpeedtest.
+func (c *Experiav10Collector) runSpeedtest(ctx context.Context) error {
+	s := &c.speedtest
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	// A test takes longer than a scrape request may; use a client without
+	// the scrape timeout sharing transport and cookie jar.
+	client := *c.client
+	client.Timeout = 0
+
+	var errs []error
+	for _, dir := range speedtest.Directions {
+		r, err := c.runSpeedtestDirection(ctx, &client, dir)
+		s.mu.Lock()
+		if err != nil {
+			s.runs[[2]string{dir, "failure"}]++
+			errs = append(errs, fmt.Errorf("%s: %w", dir, err))
+		} else {
+			s.runs[[2]string{dir, "success"}]++
+			s.results[dir] = r
+		}
+		s.mu.Unlock()
+		if err != nil {
+			log.Printf("WARN: %s speed test failed: %v", dir, err)
+			continue
+		}
+		log.Printf("INFO: %s speed test: %d kbit/s, latency %d us", dir, r.Throughput, r.Latency)
+	}
+	return errors.Join(errs...)
+}
+
+// runSpeedtestDirection starts a test and polls until the device reports a
+// result of this test or speedtestTimeout passes, so a slow download leaves
+// the upload its full time.
+func (c *Experiav10Collector) runSpeedtestDirection(ctx context.Context, client *http.Client, dir string) (speedtest.Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, speedtestTimeout)
+	defer cancel()
+	start := c.speedtestStart(ctx, client)
+	resp, err := c.post(ctx, client, speedtest.RequestBodyRun(dir))
+	if err != nil {
+		return speedtest.Result{}, err
+	}
+	countPermissionErrors(string(resp))
+	r, complete, err := speedtest.ParseResult(resp, start)
+	for err == nil && !complete {
+		select {
+		case <-ctx.Done():
+			return speedtest.Result{}, ctx.Err()
+		case <-time.After(speedtestPollInterval):
+		}
+		resp, err = c.post(ctx, client, speedtest.RequestBodyGet(dir))
+		if err != nil {
+			return speedtest.Result{}, err
+		}
+		r, complete, err = speedtest.ParseResult(resp, start)
+	}
+	return r, err
+}
+
+// speedtestStart returns the router time before a test starts; results
+// that started earlier belong to a previous run. The host clock is used
+// when the router time cannot be read.
+func (c *Experiav10Collector) speedtestStart(ctx context.Context, client *http.Client) time.Time {
+	resp, err := c.post(ctx, client, clock.RequestBody())
+	if err == nil {
+		var t time.Time
+		if t, err = clock.ParseTime(resp); err == nil {
+			return t
+		}
+	}
+	log.Printf("WARN: reading the router time before the speed test failed, using the host clock: %v", err)
+	return timeNow().Truncate(time.Second)
+}
+
+// RunSpeedtests runs a speed test every interval until ctx is cancelled. The
+// first test starts after one interval so a restart loop does not saturate
+// the line.
+func (c *Experiav10Collector) RunSpeedtests(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := c.RunSpeedtest(ctx); err != nil && !errors.Is(err, errSpeedtestRunning) {
+				log.Printf("WARN: scheduled speed test failed: %v", err)
+			}
+		}
+	}
+}
+
+// SpeedtestHandler returns a handler that starts a speed test on POST. A new
+// test is refused with 429 Too Many Requests while one runs or until
+// minInterval has passed since the last one started. The test runs in the
+// background; results appear on /metrics once it completes.
+func (c *Experiav10Collector) SpeedtestHandler(minInterval time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if wait, ok := c.beginSpeedtest(minInterval); !ok {
+			if wait < time.Second {
+				wait = time.Second
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait.Round(time.Second).Seconds())))
+			http.Error(w, "speed test rate limited", http.StatusTooManyRequests)
+			return
+		}
+		go func() {
+			if err := c.runSpeedtest(context.Background()); err != nil {
+				log.Printf("WARN: manual speed test failed: %v", err)
+			}
+		}()
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintln(w, "speed test started")
+	})
+}
@@ -0,0 +1,192 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GrammaTonic/experia-v10-exporter/internal/testutil"
+)
+
+// speedtestClock is the router time before each test, shortly before the
+// start of speedtestResult.
+const speedtestClock = `{"status":true,"data":{"time":"Wed, 24 Jan 2024 13:03:30 GMT+0100"}}`
+
+const speedtestResult = `{"status":{"RetrievedStartTS":"2024-01-24T12:03:38.106036Z","RetrievedTS":"2024-01-24T12:03:49.100036Z","testserver":"192.168.2.103","interface":"data","latency":9401,"suite":"BCMSpeedSvc","duration":10994,"rxbytes":101764920,"throughput":74051}}`
+
+func TestRunSpeedtest_PollsUntilComplete(t *testing.T) {
+	orig := speedtestPollInterval
+	speedtestPollInterval = time.Millisecond
+	defer func() { speedtestPollInterval = orig }()
+
+	c := newModuleTestCollector(map[string]string{
+		"getTime":                             speedtestClock,
+		`Download","method":"runDiagnostics"`: speedtestResult,
+		`Upload","method":"runDiagnostics"`:   `{"status":true}`,
+		`Upload","method":"get"`:              speedtestResult,
+	})
+	if err := c.SetModules("speedtest"); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+	// The upload result is only complete on the third poll.
+	polls := 0
+	next := c.client.Transport
+	c.client.Transport = testutil.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		if bytes.Contains(b, []byte(`Upload","method":"get"`)) {
+			if polls++; polls < 3 {
+				return testutil.MakeResp(`{"status":{"throughput":0}}`), nil
+			}
+		}
+		return next.RoundTrip(req)
+	})
+
+	// No test has run yet: only the run counters are exported.
+	mfs := gatherFamilies(t, c)
+	if mfs["speedtest_throughput_bits_per_second"] != nil || mfs["speedtest_runs_total"] == nil {
+		t.Fatalf("expected only run counters before the first test")
+	}
+
+	if err := c.RunSpeedtest(context.Background()); err != nil {
+		t.Fatalf("RunSpeedtest failed: %v", err)
+	}
+	if polls != 3 {
+		t.Fatalf("expected 3 upload polls, got %d", polls)
+	}
+
+	mfs = gatherFamilies(t, c)
+	for _, m := range mfs["speedtest_throughput_bits_per_second"].GetMetric() {
+		if m.GetGauge().GetValue() != 74051000 {
+			t.Fatalf("unexpected throughput for %s: %v", labelValue(m, "direction"), m.GetGauge().GetValue())
+		}
+	}
+	if n := len(mfs["speedtest_throughput_bits_per_second"].GetMetric()); n != 2 {
+		t.Fatalf("expected download and upload throughput, got %d", n)
+	}
+	if v := mfs["speedtest_latency_seconds"].GetMetric()[0].GetGauge().GetValue(); v != 0.009401 {
+		t.Fatalf("unexpected latency %v", v)
+	}
+	if v := mfs["speedtest_last_run_timestamp_seconds"].GetMetric()[0].GetGauge().GetValue(); v != 1706097829 {
+		t.Fatalf("unexpected last run timestamp %v", v)
+	}
+	for _, m := range mfs["speedtest_runs_total"].GetMetric() {
+		want := 0.0
+		if labelValue(m, "result") == "success" {
+			want = 1
+		}
+		if m.GetCounter().GetValue() != want {
+			t.Fatalf("unexpected runs_total for %s/%s", labelValue(m, "direction"), labelValue(m, "result"))
+		}
+	}
+}
+
+func TestRunSpeedtest_Failure(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{
+		"runDiagnostics": `{"status":null,"errors":[{"error":1,"description":"Busy"}]}`,
+	})
+	if err := c.RunSpeedtest(context.Background()); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if c.speedtest.runs[[2]string{"upload", "failure"}] != 1 || len(c.speedtest.results) != 0 {
+		t.Fatalf("expected a counted failure without results")
+	}
+}
+
+func TestRunSpeedtest_IgnoresPreviousResult(t *testing.T) {
+	orig := speedtestPollInterval
+	speedtestPollInterval = time.Millisecond
+	defer func() { speedtestPollInterval = orig }()
+
+	const previous = `{"status":{"RetrievedStartTS":"2024-01-24T06:00:02.5Z","RetrievedTS":"2024-01-24T06:00:13.1Z","throughput":1000}}`
+	c := newModuleTestCollector(map[string]string{
+		"getTime":        speedtestClock,
+		"runDiagnostics": `{"status":true}`,
+		`"method":"get"`: speedtestResult,
+	})
+	// The first poll of each direction still returns the previous run.
+	polls := map[string]int{}
+	next := c.client.Transport
+	c.client.Transport = testutil.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		if bytes.Contains(b, []byte(`"method":"get"`)) {
+			if polls[string(b)]++; polls[string(b)] == 1 {
+				return testutil.MakeResp(previous), nil
+			}
+		}
+		return next.RoundTrip(req)
+	})
+
+	if err := c.RunSpeedtest(context.Background()); err != nil {
+		t.Fatalf("RunSpeedtest failed: %v", err)
+	}
+	for _, dir := range []string{"download", "upload"} {
+		if r := c.speedtest.results[dir]; r.Throughput != 74051 {
+			t.Fatalf("expected the %s result of the new test, got %+v", dir, r)
+		}
+	}
+}
+
+func TestRunSpeedtest_TimeoutPerDirection(t *testing.T) {
+	origTimeout, origPoll := speedtestTimeout, speedtestPollInterval
+	speedtestTimeout, speedtestPollInterval = 50*time.Millisecond, time.Millisecond
+	defer func() { speedtestTimeout, speedtestPollInterval = origTimeout, origPoll }()
+
+	// The download never completes; the upload does on the first poll.
+	c := newModuleTestCollector(map[string]string{
+		"getTime":                           speedtestClock,
+		`Download","method"`:                `{"status":{"throughput":0}}`,
+		`Upload","method":"runDiagnostics"`: `{"status":true}`,
+		`Upload","method":"get"`:            speedtestResult,
+	})
+	if err := c.RunSpeedtest(context.Background()); err == nil {
+		t.Fatalf("expected the download to time out")
+	}
+	if c.speedtest.runs[[2]string{"download", "failure"}] != 1 {
+		t.Fatalf("expected a counted download failure")
+	}
+	if c.speedtest.runs[[2]string{"upload", "success"}] != 1 || c.speedtest.results["upload"].Throughput != 74051 {
+		t.Fatalf("expected the upload to run with its own timeout, got runs %v", c.speedtest.runs)
+	}
+}
+
+func TestSpeedtestHandler_RateLimit(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{"getTime": speedtestClock, "runDiagnostics": speedtestResult})
+	h := c.SpeedtestHandler(time.Hour)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/speedtest", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 for GET, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/speedtest", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", rec.Code)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		c.speedtest.mu.Lock()
+		finished := !c.speedtest.running && len(c.speedtest.results) == 2
+		c.speedtest.mu.Unlock()
+		if finished {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("manual speed test did not complete")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/speedtest", nil))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d", rec.Code)
+	}
+}
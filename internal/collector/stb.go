@@ -0,0 +1,29 @@
+package collector
+
+import (
+	devicemetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/devices"
+	devices "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/devices"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectSTB exports the IPTV receivers known to the device table.
+func (c *Experiav10Collector) collectSTB(_ *scrapeState, ch chan<- prometheus.Metric) {
+	resp := c.moduleFetch(devices.RequestBodySTBs())
+	if resp == nil {
+		return
+	}
+	stbs, err := devices.ParseSTBs(resp)
+	if err != nil {
+		c.moduleParseError("Devices.get", err)
+		return
+	}
+	active := 0
+	for _, d := range stbs {
+		if d.Active {
+			active++
+		}
+		ch <- prometheus.MustNewConstMetric(devicemetrics.STBUp, prometheus.GaugeValue, boolToFloat(d.Active), d.MAC(), d.Name, d.Interface())
+		ch <- prometheus.MustNewConstMetric(devicemetrics.STBInfo, prometheus.GaugeValue, 1.0, d.MAC(), d.IPAddress, d.VendorClassID)
+	}
+	ch <- prometheus.MustNewConstMetric(devicemetrics.STBActive, prometheus.GaugeValue, float64(active))
+}
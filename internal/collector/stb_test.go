@@ -0,0 +1,47 @@
+package collector
+
+import "testing"
+
+func TestCollectSTB(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{
+		"not voice": `{"status":[
+			{"Key":"C4:EB:42:06:90:77","PhysAddress":"C4:EB:42:06:90:77","Name":"living room","DeviceType":"Set-top Box","Active":true,"IPAddress":"192.168.2.15","Layer2Interface":"ETH2","VendorClassID":"IPTV_STB_SGM_DIW7022"},
+			{"Key":"C4:EB:42:06:90:78","PhysAddress":"C4:EB:42:06:90:78","Name":"bedroom","DeviceType":"Set-top Box","Active":false,"Layer2Interface":"ETH3"},
+			{"Key":"AA:BB:CC:DD:EE:FF","PhysAddress":"AA:BB:CC:DD:EE:FF","Name":"laptop","DeviceType":"Laptop","Active":true}
+		]}`,
+	})
+	if err := c.SetModules("stb"); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+	mfs := gatherFamilies(t, c)
+	up := mfs["stb_up"]
+	if up == nil || len(up.GetMetric()) != 2 {
+		t.Fatalf("expected two stb_up series")
+	}
+	for _, m := range up.GetMetric() {
+		want := 0.0
+		if labelValue(m, "name") == "living room" {
+			want = 1
+			if labelValue(m, "interface") != "ETH2" {
+				t.Fatalf("unexpected interface label %q", labelValue(m, "interface"))
+			}
+		}
+		if m.GetGauge().GetValue() != want {
+			t.Fatalf("unexpected stb_up for %s", labelValue(m, "name"))
+		}
+	}
+	if v := mfs["stb_active"].GetMetric()[0].GetGauge().GetValue(); v != 1 {
+		t.Fatalf("expected one active STB, got %v", v)
+	}
+}
+
+func TestCollectSTB_None(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{"not voice": `{"status":[]}`})
+	if err := c.SetModules("stb"); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+	mfs := gatherFamilies(t, c)
+	if mfs["stb_up"] != nil || mfs["stb_active"].GetMetric()[0].GetGauge().GetValue() != 0 {
+		t.Fatalf("expected only stb_active 0 without receivers")
+	}
+}
@@ -0,0 +1,235 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// defaultProbeModuleTimeout is the per-request timeout of a probe module that
+// does not set one.
+const defaultProbeModuleTimeout = 5 * time.Second
+
+// targetIdleTTL is how long the collector of a target that is no longer
+// probed is kept before its session is dropped.
+var targetIdleTTL = time.Hour
+
+// ProbeModule holds the credentials and options used to scrape a router on
+// /probe.
+type ProbeModule struct {
+	Username string
+	Password string
+	Timeout  time.Duration
+	// Modules lists the optional collector modules to run for the target.
+	Modules []string
+	// Targets lists the addresses and CIDR networks the module may probe.
+	// Any other target is refused, so the credentials are never sent to a
+	// host chosen by whoever can reach /probe.
+	Targets []string
+
+	allowed []netip.Prefix
+}
+
+// Targets serves /probe?target=<ip>&module=<name>, scraping routers other
+// than the one configured for /metrics. Every target and module pair gets its
+// own collector, and with it its own cookie jar and session token, which is
+// reused by later probes until the target has been idle for targetIdleTTL.
+type Targets struct {
+	modules map[string]ProbeModule
+	// newCollector builds the collector of a target; tests replace it to
+	// inject a transport.
+	newCollector func(ip net.IP, m ProbeModule) *Experiav10Collector
+
+	mu         sync.Mutex
+	collectors map[targetKey]*targetEntry
+}
+
+type targetKey struct {
+	target string
+	module string
+}
+
+type targetEntry struct {
+	col      *Experiav10Collector
+	lastUsed time.Time
+}
+
+// NewTargets returns a /probe handler for the given probe modules. An
+// unknown collector module name is an error.
+func NewTargets(modules map[string]ProbeModule) (*Targets, error) {
+	checked, err := checkProbeModules(modules)
+	if err != nil {
+		return nil, err
+	}
+	return &Targets{
+		modules:      checked,
+		newCollector: newProbeCollector,
+		collectors:   map[targetKey]*targetEntry{},
+	}, nil
+}
+
+// SetModules replaces the probe modules, for example on a configuration
+// reload. Cached sessions are kept for modules whose settings did not
+// change. On error the current modules are left unchanged.
+func (t *Targets) SetModules(modules map[string]ProbeModule) error {
+	checked, err := checkProbeModules(modules)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for k := range t.collectors {
+		if !reflect.DeepEqual(t.modules[k.module], checked[k.module]) {
+			delete(t.collectors, k)
+		}
+	}
+	t.modules = checked
+	return nil
+}
+
+// checkProbeModules normalizes the collector module names, target allowlist
+// and default timeout of every probe module.
+func checkProbeModules(modules map[string]ProbeModule) (map[string]ProbeModule, error) {
+	checked := make(map[string]ProbeModule, len(modules))
+	for name, m := range modules {
+		enabled, err := normalizeModules(m.Modules)
+		if err != nil {
+			return nil, fmt.Errorf("probe module %q: %w", name, err)
+		}
+		m.Modules = enabled
+		if m.allowed, err = ParseProbeTargets(m.Targets); err != nil {
+			return nil, fmt.Errorf("probe module %q: %w", name, err)
+		}
+		if m.Timeout <= 0 {
+			m.Timeout = defaultProbeModuleTimeout
+		}
+		checked[name] = m
+	}
+	return checked, nil
+}
+
+// ParseProbeTargets parses the target allowlist of a probe module. Every
+// entry is an IP address or a CIDR network, and the list must not be empty.
+func ParseProbeTargets(targets []string) ([]netip.Prefix, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("no targets allowed; list the addresses or networks the module may probe")
+	}
+	allowed := make([]netip.Prefix, 0, len(targets))
+	for _, t := range targets {
+		if addr, err := netip.ParseAddr(t); err == nil {
+			allowed = append(allowed, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(t)
+		if err != nil {
+			return nil, fmt.Errorf("target %q is not an IP address or CIDR network", t)
+		}
+		allowed = append(allowed, prefix.Masked())
+	}
+	return allowed, nil
+}
+
+// allows reports whether the module may probe ip.
+func (m ProbeModule) allows(ip net.IP) bool {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range m.allowed {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// newProbeCollector builds the collector used for one probe target.
+func newProbeCollector(ip net.IP, m ProbeModule) *Experiav10Collector {
+	c := NewCollector(ip, m.Username, m.Password, m.Timeout)
+	c.modules = m.Modules
+	return c
+}
+
+// ModuleNames returns the sorted names of the configured probe modules.
+func (t *Targets) ModuleNames() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	names := make([]string, 0, len(t.modules))
+	for name := range t.modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ServeHTTP scrapes the target of the request with the credentials of its
+// module. Without probe modules it answers 404, so /probe can stay
+// registered while a reload adds or removes them.
+func (t *Targets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if len(t.ModuleNames()) == 0 {
+		http.Error(w, "no probe modules configured", http.StatusNotFound)
+		return
+	}
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "target parameter is missing", http.StatusBadRequest)
+		return
+	}
+	ip := net.ParseIP(target)
+	if ip == nil {
+		http.Error(w, "target must be an IP address", http.StatusBadRequest)
+		return
+	}
+	module := r.URL.Query().Get("module")
+	if module == "" {
+		module = "default"
+	}
+	t.mu.Lock()
+	m, ok := t.modules[module]
+	t.mu.Unlock()
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown module %q (available: %s)", module, strings.Join(t.ModuleNames(), ", ")), http.StatusBadRequest)
+		return
+	}
+	if !m.allows(ip) {
+		http.Error(w, fmt.Sprintf("target %s is not allowed for module %q", target, module), http.StatusForbidden)
+		return
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(t.collector(ip, module, m))
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// collector returns the cached collector for ip and module, creating it on
+// first use, and drops collectors of targets idle for targetIdleTTL.
+func (t *Targets) collector(ip net.IP, module string, m ProbeModule) *Experiav10Collector {
+	now := time.Now()
+	key := targetKey{target: ip.String(), module: module}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for k, e := range t.collectors {
+		if now.Sub(e.lastUsed) > targetIdleTTL {
+			delete(t.collectors, k)
+		}
+	}
+	e, ok := t.collectors[key]
+	if !ok {
+		e = &targetEntry{col: t.newCollector(ip, m)}
+		t.collectors[key] = e
+	}
+	e.lastUsed = now
+	return e.col
+}
@@ -0,0 +1,179 @@
+package collector
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GrammaTonic/experia-v10-exporter/internal/testutil"
+)
+
+// testNet is the target allowlist of the test modules.
+var testNet = []string{"192.0.2.0/24"}
+
+// newTestTargets returns Targets whose collectors answer every call with
+// {"status":true} and count logins per target host.
+func newTestTargets(t *testing.T, modules map[string]ProbeModule) (*Targets, map[string]int, *sync.Mutex) {
+	t.Helper()
+	tg, err := NewTargets(modules)
+	if err != nil {
+		t.Fatalf("NewTargets failed: %v", err)
+	}
+	logins := map[string]int{}
+	var mu sync.Mutex
+	tg.newCollector = func(ip net.IP, m ProbeModule) *Experiav10Collector {
+		c := newProbeCollector(ip, m)
+		c.client.Transport = testutil.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			b, _ := io.ReadAll(req.Body)
+			if bytes.Contains(b, []byte("createContext")) {
+				mu.Lock()
+				logins[req.URL.Host+"/"+m.Username]++
+				mu.Unlock()
+				return testutil.MakeResp(`{"data":{"contextID":"CTX-TEST"}}`), nil
+			}
+			return testutil.MakeResp(`{"status":true}`), nil
+		})
+		return c
+	}
+	return tg, logins, &mu
+}
+
+func probeTarget(tg *Targets, query string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	tg.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe?"+query, nil))
+	return rec
+}
+
+func TestTargets_ReusesSessionPerTarget(t *testing.T) {
+	tg, logins, mu := newTestTargets(t, map[string]ProbeModule{
+		"default":  {Username: "admin", Password: "p", Targets: testNet},
+		"readonly": {Username: "monitor", Password: "q", Targets: testNet},
+	})
+	for i := 0; i < 3; i++ {
+		rec := probeTarget(tg, "target=192.0.2.1")
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "experia_v10_internet_connection") || strings.Contains(rec.Body.String(), "experia_v10_up 0") {
+			t.Fatalf("unexpected probe response %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	probeTarget(tg, "target=192.0.2.2&module=default")
+	probeTarget(tg, "target=192.0.2.1&module=readonly")
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]int{"192.0.2.1/admin": 1, "192.0.2.2/admin": 1, "192.0.2.1/monitor": 1}
+	for k, n := range want {
+		if logins[k] != n {
+			t.Fatalf("expected %d login(s) for %s, got %v", n, k, logins)
+		}
+	}
+	if len(tg.collectors) != 3 {
+		t.Fatalf("expected three cached collectors, got %d", len(tg.collectors))
+	}
+}
+
+func TestTargets_FreshRegistryPerRequest(t *testing.T) {
+	tg, _, _ := newTestTargets(t, map[string]ProbeModule{"default": {Targets: testNet}})
+	probeTarget(tg, "target=192.0.2.1")
+	b := probeTarget(tg, "target=192.0.2.2").Body.String()
+	if strings.Count(b, "experia_v10_wan_ifname{") != 1 {
+		t.Fatalf("expected the series of one collector per response:\n%s", b)
+	}
+}
+
+func TestTargets_InvalidRequests(t *testing.T) {
+	tg, _, _ := newTestTargets(t, map[string]ProbeModule{"default": {Targets: testNet}})
+	for _, q := range []string{"", "target=router.local", "target=192.0.2.1&module=other"} {
+		if rec := probeTarget(tg, q); rec.Code != http.StatusBadRequest {
+			t.Fatalf("query %q: expected 400, got %d", q, rec.Code)
+		}
+	}
+}
+
+func TestTargets_NoModules(t *testing.T) {
+	tg, _, _ := newTestTargets(t, nil)
+	if rec := probeTarget(tg, "target=192.0.2.1"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 without probe modules, got %d", rec.Code)
+	}
+}
+
+func TestTargets_RefusesTargetsOutsideAllowlist(t *testing.T) {
+	tg, logins, mu := newTestTargets(t, map[string]ProbeModule{
+		"default": {Username: "admin", Targets: []string{"192.0.2.1", "2001:db8::/32"}},
+	})
+	for _, target := range []string{"192.0.2.2", "198.51.100.1", "2001:db9::1"} {
+		if rec := probeTarget(tg, "target="+target); rec.Code != http.StatusForbidden {
+			t.Fatalf("target %s: expected 403, got %d", target, rec.Code)
+		}
+	}
+	for _, target := range []string{"192.0.2.1", "::ffff:192.0.2.1", "2001:db8::1"} {
+		if rec := probeTarget(tg, "target="+target); rec.Code != http.StatusOK {
+			t.Fatalf("target %s: expected 200, got %d", target, rec.Code)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if logins["198.51.100.1/admin"] != 0 {
+		t.Fatalf("expected no login to a refused target, got %v", logins)
+	}
+}
+
+func TestParseProbeTargets(t *testing.T) {
+	for _, targets := range [][]string{nil, {"router.local"}, {"192.0.2.0/33"}} {
+		if _, err := ParseProbeTargets(targets); err == nil {
+			t.Errorf("expected %q to be rejected", targets)
+		}
+	}
+}
+
+func TestTargets_DropsIdleTargets(t *testing.T) {
+	tg, _, _ := newTestTargets(t, map[string]ProbeModule{"default": {Targets: testNet}})
+	probeTarget(tg, "target=192.0.2.1")
+	for _, e := range tg.collectors {
+		e.lastUsed = time.Now().Add(-2 * targetIdleTTL)
+	}
+	probeTarget(tg, "target=192.0.2.2")
+	if _, ok := tg.collectors[targetKey{target: "192.0.2.1", module: "default"}]; ok || len(tg.collectors) != 1 {
+		t.Fatalf("expected the idle target to be dropped, got %v", tg.collectors)
+	}
+}
+
+func TestNewTargets_UnknownModule(t *testing.T) {
+	if _, err := NewTargets(map[string]ProbeModule{"default": {Modules: []string{"bogus"}, Targets: testNet}}); err == nil {
+		t.Fatalf("expected an error for an unknown collector module")
+	}
+}
+
+func TestTargets_SetModulesKeepsUnchangedSessions(t *testing.T) {
+	tg, logins, mu := newTestTargets(t, map[string]ProbeModule{
+		"default":  {Username: "admin", Targets: testNet},
+		"readonly": {Username: "monitor", Targets: testNet},
+	})
+	probeTarget(tg, "target=192.0.2.1")
+	probeTarget(tg, "target=192.0.2.1&module=readonly")
+	if err := tg.SetModules(map[string]ProbeModule{
+		"default":  {Username: "admin", Targets: testNet},
+		"readonly": {Username: "viewer", Targets: testNet},
+	}); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+	probeTarget(tg, "target=192.0.2.1")
+	probeTarget(tg, "target=192.0.2.1&module=readonly")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if logins["192.0.2.1/admin"] != 1 || logins["192.0.2.1/viewer"] != 1 {
+		t.Fatalf("expected only the changed module to log in again, got %v", logins)
+	}
+	if err := tg.SetModules(map[string]ProbeModule{"default": {Modules: []string{"bogus"}, Targets: testNet}}); err == nil {
+		t.Fatalf("expected an error for an unknown collector module")
+	}
+	if names := tg.ModuleNames(); len(names) != 2 {
+		t.Fatalf("expected the modules to be unchanged after a failed SetModules, got %v", names)
+	}
+}
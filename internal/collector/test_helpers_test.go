@@ -1,4 +1,65 @@
 package collector
 
-// Deprecated placeholder: simpleErr moved to testhelpers_test.go
-// This file intentionally left blank to avoid duplicate symbol errors.
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	metrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	"github.com/GrammaTonic/experia-v10-exporter/internal/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// newModuleTestCollector returns a collector whose transport answers
+// createContext with a token, requests containing a key of responses with the
+// mapped body and everything else with {"status":true}.
+func newModuleTestCollector(responses map[string]string) *Experiav10Collector {
+	c := NewCollector(net.ParseIP("127.0.0.1"), "u", "p", 1*time.Second, "ETH0")
+	c.client.Transport = testutil.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Body)
+		body := `{"status":true}`
+		if bytes.Contains(b, []byte("createContext")) {
+			body = `{"data":{"contextID":"CTX-TEST"}}`
+		}
+		for key, resp := range responses {
+			if bytes.Contains(b, []byte(key)) {
+				body = resp
+			}
+		}
+		return testutil.MakeResp(body), nil
+	})
+	return c
+}
+
+// gatherFamilies registers c on a fresh registry and returns the gathered
+// families keyed by name without the metric prefix.
+func gatherFamilies(t *testing.T, c prometheus.Collector) map[string]*dto.MetricFamily {
+	t.Helper()
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatalf("failed to register collector: %v", err)
+	}
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather failed: %v", err)
+	}
+	out := map[string]*dto.MetricFamily{}
+	for _, mf := range mfs {
+		out[mf.GetName()[len(metrics.MetricPrefix):]] = mf
+	}
+	return out
+}
+
+// labelValue returns the value of the named label on m.
+func labelValue(m *dto.Metric, name string) string {
+	for _, lp := range m.GetLabel() {
+		if lp.GetName() == name {
+			return lp.GetValue()
+		}
+	}
+	return ""
+}
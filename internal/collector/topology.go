@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	topologymetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/topology"
+	nemo "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/nemo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectTopology exports the LLIntf/ULIntf links of the interface stacks
+// below nemo.TopologyRoots. A root whose call fails is skipped.
+func (c *Experiav10Collector) collectTopology(_ *scrapeState, ch chan<- prometheus.Metric) {
+	var sets [][]nemo.Interface
+	for _, root := range nemo.TopologyRoots {
+		resp := c.moduleFetch(nemo.RequestBodyBase(root))
+		if resp == nil {
+			continue
+		}
+		ifaces, err := nemo.ParseBase(resp)
+		if err != nil {
+			c.moduleParseError("NeMo.Intf."+root+".getMIBs", err)
+			continue
+		}
+		sets = append(sets, ifaces)
+	}
+	for _, l := range nemo.BuildTopology(sets...).Links {
+		ch <- prometheus.MustNewConstMetric(topologymetrics.InterfaceLinkInfo, prometheus.GaugeValue, 1.0, l.Upper, l.Lower)
+	}
+}
+
+// TopologyHandler returns the /api/topology handler which serves the
+// interface stack below nemo.TopologyRoots as JSON using the collector's
+// session.
+func (c *Experiav10Collector) TopologyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		topo, err := c.topology(r.Context())
+		if err != nil {
+			log.Printf("WARN: topology request failed: %v", err)
+			http.Error(w, "failed to read interface topology from the router", http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(topo); err != nil {
+			log.Printf("WARN: failed to write topology response: %v", err)
+		}
+	})
+}
+
+// topology fetches and merges the interface stacks below every root.
+func (c *Experiav10Collector) topology(ctx context.Context) (nemo.Topology, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.client.Timeout)
+	defer cancel()
+	sets := make([][]nemo.Interface, 0, len(nemo.TopologyRoots))
+	for _, root := range nemo.TopologyRoots {
+		resp, err := c.post(ctx, c.client, nemo.RequestBodyBase(root))
+		if err != nil {
+			return nemo.Topology{}, err
+		}
+		countPermissionErrors(string(resp))
+		ifaces, err := nemo.ParseBase(resp)
+		if err != nil {
+			return nemo.Topology{}, err
+		}
+		sets = append(sets, ifaces)
+	}
+	return nemo.BuildTopology(sets...), nil
+}
@@ -0,0 +1,80 @@
+package collector
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	nemo "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/nemo"
+	"github.com/GrammaTonic/experia-v10-exporter/internal/testutil"
+)
+
+var topologyResponses = map[string]string{
+	`NeMo.Intf.data"`: `{"status":{"base":{
+		"data":{"Name":"data","Status":true,"LLIntf":{"primdata":{"Name":"primdata"}}},
+		"primdata":{"Name":"primdata","ULIntf":{"data":{}},"LLIntf":{"ptm0":{}}},
+		"ptm0":{"Name":"ptm0","ULIntf":{"primdata":{}},"LLIntf":{"dsl0":{}}},
+		"dsl0":{"Name":"dsl0","ULIntf":{"ptm0":{}}}
+	}}}`,
+	`NeMo.Intf.lan"`: `{"status":{"base":{
+		"lan":{"Name":"lan","LLIntf":{"eth0":{},"eth1":{}}}
+	}}}`,
+}
+
+func TestCollectTopology(t *testing.T) {
+	c := newModuleTestCollector(topologyResponses)
+	if err := c.SetModules("topology"); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+	mfs := gatherFamilies(t, c)
+	links := mfs["interface_link_info"]
+	if links == nil || len(links.GetMetric()) != 5 {
+		t.Fatalf("expected five interface_link_info series, got %v", links)
+	}
+	found := false
+	for _, m := range links.GetMetric() {
+		if labelValue(m, "upper") == "ptm0" && labelValue(m, "lower") == "dsl0" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected a ptm0 -> dsl0 link")
+	}
+}
+
+func TestCollectTopology_ParseError(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{`NeMo.Intf.data"`: topologyResponses[`NeMo.Intf.data"`]})
+	if err := c.SetModules("topology"); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+	mfs := gatherFamilies(t, c)
+	if links := mfs["interface_link_info"]; links == nil || len(links.GetMetric()) != 3 {
+		t.Fatalf("expected the data stack links despite the lan failure, got %v", links)
+	}
+	if testutil.ReadCounterValue(c.scrapeErrorsMetric) < 1 {
+		t.Fatalf("expected the lan parse error to be counted")
+	}
+}
+
+func TestTopologyHandler(t *testing.T) {
+	c := newModuleTestCollector(topologyResponses)
+	rec := httptest.NewRecorder()
+	c.TopologyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/topology", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected response %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var topo nemo.Topology
+	if err := json.Unmarshal(rec.Body.Bytes(), &topo); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(topo.Interfaces) != 5 || len(topo.Links) != 5 || topo.Links[0] != (nemo.Link{Upper: "data", Lower: "primdata"}) {
+		t.Fatalf("unexpected topology: %+v", topo)
+	}
+
+	rec = httptest.NewRecorder()
+	newModuleTestCollector(nil).TopologyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/topology", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502 when the router response has no base MIB, got %d", rec.Code)
+	}
+}
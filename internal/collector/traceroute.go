@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	probemetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/probe"
+	diagnostics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/diagnostics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// tracerouteCacheTTL is how long a traceroute result is reused for the same
+// target and IP version, so Prometheus retries and several scrapers do not
+// start a new trace on the router each time.
+var tracerouteCacheTTL = time.Minute
+
+type tracerouteResult struct {
+	hops []diagnostics.Hop
+	err  error
+	at   time.Time
+}
+
+// tracerouteState caches traceroute results. cache is only accessed while
+// holding the collector's diagnostic slot.
+type tracerouteState struct {
+	cache map[string]tracerouteResult
+}
+
+// TracerouteProbeHandler returns the /probe/traceroute handler which traces
+// the route to target from the router (Traceroute.start_diagnostic) using the
+// collector's session. Query parameters: target (required) and ipversion
+// (Any, IPv4 or IPv6; default IPv4).
+func (c *Experiav10Collector) TracerouteProbeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target, ipVersion, ok := probeParams(w, r)
+		if !ok {
+			return
+		}
+		ctx, cancel := probeContext(r)
+		defer cancel()
+
+		start := time.Now()
+		res, cached, err := c.traceroute(ctx, target, ipVersion)
+		if err == nil {
+			err = res.err
+		}
+		metrics := []prometheus.Metric{}
+		success := false
+		if err != nil {
+			log.Printf("WARN: traceroute probe for %s failed: %v", target, err)
+		} else {
+			for _, h := range res.hops {
+				if ms, ok := h.AverageRTT(); ok {
+					metrics = append(metrics, prometheus.MustNewConstMetric(probemetrics.TracerouteHopRTT, prometheus.GaugeValue,
+						ms/1e3, strconv.Itoa(h.Number), h.Name()))
+				}
+			}
+			success = diagnostics.TracerouteSucceeded(res.hops)
+			metrics = append(metrics,
+				prometheus.MustNewConstMetric(probemetrics.TracerouteHops, prometheus.GaugeValue, float64(len(res.hops))),
+				prometheus.MustNewConstMetric(probemetrics.TracerouteSuccess, prometheus.GaugeValue, boolToFloat(success)),
+			)
+		}
+		metrics = append(metrics,
+			prometheus.MustNewConstMetric(probemetrics.TracerouteCached, prometheus.GaugeValue, boolToFloat(cached)),
+			prometheus.MustNewConstMetric(probemetrics.Success, prometheus.GaugeValue, boolToFloat(success)),
+			prometheus.MustNewConstMetric(probemetrics.Duration, prometheus.GaugeValue, time.Since(start).Seconds()),
+		)
+		serveProbe(w, r, metrics)
+	})
+}
+
+// traceroute returns a cached result for target when one is fresh enough,
+// otherwise it waits for its turn and runs the diagnostic. A request that
+// waited behind a trace of the same target gets that trace's result. The
+// returned error is only set when ctx expired, while waiting or during the
+// trace, or when no session could be established; such a trace is not
+// cached.
+func (c *Experiav10Collector) traceroute(ctx context.Context, target, ipVersion string) (tracerouteResult, bool, error) {
+	select {
+	case c.diagnostic <- struct{}{}:
+	case <-ctx.Done():
+		return tracerouteResult{}, false, ctx.Err()
+	}
+	defer func() { <-c.diagnostic }()
+
+	key := ipVersion + "|" + target
+	if res, ok := c.tracert.cache[key]; ok && timeNow().Sub(res.at) < tracerouteCacheTTL {
+		return res, true, nil
+	}
+
+	if err := c.ensureSession(); err != nil {
+		return tracerouteResult{}, false, err
+	}
+	// The diagnostic outlasts the scrape timeout; ctx bounds it instead.
+	client := *c.client
+	client.Timeout = 0
+	res := tracerouteResult{at: timeNow()}
+	resp, err := c.post(ctx, &client, diagnostics.RequestBodyTraceroute(target, ipVersion))
+	if err == nil {
+		countPermissionErrors(string(resp))
+		res.hops, err = diagnostics.ParseTraceroute(resp)
+	}
+	if err != nil && ctx.Err() != nil {
+		// The request gave up, not the router: the next probe traces again.
+		return tracerouteResult{}, false, err
+	}
+	res.err = err
+	// Failures are cached too: a target that cannot be traced should not be
+	// retried on every scrape either.
+	for k, old := range c.tracert.cache {
+		if timeNow().Sub(old.at) >= tracerouteCacheTTL {
+			delete(c.tracert.cache, k)
+		}
+	}
+	c.tracert.cache[key] = res
+	return res, false, nil
+}
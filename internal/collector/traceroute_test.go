@@ -0,0 +1,151 @@
+package collector
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GrammaTonic/experia-v10-exporter/internal/testutil"
+)
+
+const tracerouteResp = `{"status":{"RouteHops":{
+	"1":{"ErrorCode":11,"RTTimes":"1,2,3","HostAddress":"195.190.228.1","Host":""},
+	"2":{"ErrorCode":4294967295,"RTTimes":"0,0,0","HostAddress":"","Host":""},
+	"3":{"ErrorCode":0,"RTTimes":"8,9,10","HostAddress":"142.250.179.196","Host":"dns.google"}
+}}}`
+
+// countTraceroutes wraps c's transport and counts start_diagnostic calls.
+func countTraceroutes(c *Experiav10Collector, delay time.Duration) *int32 {
+	var n int32
+	next := c.client.Transport
+	c.client.Transport = testutil.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		if bytes.Contains(b, []byte("start_diagnostic")) {
+			atomic.AddInt32(&n, 1)
+			time.Sleep(delay)
+		}
+		return next.RoundTrip(req)
+	})
+	return &n
+}
+
+func TestTracerouteProbeHandler(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{"start_diagnostic": tracerouteResp})
+	runs := countTraceroutes(c, 0)
+
+	rec := httptest.NewRecorder()
+	c.TracerouteProbeHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe/traceroute?target=8.8.8.8", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`experia_v10_traceroute_hop_rtt_seconds{hop="1",host="195.190.228.1"} 0.002`,
+		`experia_v10_traceroute_hop_rtt_seconds{hop="3",host="dns.google"} 0.009`,
+		"experia_v10_traceroute_hops 3",
+		"experia_v10_traceroute_success 1",
+		"experia_v10_traceroute_cached 0",
+		"probe_success 1",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in probe output:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `hop="2"`) {
+		t.Fatalf("expected no RTT for the timed out hop")
+	}
+
+	// A second probe within the TTL is served from the cache.
+	rec = httptest.NewRecorder()
+	c.TracerouteProbeHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe/traceroute?target=8.8.8.8", nil))
+	if !strings.Contains(rec.Body.String(), "experia_v10_traceroute_cached 1") || atomic.LoadInt32(runs) != 1 {
+		t.Fatalf("expected a cached result without a second trace, runs=%d", atomic.LoadInt32(runs))
+	}
+
+	// Another IP version is a different cache entry.
+	rec = httptest.NewRecorder()
+	c.TracerouteProbeHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe/traceroute?target=8.8.8.8&ipversion=IPv6", nil))
+	if atomic.LoadInt32(runs) != 2 {
+		t.Fatalf("expected a new trace for IPv6, runs=%d", atomic.LoadInt32(runs))
+	}
+}
+
+func TestTracerouteProbeHandler_ConcurrentRequestsShareRun(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{"start_diagnostic": tracerouteResp})
+	runs := countTraceroutes(c, 20*time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			c.TracerouteProbeHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe/traceroute?target=8.8.8.8", nil))
+			if !strings.Contains(rec.Body.String(), "probe_success 1") {
+				t.Errorf("expected probe_success 1, got:\n%s", rec.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(runs); n != 1 {
+		t.Fatalf("expected concurrent probes to share one trace, got %d", n)
+	}
+}
+
+func TestTracerouteProbeHandler_Failure(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{"start_diagnostic": `{"status":null,"errors":[{"description":"Busy"}]}`})
+	rec := httptest.NewRecorder()
+	c.TracerouteProbeHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe/traceroute?target=8.8.8.8", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, "probe_success 0") || strings.Contains(body, "experia_v10_traceroute_hops") {
+		t.Fatalf("expected only probe_success 0, got:\n%s", body)
+	}
+}
+
+func TestTracerouteProbeHandler_WaitTimeout(t *testing.T) {
+	c := newModuleTestCollector(nil)
+	c.diagnostic <- struct{}{} // a trace is running
+	defer func() { <-c.diagnostic }()
+
+	req := httptest.NewRequest(http.MethodGet, "/probe/traceroute?target=8.8.8.8", nil)
+	req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "1.1")
+	rec := httptest.NewRecorder()
+	c.TracerouteProbeHandler().ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), "probe_success 0") {
+		t.Fatalf("expected probe_success 0 after waiting too long, got:\n%s", rec.Body.String())
+	}
+}
+
+func TestTracerouteProbeHandler_TimedOutTraceNotCached(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{"start_diagnostic": tracerouteResp})
+	next := c.client.Transport
+	var hang atomic.Bool
+	hang.Store(true)
+	c.client.Transport = testutil.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if hang.Load() {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		return next.RoundTrip(req)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/probe/traceroute?target=8.8.8.8", nil)
+	req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "1.1")
+	rec := httptest.NewRecorder()
+	c.TracerouteProbeHandler().ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), "probe_success 0") {
+		t.Fatalf("expected probe_success 0 after the trace timed out, got:\n%s", rec.Body.String())
+	}
+
+	// The next probe traces again instead of reusing the timeout.
+	hang.Store(false)
+	rec = httptest.NewRecorder()
+	c.TracerouteProbeHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/probe/traceroute?target=8.8.8.8", nil))
+	if body := rec.Body.String(); !strings.Contains(body, "probe_success 1") || !strings.Contains(body, "experia_v10_traceroute_cached 0") {
+		t.Fatalf("expected a new trace after the timed out one, got:\n%s", body)
+	}
+}
@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"strings"
+
+	voicemetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/voice"
+	voice "github.com/GrammaTonic/experia-v10-exporter/internal/collector/services/voice"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectVoice exports the SIP registration state of every trunk line.
+func (c *Experiav10Collector) collectVoice(st *scrapeState, ch chan<- prometheus.Metric) {
+	resp := c.moduleFetch(voice.RequestBody())
+	if resp == nil {
+		return
+	}
+	trunks, err := voice.ParseTrunks(resp)
+	if err != nil {
+		c.moduleParseError("listTrunks", err)
+		return
+	}
+	for _, t := range trunks.Status {
+		ch <- prometheus.MustNewConstMetric(voicemetrics.TrunkInfo, prometheus.GaugeValue, 1.0,
+			t.Name, t.SIP.RegistrarServer, t.SIP.ProxyServer, t.SIP.OutboundProxyServer)
+		for _, l := range t.Lines {
+			ch <- prometheus.MustNewConstMetric(voicemetrics.LineUp, prometheus.GaugeValue, boolToFloat(l.Up()), t.Name, l.Name)
+			ch <- prometheus.MustNewConstMetric(voicemetrics.LineEnabled, prometheus.GaugeValue, boolToFloat(l.Enabled()), t.Name, l.Name)
+			number := l.DirectoryNumber
+			if st.settings.redactPhoneNumbers {
+				number = redactPhoneNumber(number)
+			}
+			ch <- prometheus.MustNewConstMetric(voicemetrics.LineInfo, prometheus.GaugeValue, 1.0,
+				t.Name, l.Name, number, l.Status, l.StatusInfo)
+		}
+	}
+}
+
+// SetRedactPhoneNumbers controls whether directory numbers are masked in the
+// voip_line_info metric.
+func (c *Experiav10Collector) SetRedactPhoneNumbers(redact bool) {
+	c.settingsMu.Lock()
+	defer c.settingsMu.Unlock()
+	c.redactPhoneNumbers = redact
+}
+
+// redactPhoneNumber masks all but the last two digits of a directory number,
+// keeping the leading '+' so the label still reads as a number. Empty input
+// stays empty so unconfigured lines remain distinguishable.
+func redactPhoneNumber(number string) string {
+	digits := 0
+	for _, r := range number {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	var b strings.Builder
+	seen := 0
+	for _, r := range number {
+		if r >= '0' && r <= '9' {
+			seen++
+			if seen <= digits-2 {
+				b.WriteRune('*')
+				continue
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
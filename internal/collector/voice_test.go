@@ -0,0 +1,99 @@
+package collector
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+const voiceTrunksJSON = `{"status":[{"name":"SIP-Trunk1","enable":"Enabled",` +
+	`"trunk_lines":[{"name":"LINE11","enable":"Enabled","status":"Up","statusInfo":"ResetTimer","directoryNumber":"+31793608718"}],` +
+	`"sip":{"proxyServer":"ims.imscore.net","registrarServer":"ims.imscore.net","outboundProxyServer":"145.7.97.60"}},` +
+	`{"name":"SIP-Trunk2","enable":"Disabled",` +
+	`"trunk_lines":[{"name":"LINE21","enable":"Disabled","status":"Disabled","statusInfo":"NoResponse","directoryNumber":""}],` +
+	`"sip":{}}]}`
+
+func TestCollectVoice_LineStatusAndInfo(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{"listTrunks": voiceTrunksJSON})
+	if err := c.SetModules("voice"); err != nil {
+		t.Fatalf("SetModules failed: %v", err)
+	}
+	mfs := gatherFamilies(t, c)
+
+	up := mfs["voip_line_up"]
+	if up == nil {
+		t.Fatalf("expected voip_line_up family")
+	}
+	got := map[string]float64{}
+	for _, m := range up.GetMetric() {
+		got[labelValue(m, "trunk")+"/"+labelValue(m, "line")] = m.GetGauge().GetValue()
+	}
+	if got["SIP-Trunk1/LINE11"] != 1 || got["SIP-Trunk2/LINE21"] != 0 {
+		t.Fatalf("unexpected voip_line_up values: %v", got)
+	}
+
+	info := mfs["voip_line_info"]
+	if info == nil || len(info.GetMetric()) != 2 {
+		t.Fatalf("expected two voip_line_info series")
+	}
+	for _, m := range info.GetMetric() {
+		if labelValue(m, "line") == "LINE11" && labelValue(m, "directory_number") != "+31793608718" {
+			t.Fatalf("expected unredacted number, got %q", labelValue(m, "directory_number"))
+		}
+	}
+
+	trunk := mfs["voip_trunk_info"]
+	if trunk == nil {
+		t.Fatalf("expected voip_trunk_info family")
+	}
+	for _, m := range trunk.GetMetric() {
+		if labelValue(m, "trunk") == "SIP-Trunk1" && labelValue(m, "outbound_proxy") != "145.7.97.60" {
+			t.Fatalf("unexpected outbound_proxy label: %q", labelValue(m, "outbound_proxy"))
+		}
+	}
+}
+
+func TestCollectVoice_RedactsPhoneNumbers(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{"listTrunks": voiceTrunksJSON})
+	_ = c.SetModules("voice")
+	c.SetRedactPhoneNumbers(true)
+	mfs := gatherFamilies(t, c)
+	for _, m := range mfs["voip_line_info"].GetMetric() {
+		if labelValue(m, "line") == "LINE11" && labelValue(m, "directory_number") != "+*********18" {
+			t.Fatalf("expected redacted number, got %q", labelValue(m, "directory_number"))
+		}
+	}
+}
+
+func TestCollectVoice_DisabledByDefault(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{"listTrunks": voiceTrunksJSON})
+	mfs := gatherFamilies(t, c)
+	if _, ok := mfs["voip_line_up"]; ok {
+		t.Fatalf("voice metrics must not be emitted unless the module is enabled")
+	}
+}
+
+func TestSetModules_UnknownName(t *testing.T) {
+	c := NewCollector(net.ParseIP("127.0.0.1"), "u", "p", time.Second)
+	_ = c.SetModules("voice")
+	if err := c.SetModules("voice", "nope"); err == nil {
+		t.Fatalf("expected error for unknown module")
+	}
+	if got := c.Modules(); len(got) != 1 || got[0] != "voice" {
+		t.Fatalf("expected enabled set to be unchanged, got %v", got)
+	}
+}
+
+func TestRedactPhoneNumber(t *testing.T) {
+	cases := map[string]string{
+		"+31793608718": "+*********18",
+		"0612":         "**12",
+		"":             "",
+		"7":            "7",
+	}
+	for in, want := range cases {
+		if got := redactPhoneNumber(in); got != want {
+			t.Fatalf("redactPhoneNumber(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
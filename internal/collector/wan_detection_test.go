@@ -115,3 +115,50 @@ func TestWanDetection(t *testing.T) {
 		t.Fatalf("expected netdev_info alias to contain 'wan' for eth1 or a wan_ifname metric, but didn't find either; metrics: %v", len(mfs))
 	}
 }
+
+// TestWanDetection_Flags ensures an interface carrying the NeMo "wan" flag is
+// detected as WAN without a MAC match and that the flags are exported as a
+// role label and netdev_flag series.
+func TestWanDetection_Flags(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{
+		"getWANStatus": `{"status":true,"data":{"MACAddress":"AA:BB:CC:DD:EE:FF","ConnectionState":"Connected"}}`,
+		`NeMo.Intf.ETH0","method":"getMIBs"`: `{"status":{"base":{"ETH0":{"Alias":"cpe-eth4","Flags":"enabled netdev eth physical ipv4 wan netdev-bound netdev-up"}},` +
+			`"netdev":{"ETH0":{"LLAddress":"11:22:33:44:55:66","NetDevState":"up","NetDevFlags":"up broadcast multicast"}}}}`,
+	})
+	mfs := gatherFamilies(t, c)
+	if w := mfs["wan_ifname"]; w == nil || labelValue(w.GetMetric()[0], "ifname") != "eth1" {
+		t.Fatalf("expected eth1 to be detected as WAN from its flags")
+	}
+	info := mfs["netdev_info"].GetMetric()[0]
+	if labelValue(info, "role") != "wan" || labelValue(mfs["wan_info"].GetMetric()[0], "role") != "wan" {
+		t.Fatalf("expected role wan, got %q", labelValue(info, "role"))
+	}
+	want := map[string]float64{"up": 1, "ipv4-up": 0, "ipv6-up": 0, "netdev-bound": 1}
+	got := map[string]float64{}
+	for _, m := range mfs["netdev_flag"].GetMetric() {
+		got[labelValue(m, "flag")] = m.GetGauge().GetValue()
+	}
+	for flag, v := range want {
+		if got[flag] != v {
+			t.Fatalf("netdev_flag{flag=%q} = %v, want %v", flag, got[flag], v)
+		}
+	}
+}
+
+// TestWanDetection_FlagsWithoutWan ensures an alias containing "wan" does not
+// mark an interface as WAN when its flags say otherwise.
+func TestWanDetection_FlagsWithoutWan(t *testing.T) {
+	c := newModuleTestCollector(map[string]string{
+		"getWANStatus":                       `{"status":true,"data":{"MACAddress":"AA:BB:CC:DD:EE:FF"}}`,
+		`NeMo.Intf.ETH0","method":"getMIBs"`: `{"status":{"base":{"ETH0":{"Alias":"wan-side switch","Flags":"enabled netdev vlan inbridge up"}}}}`,
+	})
+	mfs := gatherFamilies(t, c)
+	for _, m := range mfs["wan_ifname"].GetMetric() {
+		if labelValue(m, "ifname") != "" {
+			t.Fatalf("expected only the empty wan_ifname placeholder, got %q", labelValue(m, "ifname"))
+		}
+	}
+	if r := labelValue(mfs["netdev_info"].GetMetric()[0], "role"); r != "lan" {
+		t.Fatalf("expected role lan, got %q", r)
+	}
+}
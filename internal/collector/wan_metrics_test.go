@@ -6,7 +6,6 @@ import (
 	"io"
 	"net"
 	"net/http"
-	"os"
 	"testing"
 	"time"
 
@@ -22,10 +21,8 @@ func TestCollect_EmitsWanMetricsOnMatch(t *testing.T) {
 	// Force candidate ordering so ETH2 (which in SampleMibJSON has the
 	// LLAddress matching our test WAN MAC) is processed first and maps to
 	// canonical label "eth1".
-	_ = os.Setenv("EXPERIA_EXPECT_NETDEV_IFACES", "ETH2,ETH3,ETH0,ETH1")
-	defer os.Unsetenv("EXPERIA_EXPECT_NETDEV_IFACES")
-
 	c := NewCollector(net.ParseIP("127.0.0.1"), "u", "p", 1*time.Second)
+	c.SetNetdevCandidates("ETH2", "ETH3", "ETH0", "ETH1")
 	// Prepare transport that responds to the sequence of requests the
 	// collector performs.
 	c.client.Transport = testutil.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
@@ -0,0 +1,635 @@
+// Package config loads the exporter's YAML configuration file and applies
+// the EXPERIA_V10_* environment variables on top of it.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/GrammaTonic/experia-v10-exporter/internal/collector"
+	"go.yaml.in/yaml/v3"
+)
+
+// Config is the exporter configuration file.
+type Config struct {
+	// ListenAddress is the address the HTTP server listens on.
+	ListenAddress string      `yaml:"listen_address"`
+	Router        Router      `yaml:"router"`
+	Collector     Collector   `yaml:"collector"`
+	Events        Events      `yaml:"events"`
+	Speedtest     Speedtest   `yaml:"speedtest"`
+	MQTT          MQTT        `yaml:"mqtt"`
+	Influx        Influx      `yaml:"influx"`
+	OTLP          OTLP        `yaml:"otlp"`
+	RemoteWrite   RemoteWrite `yaml:"remote_write"`
+	// ProbeModules are the credential sets selectable with the module
+	// parameter of /probe, keyed by name.
+	ProbeModules map[string]ProbeModule `yaml:"probe_modules"`
+}
+
+// Router is the router scraped on /metrics.
+type Router struct {
+	IP       string `yaml:"ip"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// PasswordFile is read into Password by Load and ReadSecrets, so the
+	// password can come from a Docker or Kubernetes secret.
+	PasswordFile string        `yaml:"password_file"`
+	Timeout      time.Duration `yaml:"timeout"`
+}
+
+// Collector holds the collector options that a reload applies without
+// dropping the router session.
+type Collector struct {
+	// Modules lists the optional collector modules to enable.
+	Modules []string `yaml:"modules"`
+	// Interfaces are the NeMo interface candidates (for example ETH0) that
+	// are exported as eth1..ethN; empty uses the built-in list.
+	Interfaces []string `yaml:"interfaces"`
+	Labels     Labels   `yaml:"labels"`
+	// SecurityRuleInfo emits one firewall_rule_info series per rule.
+	SecurityRuleInfo bool `yaml:"security_rule_info"`
+	// Debug logs every device call and response; EXPERIA_E2E=1 sets it.
+	Debug bool `yaml:"debug"`
+}
+
+// Labels selects how label values are derived.
+type Labels struct {
+	// ForceWANAlias sets the alias label of the detected WAN interface to
+	// "wan".
+	ForceWANAlias bool `yaml:"force_wan_alias"`
+	// RedactPhoneNumbers masks directory numbers in voip_line_info.
+	RedactPhoneNumbers bool `yaml:"redact_phone_numbers"`
+}
+
+// Events configures the background event channel.
+type Events struct {
+	Enabled  bool     `yaml:"enabled"`
+	Handlers []string `yaml:"handlers"`
+}
+
+// Speedtest configures the speedtest module's schedule.
+type Speedtest struct {
+	// Interval between scheduled tests; 0 disables the schedule.
+	Interval time.Duration `yaml:"interval"`
+	// MinInterval is the minimum time between two tests started through
+	// POST /speedtest.
+	MinInterval time.Duration `yaml:"min_interval"`
+}
+
+// MQTT configures publishing to an MQTT broker, for example for Home
+// Assistant. Publishing is enabled when Broker is set.
+type MQTT struct {
+	// Broker is the broker URL: tcp://, ssl://, ws:// or wss://.
+	Broker       string `yaml:"broker"`
+	ClientID     string `yaml:"client_id"`
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"`
+	// TopicPrefix is the root of the state and availability topics.
+	TopicPrefix string `yaml:"topic_prefix"`
+	// Discovery publishes Home Assistant discovery messages under
+	// DiscoveryPrefix.
+	Discovery       bool          `yaml:"discovery"`
+	DiscoveryPrefix string        `yaml:"discovery_prefix"`
+	Interval        time.Duration `yaml:"interval"`
+	QoS             byte          `yaml:"qos"`
+	// Retain publishes the state messages retained.
+	Retain bool `yaml:"retain"`
+}
+
+// Influx configures pushing line protocol to the InfluxDB v2 write API.
+// Pushing is enabled when URL is set; /influx is served either way.
+type Influx struct {
+	// URL is the InfluxDB base URL, for example http://influxdb:8086.
+	URL       string        `yaml:"url"`
+	Token     string        `yaml:"token"`
+	TokenFile string        `yaml:"token_file"`
+	Org       string        `yaml:"org"`
+	Bucket    string        `yaml:"bucket"`
+	Interval  time.Duration `yaml:"interval"`
+}
+
+// OTLP configures pushing metrics to an OpenTelemetry receiver. Pushing is
+// enabled when Endpoint is set.
+type OTLP struct {
+	// Endpoint is the receiver URL, for example http://otel-collector:4318.
+	Endpoint string `yaml:"endpoint"`
+	// Protocol is http/protobuf (the default) or grpc.
+	Protocol string `yaml:"protocol"`
+	// Headers are sent with every export, for example an API key.
+	Headers  map[string]string `yaml:"headers"`
+	Interval time.Duration     `yaml:"interval"`
+}
+
+// RemoteWrite configures pushing samples with the Prometheus remote_write
+// protocol. Pushing is enabled when URL is set.
+type RemoteWrite struct {
+	// URL is the remote_write endpoint, for example
+	// https://prometheus.example.com/api/v1/write.
+	URL          string `yaml:"url"`
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"`
+	// BearerToken is sent instead of basic auth.
+	BearerToken     string `yaml:"bearer_token"`
+	BearerTokenFile string `yaml:"bearer_token_file"`
+	// ExternalLabels are added to every series to identify the site.
+	ExternalLabels map[string]string `yaml:"external_labels"`
+	Interval       time.Duration     `yaml:"interval"`
+	Queue          RemoteWriteQueue  `yaml:"queue"`
+}
+
+// RemoteWriteQueue bounds the samples kept while the endpoint is
+// unreachable.
+type RemoteWriteQueue struct {
+	// MaxBatches is the number of collections kept; the oldest is dropped
+	// when the queue is full.
+	MaxBatches int `yaml:"max_batches"`
+	// Directory keeps the queue on disk so it survives a restart; empty
+	// keeps it in memory.
+	Directory  string        `yaml:"directory"`
+	MinBackoff time.Duration `yaml:"min_backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+// ProbeModule holds the credentials and options used to scrape a router on
+// /probe.
+type ProbeModule struct {
+	Username     string        `yaml:"username"`
+	Password     string        `yaml:"password"`
+	PasswordFile string        `yaml:"password_file"`
+	Timeout      time.Duration `yaml:"timeout"`
+	// Modules lists the optional collector modules to run for the target.
+	Modules []string `yaml:"modules"`
+	// Targets lists the IP addresses and CIDR networks the module may
+	// probe. It is required, so the credentials only go to known routers.
+	Targets []string `yaml:"targets"`
+}
+
+// interfaceRe matches NeMo interface names.
+var interfaceRe = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Default returns the configuration used for settings that neither the file
+// nor the environment set.
+func Default() *Config {
+	return &Config{
+		ListenAddress: ":9100",
+		Router: Router{
+			IP:      "127.0.0.1",
+			Timeout: 5 * time.Second,
+		},
+		Speedtest: Speedtest{
+			Interval:    6 * time.Hour,
+			MinInterval: 5 * time.Minute,
+		},
+		MQTT: MQTT{
+			ClientID:        "experia-v10-exporter",
+			TopicPrefix:     "experia_v10",
+			Discovery:       true,
+			DiscoveryPrefix: "homeassistant",
+			Interval:        time.Minute,
+		},
+		Influx: Influx{
+			Interval: time.Minute,
+		},
+		OTLP: OTLP{
+			Protocol: "http/protobuf",
+			Interval: time.Minute,
+		},
+		RemoteWrite: RemoteWrite{
+			Interval: time.Minute,
+			Queue: RemoteWriteQueue{
+				MaxBatches: 1440,
+				MinBackoff: time.Second,
+				MaxBackoff: time.Minute,
+			},
+		},
+	}
+}
+
+// Load reads the configuration file at path, or starts from Default when
+// path is empty, applies the environment overrides, validates the result and
+// reads the password files.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if cfg, err = Parse(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := cfg.ApplyEnv(os.Getenv); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.ReadSecrets(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Parse decodes and validates a configuration document on top of Default.
+// Unknown keys are an error so typos do not silently fall back to defaults.
+func Parse(data []byte) (*Config, error) {
+	cfg := Default()
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ApplyEnv overrides settings with the EXPERIA_V10_* environment variables
+// that are set, read through getenv.
+func (c *Config) ApplyEnv(getenv func(string) string) error {
+	setString := func(name string, dst *string) {
+		if v := getenv(name); v != "" {
+			*dst = v
+		}
+	}
+	setBool := func(name string, dst *bool) {
+		if v := getenv(name); v != "" {
+			*dst = strings.EqualFold(v, "1") || strings.EqualFold(v, "true")
+		}
+	}
+	setList := func(name string, dst *[]string) {
+		if v := getenv(name); v != "" {
+			*dst = splitList(v)
+		}
+	}
+	setDuration := func(name string, dst *time.Duration) error {
+		v := getenv(name)
+		if v == "" {
+			return nil
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%s invalid: %w", name, err)
+		}
+		*dst = d
+		return nil
+	}
+
+	// setSecret sets a secret or the file holding it from the environment.
+	// Either one replaces both settings of the file, so an environment
+	// password is not rejected next to the file's password_file.
+	setSecret := func(name, fileName string, dst, dstFile *string) error {
+		v, file := getenv(name), getenv(fileName)
+		switch {
+		case v != "" && file != "":
+			return fmt.Errorf("%s and %s are mutually exclusive", name, fileName)
+		case v != "":
+			*dst, *dstFile = v, ""
+		case file != "":
+			*dst, *dstFile = "", file
+		}
+		return nil
+	}
+
+	setString("EXPERIA_V10_LISTEN_ADDR", &c.ListenAddress)
+	setString("EXPERIA_V10_ROUTER_IP", &c.Router.IP)
+	setString("EXPERIA_V10_ROUTER_USERNAME", &c.Router.Username)
+	if err := setSecret("EXPERIA_V10_ROUTER_PASSWORD", "EXPERIA_V10_ROUTER_PASSWORD_FILE", &c.Router.Password, &c.Router.PasswordFile); err != nil {
+		return err
+	}
+	setString("EXPERIA_V10_MQTT_BROKER", &c.MQTT.Broker)
+	setString("EXPERIA_V10_MQTT_USERNAME", &c.MQTT.Username)
+	if err := setSecret("EXPERIA_V10_MQTT_PASSWORD", "EXPERIA_V10_MQTT_PASSWORD_FILE", &c.MQTT.Password, &c.MQTT.PasswordFile); err != nil {
+		return err
+	}
+	setString("EXPERIA_V10_MQTT_TOPIC_PREFIX", &c.MQTT.TopicPrefix)
+	setString("EXPERIA_V10_INFLUX_URL", &c.Influx.URL)
+	if err := setSecret("EXPERIA_V10_INFLUX_TOKEN", "EXPERIA_V10_INFLUX_TOKEN_FILE", &c.Influx.Token, &c.Influx.TokenFile); err != nil {
+		return err
+	}
+	setString("EXPERIA_V10_INFLUX_ORG", &c.Influx.Org)
+	setString("EXPERIA_V10_INFLUX_BUCKET", &c.Influx.Bucket)
+	setString("EXPERIA_V10_OTLP_ENDPOINT", &c.OTLP.Endpoint)
+	setString("EXPERIA_V10_OTLP_PROTOCOL", &c.OTLP.Protocol)
+	setString("EXPERIA_V10_REMOTE_WRITE_URL", &c.RemoteWrite.URL)
+	setString("EXPERIA_V10_REMOTE_WRITE_USERNAME", &c.RemoteWrite.Username)
+	if err := setSecret("EXPERIA_V10_REMOTE_WRITE_PASSWORD", "EXPERIA_V10_REMOTE_WRITE_PASSWORD_FILE", &c.RemoteWrite.Password, &c.RemoteWrite.PasswordFile); err != nil {
+		return err
+	}
+	if err := setSecret("EXPERIA_V10_REMOTE_WRITE_BEARER_TOKEN", "EXPERIA_V10_REMOTE_WRITE_BEARER_TOKEN_FILE", &c.RemoteWrite.BearerToken, &c.RemoteWrite.BearerTokenFile); err != nil {
+		return err
+	}
+	setString("EXPERIA_V10_REMOTE_WRITE_QUEUE_DIR", &c.RemoteWrite.Queue.Directory)
+	if v := getenv("EXPERIA_V10_REMOTE_WRITE_EXTERNAL_LABELS"); v != "" {
+		labels := map[string]string{}
+		for _, pair := range splitList(v) {
+			name, value, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("EXPERIA_V10_REMOTE_WRITE_EXTERNAL_LABELS invalid: %q is not name=value", pair)
+			}
+			labels[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+		c.RemoteWrite.ExternalLabels = labels
+	}
+	setList("EXPERIA_V10_MODULES", &c.Collector.Modules)
+	// The variables predating the configuration file keep overriding the
+	// collector settings; EXPERIA_E2E=1 only turns debugging on.
+	setList("EXPERIA_EXPECT_NETDEV_IFACES", &c.Collector.Interfaces)
+	setBool("EXPERIA_FORCE_WAN_ALIAS", &c.Collector.Labels.ForceWANAlias)
+	if getenv("EXPERIA_E2E") == "1" {
+		c.Collector.Debug = true
+	}
+	setBool("EXPERIA_V10_REDACT_PHONE_NUMBERS", &c.Collector.Labels.RedactPhoneNumbers)
+	setBool("EXPERIA_V10_SECURITY_RULE_INFO", &c.Collector.SecurityRuleInfo)
+	setBool("EXPERIA_V10_EVENTS", &c.Events.Enabled)
+	setList("EXPERIA_V10_EVENT_HANDLERS", &c.Events.Handlers)
+	if err := setDuration("EXPERIA_V10_TIMEOUT", &c.Router.Timeout); err != nil {
+		return err
+	}
+	if err := setDuration("EXPERIA_V10_SPEEDTEST_INTERVAL", &c.Speedtest.Interval); err != nil {
+		return err
+	}
+	if err := setDuration("EXPERIA_V10_MQTT_INTERVAL", &c.MQTT.Interval); err != nil {
+		return err
+	}
+	if err := setDuration("EXPERIA_V10_INFLUX_INTERVAL", &c.Influx.Interval); err != nil {
+		return err
+	}
+	if err := setDuration("EXPERIA_V10_OTLP_INTERVAL", &c.OTLP.Interval); err != nil {
+		return err
+	}
+	if err := setDuration("EXPERIA_V10_REMOTE_WRITE_INTERVAL", &c.RemoteWrite.Interval); err != nil {
+		return err
+	}
+	return setDuration("EXPERIA_V10_SPEEDTEST_MIN_INTERVAL", &c.Speedtest.MinInterval)
+}
+
+// Validate reports the first invalid setting.
+func (c *Config) Validate() error {
+	if net.ParseIP(c.Router.IP) == nil {
+		return fmt.Errorf("router.ip: %q is not an IP address", c.Router.IP)
+	}
+	if c.Router.Password != "" && c.Router.PasswordFile != "" {
+		return errors.New("router: password and password_file are mutually exclusive")
+	}
+	if c.Router.Timeout <= 0 {
+		return fmt.Errorf("router.timeout must be positive, got %s", c.Router.Timeout)
+	}
+	if err := checkModules("collector.modules", c.Collector.Modules); err != nil {
+		return err
+	}
+	for _, in := range c.Collector.Interfaces {
+		if !interfaceRe.MatchString(in) {
+			return fmt.Errorf("collector.interfaces: %q is not an interface name", in)
+		}
+	}
+	if c.Speedtest.Interval < 0 || c.Speedtest.MinInterval < 0 {
+		return errors.New("speedtest: intervals must not be negative")
+	}
+	if err := c.MQTT.validate(); err != nil {
+		return err
+	}
+	if err := c.Influx.validate(); err != nil {
+		return err
+	}
+	if err := c.OTLP.validate(); err != nil {
+		return err
+	}
+	if err := c.RemoteWrite.validate(); err != nil {
+		return err
+	}
+	for name, m := range c.ProbeModules {
+		if name == "" {
+			return errors.New("probe_modules: module name must not be empty")
+		}
+		if m.Timeout < 0 {
+			return fmt.Errorf("probe_modules.%s.timeout must not be negative", name)
+		}
+		if m.Password != "" && m.PasswordFile != "" {
+			return fmt.Errorf("probe_modules.%s: password and password_file are mutually exclusive", name)
+		}
+		if err := checkModules("probe_modules."+name+".modules", m.Modules); err != nil {
+			return err
+		}
+		if _, err := collector.ParseProbeTargets(m.Targets); err != nil {
+			return fmt.Errorf("probe_modules.%s.targets: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validate checks the MQTT settings when publishing is enabled.
+func (m *MQTT) validate() error {
+	if m.Broker == "" {
+		return nil
+	}
+	u, err := url.Parse(m.Broker)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("mqtt.broker: %q is not a broker URL", m.Broker)
+	}
+	switch u.Scheme {
+	case "tcp", "mqtt", "ssl", "tls", "mqtts", "ws", "wss":
+	default:
+		return fmt.Errorf("mqtt.broker: unsupported scheme %q", u.Scheme)
+	}
+	if m.Interval <= 0 {
+		return fmt.Errorf("mqtt.interval must be positive, got %s", m.Interval)
+	}
+	if m.QoS > 2 {
+		return fmt.Errorf("mqtt.qos must be 0, 1 or 2, got %d", m.QoS)
+	}
+	if m.TopicPrefix == "" || strings.ContainsAny(m.TopicPrefix, "+#") {
+		return fmt.Errorf("mqtt.topic_prefix: %q is not a topic name", m.TopicPrefix)
+	}
+	if m.Discovery && (m.DiscoveryPrefix == "" || strings.ContainsAny(m.DiscoveryPrefix, "+#")) {
+		return fmt.Errorf("mqtt.discovery_prefix: %q is not a topic name", m.DiscoveryPrefix)
+	}
+	if m.Password != "" && m.PasswordFile != "" {
+		return errors.New("mqtt: password and password_file are mutually exclusive")
+	}
+	return nil
+}
+
+// validate checks the InfluxDB settings when pushing is enabled.
+func (i *Influx) validate() error {
+	if i.URL == "" {
+		return nil
+	}
+	u, err := url.Parse(i.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("influx.url: %q is not an http or https URL", i.URL)
+	}
+	if i.Org == "" || i.Bucket == "" {
+		return errors.New("influx: org and bucket are required")
+	}
+	if i.Interval <= 0 {
+		return fmt.Errorf("influx.interval must be positive, got %s", i.Interval)
+	}
+	if i.Token != "" && i.TokenFile != "" {
+		return errors.New("influx: token and token_file are mutually exclusive")
+	}
+	return nil
+}
+
+// validate checks the OTLP settings when pushing is enabled.
+func (o *OTLP) validate() error {
+	if o.Endpoint == "" {
+		return nil
+	}
+	u, err := url.Parse(o.Endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("otlp.endpoint: %q is not an http or https URL", o.Endpoint)
+	}
+	if o.Protocol != "http/protobuf" && o.Protocol != "grpc" {
+		return fmt.Errorf("otlp.protocol must be http/protobuf or grpc, got %q", o.Protocol)
+	}
+	if o.Interval <= 0 {
+		return fmt.Errorf("otlp.interval must be positive, got %s", o.Interval)
+	}
+	return nil
+}
+
+// labelNameRe matches Prometheus label names.
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// validate checks the remote_write settings when pushing is enabled.
+func (r *RemoteWrite) validate() error {
+	if r.URL == "" {
+		return nil
+	}
+	u, err := url.Parse(r.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("remote_write.url: %q is not an http or https URL", r.URL)
+	}
+	if r.Interval <= 0 {
+		return fmt.Errorf("remote_write.interval must be positive, got %s", r.Interval)
+	}
+	if r.Password != "" && r.PasswordFile != "" {
+		return errors.New("remote_write: password and password_file are mutually exclusive")
+	}
+	if r.BearerToken != "" && r.BearerTokenFile != "" {
+		return errors.New("remote_write: bearer_token and bearer_token_file are mutually exclusive")
+	}
+	if r.Username != "" && (r.BearerToken != "" || r.BearerTokenFile != "") {
+		return errors.New("remote_write: basic auth and bearer token are mutually exclusive")
+	}
+	for name := range r.ExternalLabels {
+		if !labelNameRe.MatchString(name) || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("remote_write.external_labels: %q is not a label name", name)
+		}
+	}
+	q := r.Queue
+	if q.MaxBatches <= 0 {
+		return fmt.Errorf("remote_write.queue.max_batches must be positive, got %d", q.MaxBatches)
+	}
+	if q.MinBackoff <= 0 || q.MaxBackoff < q.MinBackoff {
+		return fmt.Errorf("remote_write.queue: backoff must be positive with min_backoff <= max_backoff, got %s and %s", q.MinBackoff, q.MaxBackoff)
+	}
+	return nil
+}
+
+// ReadSecrets reads every password_file into the matching password. Call it
+// again to pick up rotated secrets.
+func (c *Config) ReadSecrets() error {
+	if c.Router.PasswordFile != "" {
+		p, err := readPasswordFile(c.Router.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("router.password_file: %w", err)
+		}
+		c.Router.Password = p
+	}
+	if c.MQTT.PasswordFile != "" {
+		p, err := readPasswordFile(c.MQTT.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("mqtt.password_file: %w", err)
+		}
+		c.MQTT.Password = p
+	}
+	if c.Influx.TokenFile != "" {
+		t, err := readPasswordFile(c.Influx.TokenFile)
+		if err != nil {
+			return fmt.Errorf("influx.token_file: %w", err)
+		}
+		c.Influx.Token = t
+	}
+	if c.RemoteWrite.PasswordFile != "" {
+		p, err := readPasswordFile(c.RemoteWrite.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("remote_write.password_file: %w", err)
+		}
+		c.RemoteWrite.Password = p
+	}
+	if c.RemoteWrite.BearerTokenFile != "" {
+		t, err := readPasswordFile(c.RemoteWrite.BearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("remote_write.bearer_token_file: %w", err)
+		}
+		c.RemoteWrite.BearerToken = t
+	}
+	for name, m := range c.ProbeModules {
+		if m.PasswordFile == "" {
+			continue
+		}
+		p, err := readPasswordFile(m.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("probe_modules.%s.password_file: %w", name, err)
+		}
+		m.Password = p
+		c.ProbeModules[name] = m
+	}
+	return nil
+}
+
+// readPasswordFile returns the contents of path without trailing line
+// breaks. An empty file is an error so a secret caught mid-rotation is not
+// used as an empty password.
+func readPasswordFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	p := strings.TrimRight(string(data), "\r\n")
+	if p == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return p, nil
+}
+
+// checkModules reports the first name in modules that is not an optional
+// collector module.
+func checkModules(field string, modules []string) error {
+	known := map[string]bool{}
+	for _, name := range collector.ModuleNames() {
+		known[name] = true
+	}
+	for _, m := range modules {
+		if !known[strings.ToLower(strings.TrimSpace(m))] {
+			return fmt.Errorf("%s: unknown module %q (available: %s)", field, m, strings.Join(collector.ModuleNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// splitList splits a comma-separated value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
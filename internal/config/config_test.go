@@ -0,0 +1,425 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse_Full(t *testing.T) {
+	cfg, err := Parse([]byte(`
+listen_address: ":9684"
+router:
+  ip: 192.168.2.254
+  username: Administrator
+  password: secret
+  timeout: 10s
+collector:
+  modules: [voice, dns]
+  interfaces: [ETH0, ETH1]
+  labels:
+    force_wan_alias: true
+    redact_phone_numbers: true
+  security_rule_info: true
+events:
+  enabled: true
+  handlers: [NMC]
+speedtest:
+  interval: 0s
+probe_modules:
+  default:
+    username: Administrator
+    password: secret
+    timeout: 10s
+    modules: [voice, dns]
+    targets: [10.1.0.254, 10.2.0.0/16]
+  readonly:
+    username: monitor
+    password: other
+    targets: [10.0.0.0/8]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.ListenAddress != ":9684" || cfg.Router.IP != "192.168.2.254" || cfg.Router.Timeout != 10*time.Second {
+		t.Fatalf("unexpected router settings: %+v", cfg)
+	}
+	if len(cfg.Collector.Modules) != 2 || len(cfg.Collector.Interfaces) != 2 || !cfg.Collector.Labels.ForceWANAlias || !cfg.Collector.Labels.RedactPhoneNumbers {
+		t.Fatalf("unexpected collector settings: %+v", cfg.Collector)
+	}
+	if !cfg.Events.Enabled || cfg.Speedtest.Interval != 0 || cfg.Speedtest.MinInterval != 5*time.Minute {
+		t.Fatalf("expected explicit and default speedtest settings, got %+v", cfg.Speedtest)
+	}
+	m := cfg.ProbeModules["default"]
+	if m.Username != "Administrator" || m.Password != "secret" || m.Timeout != 10*time.Second || len(m.Modules) != 2 || len(m.Targets) != 2 {
+		t.Fatalf("unexpected default module: %+v", m)
+	}
+	if cfg.ProbeModules["readonly"].Timeout != 0 {
+		t.Fatalf("expected unset timeout to stay zero")
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	cases := map[string]string{
+		"unknown key":            "probe_module:\n  default: {}\n",
+		"invalid timeout":        "probe_modules:\n  default:\n    timeout: soon\n",
+		"negative timeout":       "probe_modules:\n  default:\n    timeout: -1s\n",
+		"router ip":              "router:\n  ip: router.local\n",
+		"zero router timeout":    "router:\n  timeout: 0s\n",
+		"unknown module":         "collector:\n  modules: [voice, bogus]\n",
+		"unknown probe module":   "probe_modules:\n  fleet:\n    modules: [bogus]\n    targets: [10.0.0.0/8]\n",
+		"missing probe targets":  "probe_modules:\n  fleet:\n    username: monitor\n",
+		"probe target":           "probe_modules:\n  fleet:\n    targets: [router.local]\n",
+		"interface name":         "collector:\n  interfaces: [\"ETH 0\"]\n",
+		"negative speedtest":     "speedtest:\n  interval: -1h\n",
+		"misspelled label field": "collector:\n  labels:\n    force_wan: true\n",
+	}
+	for name, doc := range cases {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	cfg, err := Parse(nil)
+	if err != nil {
+		t.Fatalf("expected an empty document to be valid: %v", err)
+	}
+	if cfg.Router.Timeout != Default().Router.Timeout {
+		t.Fatalf("expected defaults for an empty document")
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	cfg := Default()
+	cfg.Collector.Modules = []string{"voice"}
+	env := map[string]string{
+		"EXPERIA_V10_ROUTER_IP":            "192.0.2.1",
+		"EXPERIA_V10_TIMEOUT":              "2s",
+		"EXPERIA_V10_MODULES":              "dns, firmware,",
+		"EXPERIA_V10_REDACT_PHONE_NUMBERS": "TRUE",
+		"EXPERIA_V10_EVENTS":               "0",
+	}
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Router.IP != "192.0.2.1" || cfg.Router.Timeout != 2*time.Second {
+		t.Fatalf("expected environment overrides, got %+v", cfg.Router)
+	}
+	if len(cfg.Collector.Modules) != 2 || cfg.Collector.Modules[1] != "firmware" || !cfg.Collector.Labels.RedactPhoneNumbers || cfg.Events.Enabled {
+		t.Fatalf("unexpected collector settings: %+v", cfg.Collector)
+	}
+
+	env = map[string]string{
+		"EXPERIA_EXPECT_NETDEV_IFACES": "eth2, eth3",
+		"EXPERIA_FORCE_WAN_ALIAS":      "true",
+		"EXPERIA_E2E":                  "1",
+	}
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Collector.Interfaces) != 2 || cfg.Collector.Interfaces[1] != "eth3" || !cfg.Collector.Labels.ForceWANAlias || !cfg.Collector.Debug {
+		t.Fatalf("expected the collector variables to override the settings, got %+v", cfg.Collector)
+	}
+	env["EXPERIA_V10_SPEEDTEST_INTERVAL"] = "daily"
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err == nil || !strings.Contains(err.Error(), "EXPERIA_V10_SPEEDTEST_INTERVAL") {
+		t.Fatalf("expected an error naming the variable, got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experia.yml")
+	if err := os.WriteFile(path, []byte("probe_modules:\n  default:\n    timeout: oops\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Fatalf("expected error naming the file, got %v", err)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yml")); err == nil {
+		t.Fatalf("expected error for a missing file")
+	}
+
+	if err := os.WriteFile(path, []byte("router:\n  ip: 192.0.2.1\n  username: file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EXPERIA_V10_ROUTER_USERNAME", "env")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Router.IP != "192.0.2.1" || cfg.Router.Username != "env" {
+		t.Fatalf("expected the environment to override the file, got %+v", cfg.Router)
+	}
+}
+
+func TestPasswordFile(t *testing.T) {
+	dir := t.TempDir()
+	router := filepath.Join(dir, "router")
+	probe := filepath.Join(dir, "probe")
+	if err := os.WriteFile(router, []byte("s3cret\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(probe, []byte("other"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Parse([]byte("router:\n  password_file: " + router + "\nprobe_modules:\n  fleet:\n    targets: [10.0.0.0/8]\n    password_file: " + probe + "\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cfg.ReadSecrets(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Router.Password != "s3cret" || cfg.ProbeModules["fleet"].Password != "other" {
+		t.Fatalf("expected passwords from the files, got %q and %q", cfg.Router.Password, cfg.ProbeModules["fleet"].Password)
+	}
+
+	if err := os.WriteFile(router, []byte("rotated\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.ReadSecrets(); err != nil || cfg.Router.Password != "rotated" {
+		t.Fatalf("expected the rotated password, got %q (%v)", cfg.Router.Password, err)
+	}
+	if err := os.WriteFile(router, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.ReadSecrets(); err == nil || cfg.Router.Password != "rotated" {
+		t.Fatalf("expected an empty file to fail and keep the password, got %q (%v)", cfg.Router.Password, err)
+	}
+
+	if _, err := Parse([]byte("router:\n  password: a\n  password_file: " + router + "\n")); err == nil {
+		t.Fatalf("expected password and password_file together to be rejected")
+	}
+	if _, err := Parse([]byte("probe_modules:\n  fleet:\n    password: a\n    password_file: " + probe + "\n")); err == nil {
+		t.Fatalf("expected password and password_file together in a probe module to be rejected")
+	}
+}
+
+func TestApplyEnv_PasswordFile(t *testing.T) {
+	cfg := Default()
+	cfg.Router.Password = "from-file"
+	env := map[string]string{"EXPERIA_V10_ROUTER_PASSWORD_FILE": "/run/secrets/router"}
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Router.Password != "" || cfg.Router.PasswordFile != "/run/secrets/router" {
+		t.Fatalf("expected the environment's password file to replace the password, got %+v", cfg.Router)
+	}
+	env["EXPERIA_V10_ROUTER_PASSWORD"] = "p"
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err == nil {
+		t.Fatalf("expected both password variables together to be rejected")
+	}
+}
+
+func TestApplyEnv_MQTTPassword(t *testing.T) {
+	cfg := Default()
+	cfg.MQTT.PasswordFile = "/run/secrets/mqtt"
+	env := map[string]string{"EXPERIA_V10_MQTT_PASSWORD": "p"}
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.MQTT.Password != "p" || cfg.MQTT.PasswordFile != "" {
+		t.Fatalf("expected the environment's password to replace the password file, got %+v", cfg.MQTT)
+	}
+	env = map[string]string{"EXPERIA_V10_MQTT_PASSWORD_FILE": "/run/secrets/mqtt"}
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.MQTT.Password != "" || cfg.MQTT.PasswordFile != "/run/secrets/mqtt" {
+		t.Fatalf("expected the environment's password file to replace the password, got %+v", cfg.MQTT)
+	}
+	env["EXPERIA_V10_MQTT_PASSWORD"] = "p"
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err == nil {
+		t.Fatalf("expected both password variables together to be rejected")
+	}
+}
+
+func TestApplyEnv_InfluxToken(t *testing.T) {
+	cfg := Default()
+	cfg.Influx.TokenFile = "/run/secrets/influx"
+	env := map[string]string{"EXPERIA_V10_INFLUX_TOKEN": "tok"}
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Influx.Token != "tok" || cfg.Influx.TokenFile != "" {
+		t.Fatalf("expected the environment's token to replace the token file, got %+v", cfg.Influx)
+	}
+	env["EXPERIA_V10_INFLUX_TOKEN_FILE"] = "/run/secrets/influx"
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err == nil {
+		t.Fatalf("expected both token variables together to be rejected")
+	}
+}
+
+func TestApplyEnv_RemoteWriteSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.RemoteWrite.PasswordFile = "/run/secrets/rw-password"
+	cfg.RemoteWrite.BearerTokenFile = "/run/secrets/rw-token"
+	env := map[string]string{"EXPERIA_V10_REMOTE_WRITE_PASSWORD": "p", "EXPERIA_V10_REMOTE_WRITE_BEARER_TOKEN": "tok"}
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := cfg.RemoteWrite
+	if r.Password != "p" || r.PasswordFile != "" || r.BearerToken != "tok" || r.BearerTokenFile != "" {
+		t.Fatalf("expected the environment's secrets to replace the files, got %+v", r)
+	}
+	env["EXPERIA_V10_REMOTE_WRITE_BEARER_TOKEN_FILE"] = "/run/secrets/rw-token"
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err == nil {
+		t.Fatalf("expected both bearer token variables together to be rejected")
+	}
+}
+
+func TestParse_MQTT(t *testing.T) {
+	cfg, err := Parse([]byte("mqtt:\n  broker: tcp://homeassistant.local:1883\n  discovery: false\n  qos: 1\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := cfg.MQTT
+	if m.Broker != "tcp://homeassistant.local:1883" || m.Discovery || m.QoS != 1 || m.TopicPrefix != "experia_v10" || m.Interval != time.Minute {
+		t.Fatalf("expected explicit and default MQTT settings, got %+v", m)
+	}
+
+	cases := map[string]string{
+		"broker url":       "mqtt:\n  broker: homeassistant.local\n",
+		"broker scheme":    "mqtt:\n  broker: http://homeassistant.local\n",
+		"interval":         "mqtt:\n  broker: tcp://b:1883\n  interval: 0s\n",
+		"qos":              "mqtt:\n  broker: tcp://b:1883\n  qos: 3\n",
+		"topic wildcard":   "mqtt:\n  broker: tcp://b:1883\n  topic_prefix: experia/#\n",
+		"discovery prefix": "mqtt:\n  broker: tcp://b:1883\n  discovery_prefix: \"\"\n",
+		"password file":    "mqtt:\n  broker: tcp://b:1883\n  password: a\n  password_file: /run/secrets/mqtt\n",
+	}
+	for name, doc := range cases {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	// Without a broker the remaining settings are not checked.
+	if _, err := Parse([]byte("mqtt:\n  interval: 0s\n")); err != nil {
+		t.Fatalf("expected disabled MQTT settings to be ignored, got %v", err)
+	}
+
+	env := map[string]string{"EXPERIA_V10_MQTT_BROKER": "ssl://broker:8883", "EXPERIA_V10_MQTT_INTERVAL": "30s"}
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.MQTT.Broker != "ssl://broker:8883" || cfg.MQTT.Interval != 30*time.Second {
+		t.Fatalf("expected environment overrides, got %+v", cfg.MQTT)
+	}
+}
+
+func TestParse_Influx(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Parse([]byte("influx:\n  url: http://influxdb:8086\n  token_file: " + tokenFile + "\n  org: home\n  bucket: router\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cfg.ReadSecrets(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if i := cfg.Influx; i.URL != "http://influxdb:8086" || i.Token != "s3cret" || i.Interval != time.Minute {
+		t.Fatalf("expected explicit and default InfluxDB settings, got %+v", i)
+	}
+
+	cases := map[string]string{
+		"url":        "influx:\n  url: influxdb:8086\n  org: o\n  bucket: b\n",
+		"scheme":     "influx:\n  url: udp://influxdb:8089\n  org: o\n  bucket: b\n",
+		"bucket":     "influx:\n  url: http://influxdb:8086\n  org: o\n",
+		"interval":   "influx:\n  url: http://influxdb:8086\n  org: o\n  bucket: b\n  interval: 0s\n",
+		"token file": "influx:\n  url: http://influxdb:8086\n  org: o\n  bucket: b\n  token: a\n  token_file: /run/secrets/influx\n",
+	}
+	for name, doc := range cases {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	env := map[string]string{"EXPERIA_V10_INFLUX_BUCKET": "experia", "EXPERIA_V10_INFLUX_INTERVAL": "10s"}
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Influx.Bucket != "experia" || cfg.Influx.Interval != 10*time.Second {
+		t.Fatalf("expected environment overrides, got %+v", cfg.Influx)
+	}
+}
+
+func TestParse_OTLP(t *testing.T) {
+	cfg, err := Parse([]byte("otlp:\n  endpoint: http://otel-collector:4317\n  protocol: grpc\n  headers:\n    api-key: k\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if o := cfg.OTLP; o.Endpoint != "http://otel-collector:4317" || o.Protocol != "grpc" || o.Headers["api-key"] != "k" || o.Interval != time.Minute {
+		t.Fatalf("expected explicit and default OTLP settings, got %+v", o)
+	}
+	if Default().OTLP.Protocol != "http/protobuf" {
+		t.Fatalf("expected OTLP/HTTP by default")
+	}
+
+	cases := map[string]string{
+		"endpoint": "otlp:\n  endpoint: otel-collector:4318\n",
+		"protocol": "otlp:\n  endpoint: http://otel-collector:4318\n  protocol: http/json\n",
+		"interval": "otlp:\n  endpoint: http://otel-collector:4318\n  interval: -1m\n",
+	}
+	for name, doc := range cases {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	env := map[string]string{"EXPERIA_V10_OTLP_ENDPOINT": "https://otlp.example.com", "EXPERIA_V10_OTLP_PROTOCOL": "http/protobuf", "EXPERIA_V10_OTLP_INTERVAL": "15s"}
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.OTLP.Endpoint != "https://otlp.example.com" || cfg.OTLP.Protocol != "http/protobuf" || cfg.OTLP.Interval != 15*time.Second {
+		t.Fatalf("expected environment overrides, got %+v", cfg.OTLP)
+	}
+}
+
+func TestParse_RemoteWrite(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("tok\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Parse([]byte("remote_write:\n  url: https://prometheus.example.com/api/v1/write\n  bearer_token_file: " + tokenFile + "\n  external_labels:\n    site: home\n  queue:\n    directory: /var/lib/experia/queue\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cfg.ReadSecrets(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := cfg.RemoteWrite
+	if r.BearerToken != "tok" || r.ExternalLabels["site"] != "home" || r.Interval != time.Minute || r.Queue.MaxBatches != 1440 || r.Queue.Directory != "/var/lib/experia/queue" || r.Queue.MaxBackoff != time.Minute {
+		t.Fatalf("expected explicit and default remote_write settings, got %+v", r)
+	}
+
+	const base = "remote_write:\n  url: http://prom:9090/api/v1/write\n"
+	cases := map[string]string{
+		"url":            "remote_write:\n  url: prom:9090\n",
+		"interval":       base + "  interval: 0s\n",
+		"password file":  base + "  password: a\n  password_file: /run/secrets/rw\n",
+		"auth":           base + "  username: u\n  bearer_token: t\n",
+		"label name":     base + "  external_labels:\n    site-name: home\n",
+		"reserved label": base + "  external_labels:\n    __name__: x\n",
+		"queue size":     base + "  queue:\n    max_batches: 0\n",
+		"backoff":        base + "  queue:\n    min_backoff: 2m\n",
+	}
+	for name, doc := range cases {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	env := map[string]string{"EXPERIA_V10_REMOTE_WRITE_EXTERNAL_LABELS": "site=branch, region=nl", "EXPERIA_V10_REMOTE_WRITE_INTERVAL": "30s"}
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if l := cfg.RemoteWrite.ExternalLabels; len(l) != 2 || l["site"] != "branch" || l["region"] != "nl" || cfg.RemoteWrite.Interval != 30*time.Second {
+		t.Fatalf("expected environment overrides, got %+v", cfg.RemoteWrite)
+	}
+	env = map[string]string{"EXPERIA_V10_REMOTE_WRITE_EXTERNAL_LABELS": "site"}
+	if err := cfg.ApplyEnv(func(k string) string { return env[k] }); err == nil {
+		t.Fatalf("expected an invalid label list to be rejected")
+	}
+}
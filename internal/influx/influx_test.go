@@ -0,0 +1,191 @@
+package influx
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// routerCollector emits a scrape's worth of wan, netdev and stats families
+// using the collector's descriptors.
+type routerCollector struct{}
+
+func (routerCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (routerCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(metrics.IfupTime, prometheus.GaugeValue, 1, "ethernet", "dhcp", "Connected", "203.0.113.7", "aa:bb:cc:dd:ee:ff")
+	ch <- prometheus.MustNewConstMetric(metrics.WanIfname, prometheus.GaugeValue, 1, "eth4")
+	ch <- prometheus.MustNewConstMetric(metrics.WanInfo, prometheus.GaugeValue, 1, "eth4", "wan", "wan,up", "aa:bb:cc:dd:ee:ff", "ethernet", "wan")
+	ch <- prometheus.MustNewConstMetric(metrics.WanRxBytes, prometheus.CounterValue, 1000, "eth4")
+	ch <- prometheus.MustNewConstMetric(metrics.WanUp, prometheus.GaugeValue, 1, "eth4")
+	ch <- prometheus.MustNewConstMetric(metrics.NetdevInfo, prometheus.GaugeValue, 1, "eth1", "LAN port 1", "lan", "aa:aa:aa:aa:aa:01", "ethernet", "lan")
+	ch <- prometheus.MustNewConstMetric(metrics.NetdevUp, prometheus.GaugeValue, 1, "eth1")
+	ch <- prometheus.MustNewConstMetric(metrics.NetdevRxBytes, prometheus.CounterValue, 42, "eth1")
+	ch <- prometheus.MustNewConstMetric(metrics.NetdevFlag, prometheus.GaugeValue, 1, "eth1", "ipv4-up")
+	ch <- prometheus.MustNewConstMetric(metrics.NetdevRxBytes, prometheus.CounterValue, 7, "eth 2")
+	ch <- prometheus.MustNewConstMetric(metrics.NetdevInfo, prometheus.GaugeValue, 0, "", "", "", "", "", "")
+	up := prometheus.NewGauge(prometheus.GaugeOpts{Name: metrics.MetricPrefix + "up", Help: "up"})
+	up.Set(1)
+	up.Collect(ch)
+	errs := prometheus.NewCounter(prometheus.CounterOpts{Name: metrics.MetricPrefix + "scrape_errors_total", Help: "errors"})
+	errs.Add(2)
+	errs.Collect(ch)
+}
+
+func newRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(routerCollector{})
+	return reg
+}
+
+const wantLines = `experia_v10_netdev,interface=eth\ 2 rx_bytes=7 1700000000000000000
+experia_v10_netdev,interface=eth1,role=lan flag_ipv4_up=true,rx_bytes=42,up=1 1700000000000000000
+experia_v10_stats scrape_errors=2,up=1 1700000000000000000
+experia_v10_wan,interface=eth4,role=wan connected=1,connection_state="Connected",ip="203.0.113.7",link_type="ethernet",protocol="dhcp",rx_bytes=1000,up=1 1700000000000000000
+`
+
+func TestWrite(t *testing.T) {
+	families, err := newRegistry().Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, families, time.Unix(1700000000, 0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != wantLines {
+		t.Fatalf("unexpected line protocol:\n%s\nwant:\n%s", buf.String(), wantLines)
+	}
+}
+
+func TestWrite_NonFinite(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.CollectorFunc(func(ch chan<- prometheus.Metric) {
+		ch <- prometheus.MustNewConstMetric(metrics.NetdevMtu, prometheus.GaugeValue, math.NaN(), "eth1")
+		ch <- prometheus.MustNewConstMetric(metrics.NetdevUp, prometheus.GaugeValue, 1, "eth1")
+		ch <- prometheus.MustNewConstMetric(metrics.NetdevMtu, prometheus.GaugeValue, math.Inf(1), "eth2")
+	}))
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, families, time.Unix(1700000000, 0)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The NaN field is dropped and eth2, which has no other field, is left
+	// out.
+	if want := "experia_v10_netdev,interface=eth1 up=1 1700000000000000000\n"; buf.String() != want {
+		t.Fatalf("unexpected line protocol:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestFieldValue(t *testing.T) {
+	cases := map[any]string{
+		`say "hi" \ bye`: `"say \"hi\" \\ bye"`,
+		true:             "true",
+		1.5:              "1.5",
+		1e21:             "1e+21",
+	}
+	for in, want := range cases {
+		if got := fieldValue(in); got != want {
+			t.Errorf("fieldValue(%v) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler(newRegistry()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/influx", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	if !strings.Contains(rec.Body.String(), "experia_v10_netdev,interface=eth1,role=lan ") {
+		t.Fatalf("expected netdev lines, got:\n%s", rec.Body.String())
+	}
+}
+
+func TestPusher(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		reqs  []*http.Request
+		body  string
+		fail  bool
+		calls = make(chan struct{}, 10)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		reqs = append(reqs, r)
+		body = string(data)
+		f := fail
+		mu.Unlock()
+		calls <- struct{}{}
+		if f {
+			http.Error(w, `{"code":"unauthorized","message":"unauthorized access"}`, http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	p, err := NewPusher(newRegistry(), PushOptions{URL: srv.URL + "/", Token: "s3cret", Org: "home", Bucket: "router", Interval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Push(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mu.Lock()
+	r := reqs[0]
+	if r.Method != http.MethodPost || r.URL.Path != "/api/v2/write" || r.URL.Query().Get("org") != "home" || r.URL.Query().Get("bucket") != "router" || r.URL.Query().Get("precision") != "ns" {
+		t.Fatalf("unexpected write request %s %s", r.Method, r.URL)
+	}
+	if r.Header.Get("Authorization") != "Token s3cret" {
+		t.Fatalf("expected the token, got %q", r.Header.Get("Authorization"))
+	}
+	if !strings.Contains(body, "experia_v10_wan,interface=eth4,role=wan ") {
+		t.Fatalf("unexpected body:\n%s", body)
+	}
+	fail = true
+	mu.Unlock()
+
+	// A rotated token is sent from the next write on.
+	if !p.SetToken("rotated") || p.SetToken("rotated") {
+		t.Fatalf("expected SetToken to report only a changed token")
+	}
+	if err := p.Push(context.Background()); err == nil || !strings.Contains(err.Error(), "unauthorized access") {
+		t.Fatalf("expected the server's error, got %v", err)
+	}
+	mu.Lock()
+	if got := reqs[1].Header.Get("Authorization"); got != "Token rotated" {
+		t.Fatalf("expected the rotated token, got %q", got)
+	}
+	mu.Unlock()
+
+	// Run pushes right away and stops with the context.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- p.Run(ctx) }()
+	<-calls
+	<-calls
+	<-calls
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := NewPusher(nil, PushOptions{URL: "influxdb:8086"}); err == nil {
+		t.Fatalf("expected an invalid URL to be rejected")
+	}
+}
@@ -0,0 +1,244 @@
+// Package influx converts the collector's metrics to InfluxDB line protocol,
+// served on /influx for Telegraf and pushed to the InfluxDB v2 write API.
+//
+// Three measurements are produced from the existing metric families:
+//
+//	experia_v10_wan     wan_* and internet_connection, tagged by interface and role
+//	experia_v10_netdev  netdev_*, tagged by interface and role
+//	experia_v10_stats   the exporter's own up and error counters
+//
+// Families of the optional modules are not converted.
+package influx
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	prefix = "experia_v10_"
+
+	MeasurementWAN    = "experia_v10_wan"
+	MeasurementNetdev = "experia_v10_netdev"
+	MeasurementStats  = "experia_v10_stats"
+)
+
+// statsFamilies are the exporter's own metrics written to
+// MeasurementStats, keyed by family name without the prefix.
+var statsFamilies = map[string]bool{
+	"up":                      true,
+	"auth_errors_total":       true,
+	"scrape_errors_total":     true,
+	"permission_errors_total": true,
+}
+
+// infoFamilies carry the role of an interface in a label. They are used
+// for the role tag and not written as fields.
+var infoFamilies = map[string]bool{
+	"wan_info":                  true,
+	"netdev_info":               true,
+	"wan_ifname":                true,
+	"netdev_port_set_port_info": true,
+}
+
+// point is one line: a measurement and tag set with its fields.
+type point struct {
+	measurement string
+	iface, role string
+	fields      map[string]any
+}
+
+func (p *point) key() string {
+	return p.measurement + "\x00" + p.iface
+}
+
+// Write encodes families as line protocol with timestamp ts, one line per
+// measurement and interface, sorted for stable output.
+func Write(w io.Writer, families []*dto.MetricFamily, ts time.Time) error {
+	points := map[string]*point{}
+	get := func(measurement, iface string) *point {
+		p := &point{measurement: measurement, iface: iface}
+		if existing, ok := points[p.key()]; ok {
+			return existing
+		}
+		p.fields = map[string]any{}
+		points[p.key()] = p
+		return p
+	}
+
+	// Roles come from the info families and apply to every field of the
+	// interface.
+	roles := map[string]string{}
+	wanIface := ""
+	for _, f := range families {
+		switch strings.TrimPrefix(f.GetName(), prefix) {
+		case "wan_info", "netdev_info":
+			for _, m := range f.GetMetric() {
+				if l := labels(m); l["ifname"] != "" && l["role"] != "" {
+					roles[l["ifname"]] = l["role"]
+				}
+			}
+		case "wan_ifname":
+			for _, m := range f.GetMetric() {
+				if value(m) == 1 {
+					wanIface = labels(m)["ifname"]
+				}
+			}
+		}
+	}
+
+	for _, f := range families {
+		name := strings.TrimPrefix(f.GetName(), prefix)
+		if infoFamilies[name] {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			l := labels(m)
+			v := value(m)
+			switch {
+			case name == "internet_connection":
+				p := get(MeasurementWAN, wanIface)
+				p.fields["connected"] = v
+				for _, k := range []string{"connection_state", "ip", "link_type", "protocol"} {
+					if l[k] != "" {
+						p.fields[k] = l[k]
+					}
+				}
+			case name == "netdev_flag":
+				if l["ifname"] == "" {
+					continue
+				}
+				get(MeasurementNetdev, l["ifname"]).fields["flag_"+fieldName(l["flag"])] = v == 1
+			case strings.HasPrefix(name, "wan_"):
+				iface := l["ifname"]
+				if iface == "" {
+					iface = wanIface
+				}
+				get(MeasurementWAN, iface).fields[fieldName(strings.TrimPrefix(name, "wan_"))] = v
+			case strings.HasPrefix(name, "netdev_"):
+				if l["ifname"] == "" {
+					continue
+				}
+				get(MeasurementNetdev, l["ifname"]).fields[fieldName(strings.TrimPrefix(name, "netdev_"))] = v
+			case statsFamilies[name]:
+				get(MeasurementStats, "").fields[fieldName(name)] = v
+			}
+		}
+	}
+
+	sorted := make([]*point, 0, len(points))
+	for _, p := range points {
+		// Line protocol has no NaN or infinity, so such fields are left
+		// out, and with them a line that has no other fields.
+		for k, v := range p.fields {
+			if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+				delete(p.fields, k)
+			}
+		}
+		if len(p.fields) == 0 {
+			continue
+		}
+		p.role = roles[p.iface]
+		sorted = append(sorted, p)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].key() < sorted[j].key() })
+
+	bw := bufio.NewWriter(w)
+	for _, p := range sorted {
+		writeLine(bw, p, ts)
+	}
+	return bw.Flush()
+}
+
+// writeLine writes p as one line of line protocol.
+func writeLine(w *bufio.Writer, p *point, ts time.Time) {
+	w.WriteString(escape(p.measurement, ", "))
+	for _, tag := range [][2]string{{"interface", p.iface}, {"role", p.role}} {
+		if tag[1] == "" {
+			continue
+		}
+		w.WriteString("," + tag[0] + "=" + escape(tag[1], ",= "))
+	}
+	keys := make([]string, 0, len(p.fields))
+	for k := range p.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		if i == 0 {
+			w.WriteByte(' ')
+		} else {
+			w.WriteByte(',')
+		}
+		w.WriteString(escape(k, ",= ") + "=" + fieldValue(p.fields[k]))
+	}
+	w.WriteString(" " + strconv.FormatInt(ts.UnixNano(), 10) + "\n")
+}
+
+// fieldValue formats a float, bool or string field value.
+func fieldValue(v any) string {
+	switch t := v.(type) {
+	case bool:
+		return strconv.FormatBool(t)
+	case string:
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(t) + `"`
+	case float64:
+		return strconv.FormatFloat(t, 'g', -1, 64)
+	}
+	return "0"
+}
+
+// fieldName drops the _total suffix of counters and replaces characters
+// that are awkward in Flux and InfluxQL with underscores.
+func fieldName(s string) string {
+	s = strings.TrimSuffix(s, "_total")
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// escape backslash-escapes the characters in special.
+func escape(s, special string) string {
+	if !strings.ContainsAny(s, special) {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func labels(m *dto.Metric) map[string]string {
+	out := map[string]string{}
+	for _, l := range m.GetLabel() {
+		out[l.GetName()] = l.GetValue()
+	}
+	return out
+}
+
+// value returns the sample value of a gauge, counter or untyped metric.
+func value(m *dto.Metric) float64 {
+	switch {
+	case m.Gauge != nil:
+		return m.GetGauge().GetValue()
+	case m.Counter != nil:
+		return m.GetCounter().GetValue()
+	case m.Untyped != nil:
+		return m.GetUntyped().GetValue()
+	}
+	return 0
+}
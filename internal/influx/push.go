@@ -0,0 +1,134 @@
+package influx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Handler serves the gathered metrics as line protocol, for example for
+// Telegraf's http input with data_format = "influx".
+func Handler(g prometheus.Gatherer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		families, err := g.Gather()
+		if err != nil && len(families) == 0 {
+			http.Error(w, fmt.Sprintf("failed to gather metrics: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_ = Write(w, families, time.Now())
+	})
+}
+
+// PushOptions configures a Pusher.
+type PushOptions struct {
+	// URL is the InfluxDB base URL, for example http://influxdb:8086.
+	URL      string
+	Token    string
+	Org      string
+	Bucket   string
+	Interval time.Duration
+	// Timeout bounds one write request.
+	Timeout time.Duration
+}
+
+// Pusher writes the gathered metrics to the InfluxDB v2 write API on an
+// interval.
+type Pusher struct {
+	opts     PushOptions
+	gatherer prometheus.Gatherer
+	client   *http.Client
+	writeURL string
+
+	mu sync.Mutex
+	// token is sent with every write; SetToken replaces it.
+	token string
+}
+
+// NewPusher returns a Pusher that gathers from g.
+func NewPusher(g prometheus.Gatherer, opts PushOptions) (*Pusher, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("influx: %q is not an InfluxDB URL", opts.URL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/v2/write"
+	u.RawQuery = url.Values{"org": {opts.Org}, "bucket": {opts.Bucket}, "precision": {"ns"}}.Encode()
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	return &Pusher{opts: opts, gatherer: g, client: &http.Client{Timeout: opts.Timeout}, writeURL: u.String(), token: opts.Token}, nil
+}
+
+// SetToken replaces the API token used from the next write on, for example
+// after influx.token_file was rotated. It reports whether the token changed.
+func (p *Pusher) SetToken(token string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token == token {
+		return false
+	}
+	p.token = token
+	return true
+}
+
+// Run pushes right away and then every Interval until ctx is done. Failed
+// writes are logged and retried on the next interval.
+func (p *Pusher) Run(ctx context.Context) error {
+	if p.opts.Interval <= 0 {
+		return fmt.Errorf("influx: interval must be positive, got %s", p.opts.Interval)
+	}
+	t := time.NewTicker(p.opts.Interval)
+	defer t.Stop()
+	for {
+		if err := p.Push(ctx); err != nil {
+			log.Printf("ERROR: InfluxDB write failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Push gathers once and writes the points.
+func (p *Pusher) Push(ctx context.Context) error {
+	families, err := p.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return err
+	}
+	var body bytes.Buffer
+	if err := Write(&body, families, time.Now()); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.writeURL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	p.mu.Lock()
+	token := p.token
+	p.mu.Unlock()
+	if token != "" {
+		req.Header.Set("Authorization", "Token "+token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("write returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package mqtt
+
+import (
+	"regexp"
+	"strings"
+)
+
+// entity is a Home Assistant sensor or binary_sensor announced through MQTT
+// discovery.
+type entity struct {
+	Component   string
+	ObjectID    string
+	Name        string
+	StateTopic  string
+	Template    string
+	DeviceClass string
+	Unit        string
+	StateClass  string
+	Category    string
+}
+
+// discoveryConfig is the payload of a <discovery_prefix>/<component>/
+// <node_id>/<object_id>/config message.
+type discoveryConfig struct {
+	Name                string          `json:"name"`
+	UniqueID            string          `json:"unique_id"`
+	StateTopic          string          `json:"state_topic"`
+	ValueTemplate       string          `json:"value_template"`
+	AvailabilityTopic   string          `json:"availability_topic"`
+	PayloadAvailable    string          `json:"payload_available"`
+	PayloadNotAvailable string          `json:"payload_not_available"`
+	DeviceClass         string          `json:"device_class,omitempty"`
+	UnitOfMeasurement   string          `json:"unit_of_measurement,omitempty"`
+	StateClass          string          `json:"state_class,omitempty"`
+	EntityCategory      string          `json:"entity_category,omitempty"`
+	PayloadOn           string          `json:"payload_on,omitempty"`
+	PayloadOff          string          `json:"payload_off,omitempty"`
+	Device              discoveryDevice `json:"device"`
+}
+
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer,omitempty"`
+	Model        string   `json:"model,omitempty"`
+	SWVersion    string   `json:"sw_version,omitempty"`
+	HWVersion    string   `json:"hw_version,omitempty"`
+}
+
+var objectIDRe = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// objectID lowercases s and replaces characters Home Assistant does not
+// accept in object IDs with underscores.
+func objectID(s string) string {
+	return strings.Trim(objectIDRe.ReplaceAllString(strings.ToLower(s), "_"), "_")
+}
+
+// wanEntities are the sensors backed by the WAN state topic.
+func wanEntities(topic string) []entity {
+	return []entity{
+		{Component: "binary_sensor", ObjectID: "wan_connected", Name: "WAN connected", StateTopic: topic, Template: "{{ 'ON' if value_json.connected else 'OFF' }}", DeviceClass: "connectivity"},
+		{Component: "sensor", ObjectID: "wan_connection_state", Name: "WAN connection state", StateTopic: topic, Template: "{{ value_json.connection_state }}"},
+		{Component: "sensor", ObjectID: "wan_ip", Name: "WAN IP address", StateTopic: topic, Template: "{{ value_json.ip }}", Category: "diagnostic"},
+		{Component: "sensor", ObjectID: "wan_rx_bytes", Name: "WAN received", StateTopic: topic, Template: "{{ value_json.rx_bytes }}", DeviceClass: "data_size", Unit: "B", StateClass: "total_increasing"},
+		{Component: "sensor", ObjectID: "wan_tx_bytes", Name: "WAN sent", StateTopic: topic, Template: "{{ value_json.tx_bytes }}", DeviceClass: "data_size", Unit: "B", StateClass: "total_increasing"},
+	}
+}
+
+// interfaceEntities are the sensors backed by an interface state topic.
+func interfaceEntities(ifname, topic string) []entity {
+	id := objectID(ifname)
+	return []entity{
+		{Component: "binary_sensor", ObjectID: id + "_up", Name: ifname + " link", StateTopic: topic, Template: "{{ 'ON' if value_json.up else 'OFF' }}", DeviceClass: "connectivity"},
+		{Component: "sensor", ObjectID: id + "_rx_bytes", Name: ifname + " received", StateTopic: topic, Template: "{{ value_json.rx_bytes }}", DeviceClass: "data_size", Unit: "B", StateClass: "total_increasing"},
+		{Component: "sensor", ObjectID: id + "_tx_bytes", Name: ifname + " sent", StateTopic: topic, Template: "{{ value_json.tx_bytes }}", DeviceClass: "data_size", Unit: "B", StateClass: "total_increasing"},
+		{Component: "sensor", ObjectID: id + "_rx_errors", Name: ifname + " receive errors", StateTopic: topic, Template: "{{ value_json.rx_errors }}", StateClass: "total_increasing", Category: "diagnostic"},
+		{Component: "sensor", ObjectID: id + "_tx_errors", Name: ifname + " transmit errors", StateTopic: topic, Template: "{{ value_json.tx_errors }}", StateClass: "total_increasing", Category: "diagnostic"},
+	}
+}
+
+// deviceEntities are the sensors backed by the device state topic.
+func deviceEntities(topic string) []entity {
+	return []entity{
+		{Component: "sensor", ObjectID: "uptime", Name: "Uptime", StateTopic: topic, Template: "{{ value_json.uptime_seconds }}", DeviceClass: "duration", Unit: "s", StateClass: "measurement", Category: "diagnostic"},
+		{Component: "sensor", ObjectID: "software_version", Name: "Software version", StateTopic: topic, Template: "{{ value_json.software_version }}", Category: "diagnostic"},
+	}
+}
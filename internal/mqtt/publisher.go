@@ -0,0 +1,277 @@
+// Package mqtt publishes the collected router state to an MQTT broker for
+// Home Assistant and similar consumers. Each poll gathers the collector,
+// publishes JSON state messages for the WAN connection, every interface and
+// the device, and announces the matching sensors through Home Assistant MQTT
+// discovery. Availability is reported on <topic_prefix>/status, with an
+// "offline" last will so the sensors go unavailable when the exporter
+// disappears.
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	payloadOnline  = "online"
+	payloadOffline = "offline"
+)
+
+// Options configures a Publisher.
+type Options struct {
+	// Broker is the broker URL, for example tcp://homeassistant.local:1883
+	// or ssl://broker:8883.
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+	// TopicPrefix is the root of the state and availability topics.
+	TopicPrefix string
+	// DiscoveryPrefix is the Home Assistant discovery prefix; empty
+	// disables discovery.
+	DiscoveryPrefix string
+	// NodeID identifies the router in discovery topics and unique IDs.
+	NodeID   string
+	Interval time.Duration
+	QoS      byte
+	// Retain publishes the state messages retained.
+	Retain bool
+}
+
+const (
+	publishTimeout       = 10 * time.Second
+	connectRetryInterval = 5 * time.Second
+	maxReconnectInterval = time.Minute
+)
+
+var errNotConnected = errors.New("not connected to the MQTT broker")
+
+// Publisher publishes gathered metrics to MQTT on an interval.
+type Publisher struct {
+	opts     Options
+	gatherer prometheus.Gatherer
+	client   paho.Client
+	// connected is signalled on every (re)connect so Run publishes right
+	// away instead of waiting for the next tick.
+	connected chan struct{}
+
+	mu sync.Mutex
+	// announced holds the discovery payload last published per topic;
+	// it is cleared on reconnect so the broker gets them again.
+	announced map[string]string
+	// password is sent on every (re)connect; SetPassword replaces it.
+	password string
+}
+
+// New returns a Publisher that gathers from g. It does not connect until
+// Run is called.
+func New(g prometheus.Gatherer, opts Options) *Publisher {
+	if opts.NodeID == "" {
+		opts.NodeID = objectID(opts.TopicPrefix)
+	}
+	p := &Publisher{
+		opts:      opts,
+		gatherer:  g,
+		connected: make(chan struct{}, 1),
+		announced: map[string]string{},
+		password:  opts.Password,
+	}
+	co := paho.NewClientOptions().
+		AddBroker(opts.Broker).
+		SetClientID(opts.ClientID).
+		SetCredentialsProvider(p.credentials).
+		SetWill(p.statusTopic(), payloadOffline, 1, true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(connectRetryInterval).
+		SetMaxReconnectInterval(maxReconnectInterval).
+		SetOrderMatters(false).
+		SetOnConnectHandler(p.onConnect).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			log.Printf("warning: MQTT connection lost: %v", err)
+		})
+	p.client = paho.NewClient(co)
+	return p
+}
+
+// SetPassword replaces the password used from the next connection on, for
+// example after mqtt.password_file was rotated. The open connection is kept.
+// It reports whether the password changed.
+func (p *Publisher) SetPassword(password string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.password == password {
+		return false
+	}
+	p.password = password
+	return true
+}
+
+// credentials returns the username and password of the next connection.
+func (p *Publisher) credentials() (string, string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.opts.Username, p.password
+}
+
+// Run connects to the broker and publishes every Interval until ctx is
+// done. Connection failures are retried in the background. On return it
+// marks the exporter offline and disconnects.
+func (p *Publisher) Run(ctx context.Context) error {
+	if p.opts.Interval <= 0 {
+		return fmt.Errorf("mqtt: interval must be positive, got %s", p.opts.Interval)
+	}
+	p.client.Connect()
+	defer p.client.Disconnect(250)
+
+	t := time.NewTicker(p.opts.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if p.client.IsConnectionOpen() {
+				p.client.Publish(p.statusTopic(), 1, true, payloadOffline).WaitTimeout(time.Second)
+			}
+			return nil
+		case <-p.connected:
+		case <-t.C:
+		}
+		if err := p.Publish(); err != nil {
+			log.Printf("ERROR: MQTT publish failed: %v", err)
+		}
+	}
+}
+
+// onConnect marks the exporter online and schedules a publish. Discovery
+// messages are sent again after every reconnect.
+func (p *Publisher) onConnect(c paho.Client) {
+	log.Printf("connected to MQTT broker %s", p.opts.Broker)
+	c.Publish(p.statusTopic(), 1, true, payloadOnline)
+	p.mu.Lock()
+	p.announced = map[string]string{}
+	p.mu.Unlock()
+	select {
+	case p.connected <- struct{}{}:
+	default:
+	}
+}
+
+// Publish gathers once and publishes the discovery and state messages.
+func (p *Publisher) Publish() error {
+	if !p.client.IsConnectionOpen() {
+		return errNotConnected
+	}
+	families, err := p.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return err
+	}
+	snap := NewSnapshot(families)
+
+	wanTopic := p.opts.TopicPrefix + "/wan/state"
+	deviceTopic := p.opts.TopicPrefix + "/device/state"
+	type state struct {
+		topic string
+		value any
+	}
+	entities := wanEntities(wanTopic)
+	states := []state{{wanTopic, snap.WAN}}
+	for _, name := range snap.InterfaceNames() {
+		topic := p.opts.TopicPrefix + "/interface/" + name + "/state"
+		entities = append(entities, interfaceEntities(name, topic)...)
+		states = append(states, state{topic, snap.Interfaces[name]})
+	}
+	if snap.Device != nil {
+		entities = append(entities, deviceEntities(deviceTopic)...)
+		states = append(states, state{deviceTopic, snap.Device})
+	}
+
+	var errs []error
+	if p.opts.DiscoveryPrefix != "" {
+		errs = append(errs, p.announce(entities, snap.Device))
+	}
+	for _, st := range states {
+		payload, err := json.Marshal(st.value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, p.publish(st.topic, p.opts.Retain, payload))
+	}
+	return errors.Join(errs...)
+}
+
+// announce publishes the retained discovery config of every entity whose
+// payload changed since it was last sent.
+func (p *Publisher) announce(entities []entity, dev *DeviceState) error {
+	device := discoveryDevice{Identifiers: []string{p.opts.NodeID}, Name: "Experia Box v10"}
+	if dev != nil {
+		device.Manufacturer = dev.Manufacturer
+		device.Model = dev.Model
+		device.SWVersion = dev.SoftwareVersion
+		device.HWVersion = dev.HardwareVersion
+	}
+	var errs []error
+	for _, e := range entities {
+		cfg := discoveryConfig{
+			Name:                e.Name,
+			UniqueID:            p.opts.NodeID + "_" + e.ObjectID,
+			StateTopic:          e.StateTopic,
+			ValueTemplate:       e.Template,
+			AvailabilityTopic:   p.statusTopic(),
+			PayloadAvailable:    payloadOnline,
+			PayloadNotAvailable: payloadOffline,
+			DeviceClass:         e.DeviceClass,
+			UnitOfMeasurement:   e.Unit,
+			StateClass:          e.StateClass,
+			EntityCategory:      e.Category,
+			Device:              device,
+		}
+		if e.Component == "binary_sensor" {
+			cfg.PayloadOn, cfg.PayloadOff = "ON", "OFF"
+		}
+		payload, err := json.Marshal(cfg)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		topic := fmt.Sprintf("%s/%s/%s/%s/config", p.opts.DiscoveryPrefix, e.Component, p.opts.NodeID, e.ObjectID)
+		p.mu.Lock()
+		same := p.announced[topic] == string(payload)
+		p.mu.Unlock()
+		if same {
+			continue
+		}
+		if err := p.publish(topic, true, payload); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		p.mu.Lock()
+		p.announced[topic] = string(payload)
+		p.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// publish sends payload to topic and waits for the broker to accept it.
+func (p *Publisher) publish(topic string, retain bool, payload []byte) error {
+	tok := p.client.Publish(topic, p.opts.QoS, retain, payload)
+	if !tok.WaitTimeout(publishTimeout) {
+		return fmt.Errorf("publish to %s timed out", topic)
+	}
+	if err := tok.Error(); err != nil {
+		return fmt.Errorf("publish to %s: %w", topic, err)
+	}
+	return nil
+}
+
+func (p *Publisher) statusTopic() string {
+	return p.opts.TopicPrefix + "/status"
+}
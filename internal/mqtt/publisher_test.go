@@ -0,0 +1,317 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	firmwaremetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/firmware"
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// testBroker is a minimal in-process MQTT 3.1.1 broker that acknowledges
+// connections and publishes and records what clients send.
+type testBroker struct {
+	l net.Listener
+
+	mu       sync.Mutex
+	connects []*packets.ConnectPacket
+	messages []*packets.PublishPacket
+	conns    []net.Conn
+}
+
+func newTestBroker(t *testing.T) *testBroker {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &testBroker{l: l}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			b.mu.Lock()
+			b.conns = append(b.conns, c)
+			b.mu.Unlock()
+			go b.serve(c)
+		}
+	}()
+	t.Cleanup(func() {
+		_ = l.Close()
+		b.dropConnections()
+	})
+	return b
+}
+
+func (b *testBroker) url() string { return "tcp://" + b.l.Addr().String() }
+
+func (b *testBroker) serve(c net.Conn) {
+	defer func() { _ = c.Close() }()
+	pkt, err := packets.ReadPacket(c)
+	if err != nil {
+		return
+	}
+	connect, ok := pkt.(*packets.ConnectPacket)
+	if !ok {
+		return
+	}
+	b.mu.Lock()
+	b.connects = append(b.connects, connect)
+	b.mu.Unlock()
+	if err := packets.NewControlPacket(packets.Connack).Write(c); err != nil {
+		return
+	}
+	for {
+		pkt, err := packets.ReadPacket(c)
+		if err != nil {
+			return
+		}
+		switch p := pkt.(type) {
+		case *packets.PublishPacket:
+			b.mu.Lock()
+			b.messages = append(b.messages, p)
+			b.mu.Unlock()
+			if p.Qos == 1 {
+				ack := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				ack.MessageID = p.MessageID
+				if err := ack.Write(c); err != nil {
+					return
+				}
+			}
+		case *packets.PingreqPacket:
+			if err := packets.NewControlPacket(packets.Pingresp).Write(c); err != nil {
+				return
+			}
+		case *packets.DisconnectPacket:
+			return
+		}
+	}
+}
+
+// dropConnections closes every client connection, like a broker restart.
+func (b *testBroker) dropConnections() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range b.conns {
+		_ = c.Close()
+	}
+	b.conns = nil
+}
+
+// published returns the messages received on topic in order.
+func (b *testBroker) published(topic string) []*packets.PublishPacket {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var out []*packets.PublishPacket
+	for _, m := range b.messages {
+		if m.TopicName == topic {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func (b *testBroker) connectCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.connects)
+}
+
+// waitFor polls cond until it holds or fails the test after five seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// routerCollector emits the families the publisher reads, using the
+// collector's descriptors.
+type routerCollector struct{ device bool }
+
+func (routerCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (r routerCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(metrics.IfupTime, prometheus.GaugeValue, 1, "ethernet", "dhcp", "Connected", "203.0.113.7", "aa:bb:cc:dd:ee:ff")
+	ch <- prometheus.MustNewConstMetric(metrics.WanIfname, prometheus.GaugeValue, 1, "eth4")
+	ch <- prometheus.MustNewConstMetric(metrics.WanRxBytes, prometheus.CounterValue, 1000, "eth4")
+	ch <- prometheus.MustNewConstMetric(metrics.WanTxBytes, prometheus.CounterValue, 500, "eth4")
+	ch <- prometheus.MustNewConstMetric(metrics.NetdevUp, prometheus.GaugeValue, 1, "eth1")
+	ch <- prometheus.MustNewConstMetric(metrics.NetdevRxBytes, prometheus.CounterValue, 42, "eth1")
+	ch <- prometheus.MustNewConstMetric(metrics.NetdevTxErrors, prometheus.CounterValue, 3, "eth1")
+	ch <- prometheus.MustNewConstMetric(metrics.NetdevUp, prometheus.GaugeValue, 0, "eth2")
+	if r.device {
+		ch <- prometheus.MustNewConstMetric(firmwaremetrics.DeviceInfo, prometheus.GaugeValue, 1, "Sagemcom", "Experia Box v10", "SN123", "1.0", "V10.C.24.04")
+		ch <- prometheus.MustNewConstMetric(firmwaremetrics.DeviceUptime, prometheus.GaugeValue, 3600)
+	}
+}
+
+func newRegistry(t *testing.T, device bool) *prometheus.Registry {
+	t.Helper()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(routerCollector{device: device})
+	return reg
+}
+
+func TestNewSnapshot(t *testing.T) {
+	families, err := newRegistry(t, true).Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSnapshot(families)
+	want := WANState{Connected: true, ConnectionState: "Connected", IP: "203.0.113.7", LinkType: "ethernet", Protocol: "dhcp", Interface: "eth4", RxBytes: 1000, TxBytes: 500}
+	if s.WAN != want {
+		t.Fatalf("unexpected WAN state %+v", s.WAN)
+	}
+	if names := s.InterfaceNames(); len(names) != 2 || names[0] != "eth1" {
+		t.Fatalf("unexpected interfaces %v", names)
+	}
+	if eth1 := s.Interfaces["eth1"]; !eth1.Up || eth1.RxBytes != 42 || eth1.TxErrors != 3 {
+		t.Fatalf("unexpected eth1 state %+v", eth1)
+	}
+	if s.Interfaces["eth2"].Up {
+		t.Fatalf("expected eth2 to be down")
+	}
+	if s.Device == nil || s.Device.Serial != "SN123" || s.Device.UptimeSeconds != 3600 {
+		t.Fatalf("unexpected device state %+v", s.Device)
+	}
+
+	families, _ = newRegistry(t, false).Gather()
+	if NewSnapshot(families).Device != nil {
+		t.Fatalf("expected no device state without device_info")
+	}
+}
+
+func newTestPublisher(b *testBroker, reg prometheus.Gatherer) *Publisher {
+	return New(reg, Options{
+		Broker:          b.url(),
+		ClientID:        "test",
+		TopicPrefix:     "experia_v10",
+		DiscoveryPrefix: "homeassistant",
+		Interval:        time.Hour,
+		QoS:             1,
+		Username:        "user",
+		Password:        "secret",
+	})
+}
+
+func TestPublisher_DiscoveryAndState(t *testing.T) {
+	b := newTestBroker(t)
+	p := newTestPublisher(b, newRegistry(t, true))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- p.Run(ctx) }()
+
+	waitFor(t, "the device state", func() bool { return len(b.published("experia_v10/device/state")) > 0 })
+
+	b.mu.Lock()
+	connect := b.connects[0]
+	b.mu.Unlock()
+	if !connect.WillFlag || connect.WillTopic != "experia_v10/status" || string(connect.WillMessage) != "offline" || !connect.WillRetain {
+		t.Fatalf("expected a retained offline last will, got %+v", connect)
+	}
+	if st := b.published("experia_v10/status"); len(st) == 0 || string(st[0].Payload) != "online" || !st[0].Retain {
+		t.Fatalf("expected a retained online status, got %v", st)
+	}
+
+	var wan WANState
+	if err := json.Unmarshal(b.published("experia_v10/wan/state")[0].Payload, &wan); err != nil || !wan.Connected || wan.IP != "203.0.113.7" {
+		t.Fatalf("unexpected WAN state %+v (%v)", wan, err)
+	}
+	var eth1 InterfaceState
+	if err := json.Unmarshal(b.published("experia_v10/interface/eth1/state")[0].Payload, &eth1); err != nil || eth1.RxBytes != 42 {
+		t.Fatalf("unexpected eth1 state %+v (%v)", eth1, err)
+	}
+
+	msgs := b.published("homeassistant/binary_sensor/experia_v10/wan_connected/config")
+	if len(msgs) != 1 || !msgs[0].Retain {
+		t.Fatalf("expected one retained discovery message, got %d", len(msgs))
+	}
+	var cfg discoveryConfig
+	if err := json.Unmarshal(msgs[0].Payload, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.UniqueID != "experia_v10_wan_connected" || cfg.StateTopic != "experia_v10/wan/state" || cfg.AvailabilityTopic != "experia_v10/status" || cfg.PayloadOn != "ON" {
+		t.Fatalf("unexpected discovery config %+v", cfg)
+	}
+	if cfg.Device.Model != "Experia Box v10" || cfg.Device.SWVersion != "V10.C.24.04" || cfg.Device.Identifiers[0] != "experia_v10" {
+		t.Fatalf("expected the device info in the discovery config, got %+v", cfg.Device)
+	}
+	if len(b.published("homeassistant/sensor/experia_v10/eth1_rx_bytes/config")) != 1 {
+		t.Fatalf("expected discovery for the eth1 counters")
+	}
+
+	// Unchanged discovery configs are not sent again.
+	if err := p.Publish(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := len(b.published("homeassistant/binary_sensor/experia_v10/wan_connected/config")); n != 1 {
+		t.Fatalf("expected unchanged discovery to be skipped, got %d messages", n)
+	}
+	if n := len(b.published("experia_v10/wan/state")); n != 2 {
+		t.Fatalf("expected a second state message, got %d", n)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	st := b.published("experia_v10/status")
+	if last := st[len(st)-1]; string(last.Payload) != "offline" || !last.Retain {
+		t.Fatalf("expected a retained offline status on shutdown, got %q", last.Payload)
+	}
+}
+
+func TestPublisher_Reconnect(t *testing.T) {
+	b := newTestBroker(t)
+	p := newTestPublisher(b, newRegistry(t, false))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = p.Run(ctx) }()
+
+	topic := "homeassistant/binary_sensor/experia_v10/wan_connected/config"
+	waitFor(t, "the first discovery", func() bool { return len(b.published(topic)) == 1 })
+	// A rotated password is used from the next connection on.
+	if !p.SetPassword("rotated") || p.SetPassword("rotated") {
+		t.Fatalf("expected SetPassword to report only a changed password")
+	}
+	b.dropConnections()
+	waitFor(t, "a reconnect", func() bool { return b.connectCount() == 2 })
+	b.mu.Lock()
+	first, second := b.connects[0], b.connects[1]
+	b.mu.Unlock()
+	if string(first.Password) != "secret" || string(second.Password) != "rotated" || second.Username != "user" {
+		t.Fatalf("expected the rotated password on reconnect, got %q then %q", first.Password, second.Password)
+	}
+	// After reconnecting the exporter is online again and discovery and
+	// state are published without waiting for the interval.
+	waitFor(t, "discovery after the reconnect", func() bool { return len(b.published(topic)) == 2 })
+	waitFor(t, "state after the reconnect", func() bool { return len(b.published("experia_v10/wan/state")) == 2 })
+	if st := b.published("experia_v10/status"); len(st) != 2 || string(st[1].Payload) != "online" {
+		t.Fatalf("expected online after the reconnect, got %d status messages", len(st))
+	}
+}
+
+func TestPublisher_NotConnected(t *testing.T) {
+	p := New(newRegistry(t, false), Options{Broker: "tcp://127.0.0.1:1", TopicPrefix: "experia_v10", Interval: time.Minute})
+	if err := p.Publish(); !errors.Is(err, errNotConnected) {
+		t.Fatalf("expected errNotConnected, got %v", err)
+	}
+	if err := New(nil, Options{}).Run(context.Background()); err == nil || !strings.Contains(err.Error(), "interval") {
+		t.Fatalf("expected an interval error, got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package mqtt
+
+import (
+	"sort"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+const prefix = "experia_v10_"
+
+// WANState is published on <topic_prefix>/wan/state.
+type WANState struct {
+	Connected       bool    `json:"connected"`
+	ConnectionState string  `json:"connection_state"`
+	IP              string  `json:"ip"`
+	LinkType        string  `json:"link_type"`
+	Protocol        string  `json:"protocol"`
+	Interface       string  `json:"interface"`
+	RxBytes         float64 `json:"rx_bytes"`
+	TxBytes         float64 `json:"tx_bytes"`
+}
+
+// InterfaceState is published on <topic_prefix>/interface/<ifname>/state.
+type InterfaceState struct {
+	Up        bool    `json:"up"`
+	RxBytes   float64 `json:"rx_bytes"`
+	TxBytes   float64 `json:"tx_bytes"`
+	RxPackets float64 `json:"rx_packets"`
+	TxPackets float64 `json:"tx_packets"`
+	RxErrors  float64 `json:"rx_errors"`
+	TxErrors  float64 `json:"tx_errors"`
+}
+
+// DeviceState is published on <topic_prefix>/device/state. It is only
+// available with the firmware module enabled.
+type DeviceState struct {
+	Manufacturer    string  `json:"manufacturer"`
+	Model           string  `json:"model"`
+	Serial          string  `json:"serial"`
+	HardwareVersion string  `json:"hardware_version"`
+	SoftwareVersion string  `json:"software_version"`
+	UptimeSeconds   float64 `json:"uptime_seconds"`
+}
+
+// Snapshot is the state extracted from one Gather.
+type Snapshot struct {
+	WAN        WANState
+	Interfaces map[string]InterfaceState
+	// Device is nil when device_info was not collected.
+	Device *DeviceState
+}
+
+// InterfaceNames returns the interface names in s sorted.
+func (s Snapshot) InterfaceNames() []string {
+	names := make([]string, 0, len(s.Interfaces))
+	for name := range s.Interfaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewSnapshot extracts the WAN status, per-interface counters and device
+// info from gathered metric families.
+func NewSnapshot(families []*dto.MetricFamily) Snapshot {
+	s := Snapshot{Interfaces: map[string]InterfaceState{}}
+	byName := map[string]*dto.MetricFamily{}
+	for _, f := range families {
+		byName[f.GetName()] = f
+	}
+	each := func(name string, fn func(labels map[string]string, v float64)) {
+		f := byName[prefix+name]
+		if f == nil {
+			return
+		}
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			fn(labels, value(m))
+		}
+	}
+
+	each("internet_connection", func(l map[string]string, v float64) {
+		s.WAN.Connected = v == 1
+		s.WAN.ConnectionState = l["connection_state"]
+		s.WAN.IP = l["ip"]
+		s.WAN.LinkType = l["link_type"]
+		s.WAN.Protocol = l["protocol"]
+	})
+	each("wan_ifname", func(l map[string]string, v float64) {
+		if v == 1 {
+			s.WAN.Interface = l["ifname"]
+		}
+	})
+	each("wan_rx_bytes_total", func(_ map[string]string, v float64) { s.WAN.RxBytes = v })
+	each("wan_tx_bytes_total", func(_ map[string]string, v float64) { s.WAN.TxBytes = v })
+
+	iface := func(name string, set func(*InterfaceState, float64)) {
+		each(name, func(l map[string]string, v float64) {
+			ifname := l["ifname"]
+			if ifname == "" {
+				return
+			}
+			st := s.Interfaces[ifname]
+			set(&st, v)
+			s.Interfaces[ifname] = st
+		})
+	}
+	iface("netdev_up", func(st *InterfaceState, v float64) { st.Up = v == 1 })
+	iface("netdev_rx_bytes_total", func(st *InterfaceState, v float64) { st.RxBytes = v })
+	iface("netdev_tx_bytes_total", func(st *InterfaceState, v float64) { st.TxBytes = v })
+	iface("netdev_rx_packets_total", func(st *InterfaceState, v float64) { st.RxPackets = v })
+	iface("netdev_tx_packets_total", func(st *InterfaceState, v float64) { st.TxPackets = v })
+	iface("netdev_rx_errors_total", func(st *InterfaceState, v float64) { st.RxErrors = v })
+	iface("netdev_tx_errors_total", func(st *InterfaceState, v float64) { st.TxErrors = v })
+
+	each("device_info", func(l map[string]string, _ float64) {
+		s.Device = &DeviceState{
+			Manufacturer:    l["manufacturer"],
+			Model:           l["model"],
+			Serial:          l["serial"],
+			HardwareVersion: l["hardware_version"],
+			SoftwareVersion: l["software_version"],
+		}
+	})
+	each("device_uptime_seconds", func(_ map[string]string, v float64) {
+		if s.Device != nil {
+			s.Device.UptimeSeconds = v
+		}
+	})
+	return s
+}
+
+// value returns the sample value of a gauge, counter or untyped metric.
+func value(m *dto.Metric) float64 {
+	switch {
+	case m.Gauge != nil:
+		return m.GetGauge().GetValue()
+	case m.Counter != nil:
+		return m.GetCounter().GetValue()
+	case m.Untyped != nil:
+		return m.GetUntyped().GetValue()
+	}
+	return 0
+}
@@ -0,0 +1,133 @@
+// Package otlp pushes the collector's metrics to an OpenTelemetry collector
+// over OTLP/HTTP or OTLP/gRPC.
+//
+// Counters become cumulative monotonic sums and every other value (status,
+// info and gauge metrics) becomes a gauge; metric names and labels are kept
+// as they appear on /metrics. The resource describes the router with its
+// serial number, model and manufacturer, read from the router independently
+// of the enabled modules.
+package otlp
+
+import (
+	"sort"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+const (
+	serviceName = "experia-v10-exporter"
+	scopeName   = "github.com/GrammaTonic/experia-v10-exporter"
+
+	deviceInfoFamily = "experia_v10_device_info"
+)
+
+// resourceLabels maps device_info labels to resource attributes.
+var resourceLabels = map[string]string{
+	"serial":       "device.id",
+	"model":        "device.model.name",
+	"manufacturer": "device.manufacturer",
+}
+
+// Device identifies the router in the resource.
+type Device struct {
+	// ID is the serial number.
+	ID           string
+	Model        string
+	Manufacturer string
+}
+
+// NewRequest converts families to an export request for device. start is
+// the start time of the cumulative sums and now the time of every data
+// point.
+func NewRequest(families []*dto.MetricFamily, device Device, start, now time.Time) *colmetricspb.ExportMetricsServiceRequest {
+	startNano, nowNano := uint64(start.UnixNano()), uint64(now.UnixNano())
+	var out []*metricspb.Metric
+	for _, f := range families {
+		m := &metricspb.Metric{Name: f.GetName(), Description: f.GetHelp()}
+		var points []*metricspb.NumberDataPoint
+		for _, s := range f.GetMetric() {
+			p := &metricspb.NumberDataPoint{Attributes: attributes(s.GetLabel()), TimeUnixNano: nowNano}
+			switch f.GetType() {
+			case dto.MetricType_COUNTER:
+				p.StartTimeUnixNano = startNano
+				p.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: s.GetCounter().GetValue()}
+			case dto.MetricType_GAUGE:
+				p.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: s.GetGauge().GetValue()}
+			case dto.MetricType_UNTYPED:
+				p.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: s.GetUntyped().GetValue()}
+			default:
+				// The collector emits no histograms or summaries.
+				continue
+			}
+			points = append(points, p)
+		}
+		if len(points) == 0 {
+			continue
+		}
+		if f.GetType() == dto.MetricType_COUNTER {
+			m.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+				DataPoints:             points,
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				IsMonotonic:            true,
+			}}
+		} else {
+			m.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: points}}
+		}
+		out = append(out, m)
+	}
+	return &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: resource(families, device),
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Scope:   &commonpb.InstrumentationScope{Name: scopeName},
+				Metrics: out,
+			}},
+		}},
+	}
+}
+
+// resource returns the service attributes and the router's identity from
+// device, completed from device_info when the firmware module is enabled.
+func resource(families []*dto.MetricFamily, device Device) *resourcepb.Resource {
+	values := map[string]string{
+		"service.name":        serviceName,
+		"device.id":           device.ID,
+		"device.model.name":   device.Model,
+		"device.manufacturer": device.Manufacturer,
+	}
+	for _, f := range families {
+		if f.GetName() != deviceInfoFamily || len(f.GetMetric()) == 0 {
+			continue
+		}
+		for _, l := range f.GetMetric()[0].GetLabel() {
+			if key, ok := resourceLabels[l.GetName()]; ok && values[key] == "" {
+				values[key] = l.GetValue()
+			}
+		}
+	}
+	attrs := make([]*commonpb.KeyValue, 0, len(values))
+	for key, value := range values {
+		if value != "" {
+			attrs = append(attrs, stringAttr(key, value))
+		}
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	return &resourcepb.Resource{Attributes: attrs}
+}
+
+func attributes(labels []*dto.LabelPair) []*commonpb.KeyValue {
+	attrs := make([]*commonpb.KeyValue, 0, len(labels))
+	for _, l := range labels {
+		attrs = append(attrs, stringAttr(l.GetName(), l.GetValue()))
+	}
+	return attrs
+}
+
+func stringAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+}
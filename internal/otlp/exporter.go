@@ -0,0 +1,199 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// Protocols supported by Exporter, named as in OTEL_EXPORTER_OTLP_PROTOCOL.
+const (
+	ProtocolHTTP = "http/protobuf"
+	ProtocolGRPC = "grpc"
+)
+
+// exportTimeout bounds one export.
+const exportTimeout = 10 * time.Second
+
+// Options configures an Exporter.
+type Options struct {
+	// Endpoint is the receiver URL, for example http://otel-collector:4318
+	// for OTLP/HTTP or http://otel-collector:4317 for gRPC. An OTLP/HTTP
+	// endpoint without a path gets /v1/metrics; https enables TLS.
+	Endpoint string
+	Protocol string
+	// Headers are sent with every export, for example an API key.
+	Headers  map[string]string
+	Interval time.Duration
+	// Device looks up the router's identity for the resource. It is called
+	// before every export until it succeeds; nil leaves the resource to
+	// device_info.
+	Device func(context.Context) (Device, error)
+}
+
+// Exporter pushes gathered metrics to an OTLP receiver on an interval.
+type Exporter struct {
+	opts     Options
+	gatherer prometheus.Gatherer
+	// start is the start time of the cumulative sums.
+	start time.Time
+	// device is the router's identity once Options.Device succeeded.
+	device     Device
+	haveDevice bool
+
+	client *http.Client
+	url    string
+
+	conn *grpc.ClientConn
+	grpc colmetricspb.MetricsServiceClient
+}
+
+// New returns an Exporter that gathers from g. A gRPC connection is
+// established lazily on the first export.
+func New(g prometheus.Gatherer, opts Options) (*Exporter, error) {
+	u, err := url.Parse(opts.Endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("otlp: %q is not an http or https URL", opts.Endpoint)
+	}
+	e := &Exporter{opts: opts, gatherer: g, start: time.Now()}
+	switch opts.Protocol {
+	case ProtocolHTTP, "":
+		if u.Path == "" || u.Path == "/" {
+			u.Path = "/v1/metrics"
+		}
+		e.url = u.String()
+		e.client = &http.Client{Timeout: exportTimeout}
+	case ProtocolGRPC:
+		creds := insecure.NewCredentials()
+		if u.Scheme == "https" {
+			creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+		}
+		if e.conn, err = grpc.NewClient(u.Host, grpc.WithTransportCredentials(creds)); err != nil {
+			return nil, fmt.Errorf("otlp: %w", err)
+		}
+		e.grpc = colmetricspb.NewMetricsServiceClient(e.conn)
+	default:
+		return nil, fmt.Errorf("otlp: unsupported protocol %q", opts.Protocol)
+	}
+	return e, nil
+}
+
+// Run exports right away and then every Interval until ctx is done. Failed
+// exports are logged and retried on the next interval.
+func (e *Exporter) Run(ctx context.Context) error {
+	if e.opts.Interval <= 0 {
+		return fmt.Errorf("otlp: interval must be positive, got %s", e.opts.Interval)
+	}
+	if e.conn != nil {
+		defer e.conn.Close()
+	}
+	t := time.NewTicker(e.opts.Interval)
+	defer t.Stop()
+	for {
+		if err := e.Export(ctx); err != nil {
+			log.Printf("ERROR: OTLP export failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Export gathers once and sends the metrics. It is not safe for concurrent
+// use.
+func (e *Exporter) Export(ctx context.Context) error {
+	if !e.haveDevice && e.opts.Device != nil {
+		if d, err := e.opts.Device(ctx); err != nil {
+			log.Printf("warning: OTLP resource: failed to read the device info, retrying on the next export: %v", err)
+		} else {
+			e.device, e.haveDevice = d, true
+		}
+	}
+	families, err := e.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return err
+	}
+	req := NewRequest(families, e.device, e.start, time.Now())
+	ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+	defer cancel()
+	var resp *colmetricspb.ExportMetricsServiceResponse
+	if e.grpc != nil {
+		resp, err = e.exportGRPC(ctx, req)
+	} else {
+		resp, err = e.exportHTTP(ctx, req)
+	}
+	if err != nil {
+		return err
+	}
+	if ps := resp.GetPartialSuccess(); ps.GetRejectedDataPoints() > 0 {
+		return fmt.Errorf("receiver rejected %d data points: %s", ps.GetRejectedDataPoints(), ps.GetErrorMessage())
+	}
+	return nil
+}
+
+func (e *Exporter) exportGRPC(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	if len(e.opts.Headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(e.opts.Headers))
+	}
+	return e.grpc.Export(ctx, req)
+}
+
+func (e *Exporter) exportHTTP(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range e.opts.Headers {
+		r.Header.Set(k, v)
+	}
+	r.Header.Set("Content-Type", "application/x-protobuf")
+	resp, err := e.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		// Receivers answer with a google.rpc.Status; fall back to the raw
+		// body for proxies in front of them.
+		var st statuspb.Status
+		msg := strings.TrimSpace(string(data))
+		if proto.Unmarshal(data, &st) == nil && st.GetMessage() != "" {
+			msg = st.GetMessage()
+		}
+		if len(msg) > 512 {
+			msg = msg[:512]
+		}
+		return nil, fmt.Errorf("export returned %s: %s", resp.Status, msg)
+	}
+	out := &colmetricspb.ExportMetricsServiceResponse{}
+	if err := proto.Unmarshal(data, out); err != nil {
+		return nil, fmt.Errorf("decoding the export response: %w", err)
+	}
+	return out, nil
+}
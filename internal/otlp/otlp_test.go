@@ -0,0 +1,284 @@
+package otlp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	firmwaremetrics "github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics/firmware"
+	"github.com/prometheus/client_golang/prometheus"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	statuspb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// routerCollector emits a counter, status gauges and device_info using the
+// collector's descriptors.
+type routerCollector struct{}
+
+func (routerCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (routerCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(metrics.IfupTime, prometheus.GaugeValue, 1, "ethernet", "dhcp", "Connected", "203.0.113.7", "aa:bb:cc:dd:ee:ff")
+	ch <- prometheus.MustNewConstMetric(metrics.WanRxBytes, prometheus.CounterValue, 1000, "eth4")
+	ch <- prometheus.MustNewConstMetric(metrics.NetdevUp, prometheus.GaugeValue, 1, "eth1")
+	ch <- prometheus.MustNewConstMetric(firmwaremetrics.DeviceInfo, prometheus.GaugeValue, 1, "Sagemcom", "Experia Box v10", "SN123", "1.0", "V10.C.24.04")
+}
+
+func newRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(routerCollector{})
+	return reg
+}
+
+// metricsByName indexes the metrics of a single-resource request.
+func metricsByName(t *testing.T, req *colmetricspb.ExportMetricsServiceRequest) map[string]*metricspb.Metric {
+	t.Helper()
+	if len(req.GetResourceMetrics()) != 1 || len(req.GetResourceMetrics()[0].GetScopeMetrics()) != 1 {
+		t.Fatalf("expected one resource and scope, got %v", req)
+	}
+	out := map[string]*metricspb.Metric{}
+	for _, m := range req.GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics() {
+		out[m.GetName()] = m
+	}
+	return out
+}
+
+func resourceAttrs(req *colmetricspb.ExportMetricsServiceRequest) map[string]string {
+	out := map[string]string{}
+	for _, kv := range req.GetResourceMetrics()[0].GetResource().GetAttributes() {
+		out[kv.GetKey()] = kv.GetValue().GetStringValue()
+	}
+	return out
+}
+
+func TestNewRequest(t *testing.T) {
+	families, err := newRegistry().Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, now := time.Unix(1700000000, 0), time.Unix(1700000060, 0)
+	req := NewRequest(families, Device{}, start, now)
+
+	attrs := resourceAttrs(req)
+	if attrs["service.name"] != "experia-v10-exporter" || attrs["device.id"] != "SN123" || attrs["device.model.name"] != "Experia Box v10" || attrs["device.manufacturer"] != "Sagemcom" {
+		t.Fatalf("unexpected resource attributes %v", attrs)
+	}
+
+	byName := metricsByName(t, req)
+	sum := byName["experia_v10_wan_rx_bytes_total"].GetSum()
+	if sum == nil || !sum.GetIsMonotonic() || sum.GetAggregationTemporality() != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+		t.Fatalf("expected a cumulative monotonic sum, got %v", byName["experia_v10_wan_rx_bytes_total"])
+	}
+	p := sum.GetDataPoints()[0]
+	if p.GetAsDouble() != 1000 || p.GetStartTimeUnixNano() != uint64(start.UnixNano()) || p.GetTimeUnixNano() != uint64(now.UnixNano()) {
+		t.Fatalf("unexpected sum data point %v", p)
+	}
+	if kv := p.GetAttributes()[0]; kv.GetKey() != "ifname" || kv.GetValue().GetStringValue() != "eth4" {
+		t.Fatalf("expected the ifname attribute, got %v", kv)
+	}
+
+	up := byName["experia_v10_netdev_up"].GetGauge()
+	if up == nil || up.GetDataPoints()[0].GetAsDouble() != 1 || up.GetDataPoints()[0].GetStartTimeUnixNano() != 0 {
+		t.Fatalf("expected a gauge, got %v", byName["experia_v10_netdev_up"])
+	}
+	if byName["experia_v10_internet_connection"].GetGauge() == nil {
+		t.Fatalf("expected internet_connection as a gauge")
+	}
+
+	// Without device_info the resource only names the service.
+	if attrs := resourceAttrs(NewRequest(nil, Device{}, start, now)); len(attrs) != 1 {
+		t.Fatalf("unexpected resource attributes %v", attrs)
+	}
+	// The device read from the router identifies it without the firmware
+	// module.
+	attrs = resourceAttrs(NewRequest(nil, Device{ID: "SN9", Model: "Experia Box v10"}, start, now))
+	if len(attrs) != 3 || attrs["device.id"] != "SN9" || attrs["device.model.name"] != "Experia Box v10" {
+		t.Fatalf("unexpected resource attributes %v", attrs)
+	}
+}
+
+// receiver is an in-process OTLP receiver stub for both transports.
+type receiver struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+
+	mu       sync.Mutex
+	requests []*colmetricspb.ExportMetricsServiceRequest
+	headers  []string
+}
+
+func (r *receiver) record(req *colmetricspb.ExportMetricsServiceRequest, header string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.headers = append(r.headers, header)
+}
+
+func (r *receiver) last() (*colmetricspb.ExportMetricsServiceRequest, string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.requests) == 0 {
+		return nil, ""
+	}
+	return r.requests[len(r.requests)-1], r.headers[len(r.headers)-1]
+}
+
+func (r *receiver) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	r.record(req, strings.Join(md.Get("api-key"), ","))
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/v1/metrics" || req.Header.Get("Content-Type") != "application/x-protobuf" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	body, _ := io.ReadAll(req.Body)
+	in := &colmetricspb.ExportMetricsServiceRequest{}
+	if err := proto.Unmarshal(body, in); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.record(in, req.Header.Get("Api-Key"))
+	resp := &colmetricspb.ExportMetricsServiceResponse{}
+	if len(in.GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()) == 0 {
+		resp.PartialSuccess = &colmetricspb.ExportMetricsPartialSuccess{RejectedDataPoints: 1, ErrorMessage: "no data"}
+	}
+	data, _ := proto.Marshal(resp)
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(data)
+}
+
+func TestExporter_HTTP(t *testing.T) {
+	r := &receiver{}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	e, err := New(newRegistry(), Options{Endpoint: srv.URL, Headers: map[string]string{"Api-Key": "k"}, Interval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- e.Run(ctx) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if req, _ := r.last(); req != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the first export")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req, key := r.last()
+	if key != "k" {
+		t.Fatalf("expected the configured header, got %q", key)
+	}
+	if resourceAttrs(req)["device.id"] != "SN123" || metricsByName(t, req)["experia_v10_wan_rx_bytes_total"].GetSum() == nil {
+		t.Fatalf("unexpected request %v", req)
+	}
+}
+
+func TestExporter_Device(t *testing.T) {
+	r := &receiver{}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	calls := 0
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "experia_v10_scrape_errors_total", Help: "errors"}))
+	e, err := New(reg, Options{Endpoint: srv.URL, Interval: time.Minute, Device: func(context.Context) (Device, error) {
+		calls++
+		if calls == 1 {
+			return Device{}, errors.New("router unreachable")
+		}
+		return Device{ID: "SN9", Model: "Experia Box v10", Manufacturer: "Sagemcom"}, nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := e.Export(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// A failed lookup is retried and a successful one is kept.
+	req, _ := r.last()
+	if calls != 2 || resourceAttrs(req)["device.id"] != "SN9" {
+		t.Fatalf("expected the device from the second lookup, got %v after %d calls", resourceAttrs(req), calls)
+	}
+}
+
+func TestExporter_HTTPErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		data, _ := proto.Marshal(&statuspb.Status{Code: 16, Message: "invalid api key"})
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write(data)
+	}))
+	defer srv.Close()
+	e, err := New(newRegistry(), Options{Endpoint: srv.URL + "/otlp/v1/metrics", Interval: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Export(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid api key") {
+		t.Fatalf("expected the receiver's status message, got %v", err)
+	}
+
+	// A rejected export is an error even with a 200 response.
+	r := &receiver{}
+	ok := httptest.NewServer(r)
+	defer ok.Close()
+	e, _ = New(prometheus.NewRegistry(), Options{Endpoint: ok.URL, Interval: time.Minute})
+	if err := e.Export(context.Background()); err == nil || !strings.Contains(err.Error(), "no data") {
+		t.Fatalf("expected a partial success error, got %v", err)
+	}
+
+	for _, opts := range []Options{{Endpoint: "otel-collector:4318"}, {Endpoint: "http://c:4318", Protocol: "udp"}} {
+		if _, err := New(nil, opts); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+}
+
+func TestExporter_GRPC(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &receiver{}
+	srv := grpc.NewServer()
+	colmetricspb.RegisterMetricsServiceServer(srv, r)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	e, err := New(newRegistry(), Options{Endpoint: "http://" + l.Addr().String(), Protocol: ProtocolGRPC, Headers: map[string]string{"api-key": "k"}, Interval: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.conn.Close()
+	if err := e.Export(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req, key := r.last()
+	if key != "k" || metricsByName(t, req)["experia_v10_netdev_up"].GetGauge() == nil {
+		t.Fatalf("unexpected request %v (api-key %q)", req, key)
+	}
+}
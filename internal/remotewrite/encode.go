@@ -0,0 +1,107 @@
+// Package remotewrite pushes the collector's metrics to a Prometheus
+// remote_write endpoint, for routers that the central Prometheus cannot
+// scrape, for example behind CGNAT.
+//
+// Every interval the metrics are gathered into one snappy-compressed
+// WriteRequest and appended to a bounded queue. A sender drains the queue
+// oldest first and retries failed writes with exponential backoff, so
+// samples collected during an outage are delivered once the endpoint is
+// reachable again. When the queue is full the oldest batch is dropped. With
+// a queue directory the batches also survive a restart.
+package remotewrite
+
+import (
+	"math"
+	"sort"
+
+	"github.com/golang/snappy"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the remote_write 1.0 messages in prompb/types.proto and
+// prompb/remote.proto.
+const (
+	writeRequestTimeseries = 1
+
+	timeSeriesLabels  = 1
+	timeSeriesSamples = 2
+
+	labelName  = 1
+	labelValue = 2
+
+	sampleValue     = 1
+	sampleTimestamp = 2
+)
+
+// label is one label pair of a series.
+type label struct{ name, value string }
+
+// Encode converts families to a snappy-compressed WriteRequest with one
+// sample per series at timestamp ts (in milliseconds). External labels are
+// added to every series that does not already carry the label.
+func Encode(families []*dto.MetricFamily, external map[string]string, ts int64) []byte {
+	var req []byte
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			v, ok := value(f.GetType(), m)
+			if !ok {
+				continue
+			}
+			labels := []label{{"__name__", f.GetName()}}
+			seen := map[string]bool{}
+			for _, l := range m.GetLabel() {
+				labels = append(labels, label{l.GetName(), l.GetValue()})
+				seen[l.GetName()] = true
+			}
+			for k, v := range external {
+				if !seen[k] {
+					labels = append(labels, label{k, v})
+				}
+			}
+			// Receivers require the labels sorted by name.
+			sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+			req = protowire.AppendTag(req, writeRequestTimeseries, protowire.BytesType)
+			req = protowire.AppendBytes(req, timeSeries(labels, v, ts))
+		}
+	}
+	return snappy.Encode(nil, req)
+}
+
+func timeSeries(labels []label, v float64, ts int64) []byte {
+	var b []byte
+	for _, l := range labels {
+		if l.value == "" {
+			// An empty value is the same as no label.
+			continue
+		}
+		var lb []byte
+		lb = protowire.AppendTag(lb, labelName, protowire.BytesType)
+		lb = protowire.AppendString(lb, l.name)
+		lb = protowire.AppendTag(lb, labelValue, protowire.BytesType)
+		lb = protowire.AppendString(lb, l.value)
+		b = protowire.AppendTag(b, timeSeriesLabels, protowire.BytesType)
+		b = protowire.AppendBytes(b, lb)
+	}
+	var sb []byte
+	sb = protowire.AppendTag(sb, sampleValue, protowire.Fixed64Type)
+	sb = protowire.AppendFixed64(sb, math.Float64bits(v))
+	sb = protowire.AppendTag(sb, sampleTimestamp, protowire.VarintType)
+	sb = protowire.AppendVarint(sb, uint64(ts))
+	b = protowire.AppendTag(b, timeSeriesSamples, protowire.BytesType)
+	return protowire.AppendBytes(b, sb)
+}
+
+// value returns the sample value of a gauge, counter or untyped metric. The
+// collector emits no histograms or summaries.
+func value(t dto.MetricType, m *dto.Metric) (float64, bool) {
+	switch t {
+	case dto.MetricType_COUNTER:
+		return m.GetCounter().GetValue(), true
+	case dto.MetricType_GAUGE:
+		return m.GetGauge().GetValue(), true
+	case dto.MetricType_UNTYPED:
+		return m.GetUntyped().GetValue(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,141 @@
+package remotewrite
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// batchSuffix names the batch files in a queue directory.
+const batchSuffix = ".snappy"
+
+// batch is one encoded WriteRequest.
+type batch struct {
+	name string
+	data []byte
+}
+
+// queue is a bounded FIFO of batches. With a directory every batch is also
+// written to a file, so a restart resumes where the previous process
+// stopped.
+type queue struct {
+	dir string
+	max int
+	// notify is signalled when a batch is pushed.
+	notify chan struct{}
+
+	mu      sync.Mutex
+	batches []batch
+	seq     int
+}
+
+// newQueue returns a queue holding at most max batches, loading the batches
+// left in dir by a previous run.
+func newQueue(dir string, max int) (*queue, error) {
+	q := &queue{dir: dir, max: max, notify: make(chan struct{}, 1)}
+	if dir == "" {
+		return q, nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), batchSuffix) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		q.batches = append(q.batches, batch{name: name, data: data})
+	}
+	q.trim()
+	return q, nil
+}
+
+// push appends data, dropping the oldest batch when the queue is full.
+func (q *queue) push(data []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.seq++
+	// The names sort in push order, also across restarts.
+	b := batch{name: fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), q.seq%1000000, batchSuffix), data: data}
+	if q.dir != "" {
+		path := filepath.Join(q.dir, b.name)
+		if err := os.WriteFile(path+".tmp", data, 0o600); err != nil {
+			return err
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return err
+		}
+	}
+	q.batches = append(q.batches, b)
+	q.trim()
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// trim drops the oldest batches beyond max. q.mu must be held or q not yet
+// shared.
+func (q *queue) trim() {
+	if n := len(q.batches) - q.max; n > 0 {
+		log.Printf("warning: remote_write queue full, dropping the %d oldest batches", n)
+		for _, b := range q.batches[:n] {
+			q.remove(b)
+		}
+		q.batches = append([]batch(nil), q.batches[n:]...)
+	}
+}
+
+// peek returns the oldest batch.
+func (q *queue) peek() (batch, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.batches) == 0 {
+		return batch{}, false
+	}
+	return q.batches[0], true
+}
+
+// pop removes b if it is still the oldest batch; it may have been dropped
+// while it was sent.
+func (q *queue) pop(b batch) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.batches) > 0 && q.batches[0].name == b.name {
+		q.remove(b)
+		q.batches = q.batches[1:]
+	}
+}
+
+func (q *queue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.batches)
+}
+
+// remove deletes the file of b.
+func (q *queue) remove(b batch) {
+	if q.dir == "" {
+		return
+	}
+	if err := os.Remove(filepath.Join(q.dir, b.name)); err != nil && !os.IsNotExist(err) {
+		log.Printf("warning: remote_write queue: %v", err)
+	}
+}
@@ -0,0 +1,302 @@
+package remotewrite
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GrammaTonic/experia-v10-exporter/internal/collector/metrics"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// series is a decoded TimeSeries with one sample.
+type series struct {
+	labels    map[string]string
+	names     []string
+	value     float64
+	timestamp int64
+}
+
+// decode parses a snappy-compressed WriteRequest.
+func decode(t *testing.T, data []byte) []series {
+	t.Helper()
+	raw, err := snappy.Decode(nil, data)
+	if err != nil {
+		t.Fatalf("snappy: %v", err)
+	}
+	var out []series
+	fields(t, raw, func(num protowire.Number, b []byte, _ uint64) {
+		if num != writeRequestTimeseries {
+			return
+		}
+		s := series{labels: map[string]string{}}
+		fields(t, b, func(num protowire.Number, b []byte, _ uint64) {
+			switch num {
+			case timeSeriesLabels:
+				var name, value string
+				fields(t, b, func(num protowire.Number, b []byte, _ uint64) {
+					if num == labelName {
+						name = string(b)
+					} else {
+						value = string(b)
+					}
+				})
+				s.labels[name] = value
+				s.names = append(s.names, name)
+			case timeSeriesSamples:
+				fields(t, b, func(num protowire.Number, _ []byte, v uint64) {
+					if num == sampleValue {
+						s.value = math.Float64frombits(v)
+					} else {
+						s.timestamp = int64(v)
+					}
+				})
+			}
+		})
+		out = append(out, s)
+	})
+	return out
+}
+
+// fields calls fn for every field of a message with the bytes of
+// length-delimited fields or the value of numeric ones.
+func fields(t *testing.T, b []byte, fn func(protowire.Number, []byte, uint64)) {
+	t.Helper()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("bad tag")
+		}
+		b = b[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			fn(num, v, 0)
+			b = b[n:]
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			fn(num, nil, v)
+			b = b[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			fn(num, nil, v)
+			b = b[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+	}
+}
+
+type routerCollector struct{}
+
+func (routerCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (routerCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(metrics.WanRxBytes, prometheus.CounterValue, 1000, "eth4")
+	ch <- prometheus.MustNewConstMetric(metrics.WanIfname, prometheus.GaugeValue, 0, "")
+}
+
+func newRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(routerCollector{})
+	return reg
+}
+
+func TestEncode(t *testing.T) {
+	families, err := newRegistry().Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := decode(t, Encode(families, map[string]string{"site": "home", "ifname": "ignored"}, 1700000000000))
+	if len(got) != 2 {
+		t.Fatalf("expected two series, got %d", len(got))
+	}
+	// Gather sorts the families by name.
+	ifname, rx := got[0], got[1]
+	if rx.labels["__name__"] != "experia_v10_wan_rx_bytes_total" || rx.labels["ifname"] != "eth4" || rx.labels["site"] != "home" {
+		t.Fatalf("unexpected labels %v", rx.labels)
+	}
+	if want := []string{"__name__", "ifname", "site"}; len(rx.names) != 3 || rx.names[0] != want[0] || rx.names[1] != want[1] || rx.names[2] != want[2] {
+		t.Fatalf("expected sorted labels, got %v", rx.names)
+	}
+	if rx.value != 1000 || rx.timestamp != 1700000000000 {
+		t.Fatalf("unexpected sample %v@%d", rx.value, rx.timestamp)
+	}
+	// Empty label values are left out and do not make room for an external
+	// label of the same name.
+	if _, ok := ifname.labels["ifname"]; ok || ifname.labels["__name__"] != "experia_v10_wan_ifname" {
+		t.Fatalf("unexpected labels %v", ifname.labels)
+	}
+}
+
+// endpoint is a remote_write receiver stub that fails while down is set.
+type endpoint struct {
+	mu       sync.Mutex
+	down     bool
+	status   int
+	requests []*http.Request
+	batches  [][]series
+	t        *testing.T
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.requests = append(e.requests, r)
+	if e.down {
+		http.Error(w, "unavailable", e.status)
+		return
+	}
+	e.batches = append(e.batches, decode(e.t, body))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (e *endpoint) counts() (requests, batches int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.requests), len(e.batches)
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWriter_RetriesDuringOutage(t *testing.T) {
+	ep := &endpoint{down: true, status: http.StatusServiceUnavailable, t: t}
+	srv := httptest.NewServer(ep)
+	defer srv.Close()
+
+	w, err := New(newRegistry(), Options{
+		URL:            srv.URL + "/api/v1/write",
+		Username:       "site1",
+		Password:       "secret",
+		ExternalLabels: map[string]string{"site": "home"},
+		Interval:       time.Hour,
+		MinBackoff:     5 * time.Millisecond,
+		MaxBackoff:     20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+
+	// While the endpoint is down the batches queue up and are retried.
+	waitFor(t, "retries", func() bool { n, _ := ep.counts(); return n >= 3 })
+	if err := w.Collect(); err != nil {
+		t.Fatal(err)
+	}
+	if n := w.queue.len(); n != 2 {
+		t.Fatalf("expected two queued batches, got %d", n)
+	}
+	ep.mu.Lock()
+	ep.down = false
+	ep.mu.Unlock()
+	waitFor(t, "delivery", func() bool { _, n := ep.counts(); return n == 2 })
+	waitFor(t, "an empty queue", func() bool { return w.queue.len() == 0 })
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := ep.requests[0]
+	if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("Content-Type") != "application/x-protobuf" || r.Header.Get("X-Prometheus-Remote-Write-Version") != "0.1.0" {
+		t.Fatalf("unexpected headers %v", r.Header)
+	}
+	if user, pass, ok := r.BasicAuth(); !ok || user != "site1" || pass != "secret" {
+		t.Fatalf("expected basic auth, got %q %q", user, pass)
+	}
+	// A rotated password is sent from the next write on.
+	if !w.SetCredentials("rotated", "") || w.SetCredentials("rotated", "") {
+		t.Fatalf("expected SetCredentials to report only changed credentials")
+	}
+	if err := w.write(context.Background(), Encode(nil, nil, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, pass, _ := ep.requests[len(ep.requests)-1].BasicAuth(); pass != "rotated" {
+		t.Fatalf("expected the rotated password, got %q", pass)
+	}
+	if ep.batches[0][1].labels["site"] != "home" {
+		t.Fatalf("expected the external label, got %v", ep.batches[0][1].labels)
+	}
+	if ep.batches[0][0].timestamp > ep.batches[1][0].timestamp {
+		t.Fatalf("expected the batches in order")
+	}
+}
+
+func TestWriter_DropsRejectedBatches(t *testing.T) {
+	ep := &endpoint{down: true, status: http.StatusBadRequest, t: t}
+	srv := httptest.NewServer(ep)
+	defer srv.Close()
+	w, err := New(newRegistry(), Options{URL: srv.URL, BearerToken: "tok", Interval: time.Hour, MinBackoff: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+	waitFor(t, "the request", func() bool { n, _ := ep.counts(); return n == 1 })
+	waitFor(t, "the batch to be dropped", func() bool { return w.queue.len() == 0 })
+	cancel()
+	<-done
+	if got := ep.requests[0].Header.Get("Authorization"); got != "Bearer tok" {
+		t.Fatalf("expected the bearer token, got %q", got)
+	}
+
+	if _, err := New(nil, Options{URL: "prometheus:9090"}); err == nil {
+		t.Fatalf("expected an invalid URL to be rejected")
+	}
+}
+
+func TestQueue(t *testing.T) {
+	dir := t.TempDir()
+	q, err := newQueue(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"a", "b", "c"} {
+		if err := q.push([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b, _ := q.peek(); q.len() != 2 || string(b.data) != "b" {
+		t.Fatalf("expected the oldest batch to be dropped, got %q of %d", b.data, q.len())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Fatalf("expected two batch files, got %d", len(entries))
+	}
+
+	// A new queue on the same directory resumes with the stored batches.
+	q, err = newQueue(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := q.peek()
+	if q.len() != 2 || string(b.data) != "b" {
+		t.Fatalf("expected the stored batches, got %q of %d", b.data, q.len())
+	}
+	q.pop(b)
+	if b, _ := q.peek(); q.len() != 1 || string(b.data) != "c" {
+		t.Fatalf("unexpected queue after pop: %q", b.data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("expected the sent batch file to be removed, got %d files", len(entries))
+	}
+}
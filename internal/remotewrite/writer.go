@@ -0,0 +1,219 @@
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	writeTimeout = 30 * time.Second
+	userAgent    = "experia-v10-exporter"
+
+	defaultQueueSize  = 1440
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = time.Minute
+)
+
+// Options configures a Writer.
+type Options struct {
+	// URL is the remote_write endpoint, for example
+	// https://prometheus.example.com/api/v1/write.
+	URL string
+	// Username and Password enable basic auth; BearerToken sends an
+	// Authorization: Bearer header instead.
+	Username    string
+	Password    string
+	BearerToken string
+	// ExternalLabels are added to every series, for example site="home".
+	ExternalLabels map[string]string
+	Interval       time.Duration
+	// QueueSize is the maximum number of batches kept while the endpoint is
+	// unreachable; 0 keeps one day of batches at a one minute interval.
+	QueueSize int
+	// QueueDir keeps the queued batches on disk when set.
+	QueueDir string
+	// MinBackoff and MaxBackoff bound the delay between retries.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// recoverableError marks a failed write that is retried: a network error,
+// a 5xx or a 429 response.
+type recoverableError struct{ error }
+
+// Writer gathers on an interval and sends the batches to a remote_write
+// endpoint.
+type Writer struct {
+	opts     Options
+	gatherer prometheus.Gatherer
+	client   *http.Client
+	queue    *queue
+
+	mu sync.Mutex
+	// password and bearerToken are sent with every write; SetCredentials
+	// replaces them.
+	password    string
+	bearerToken string
+}
+
+// New returns a Writer that gathers from g, loading the batches left in
+// opts.QueueDir by a previous run.
+func New(g prometheus.Gatherer, opts Options) (*Writer, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("remote_write: %q is not an http or https URL", opts.URL)
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultQueueSize
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = defaultMinBackoff
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = max(defaultMaxBackoff, opts.MinBackoff)
+	}
+	q, err := newQueue(opts.QueueDir, opts.QueueSize)
+	if err != nil {
+		return nil, fmt.Errorf("remote_write queue: %w", err)
+	}
+	return &Writer{
+		opts:        opts,
+		gatherer:    g,
+		client:      &http.Client{Timeout: writeTimeout},
+		queue:       q,
+		password:    opts.Password,
+		bearerToken: opts.BearerToken,
+	}, nil
+}
+
+// SetCredentials replaces the basic auth password and the bearer token used
+// from the next write on, for example after remote_write.password_file or
+// bearer_token_file was rotated. It reports whether either changed.
+func (w *Writer) SetCredentials(password, bearerToken string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.password == password && w.bearerToken == bearerToken {
+		return false
+	}
+	w.password, w.bearerToken = password, bearerToken
+	return true
+}
+
+// Run collects right away and then every Interval until ctx is done, while
+// the queued batches are sent in the background.
+func (w *Writer) Run(ctx context.Context) error {
+	if w.opts.Interval <= 0 {
+		return fmt.Errorf("remote_write: interval must be positive, got %s", w.opts.Interval)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.send(ctx)
+	}()
+	t := time.NewTicker(w.opts.Interval)
+	defer t.Stop()
+	for {
+		if err := w.Collect(); err != nil {
+			log.Printf("ERROR: remote_write collection failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			<-done
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// Collect gathers once and queues the samples.
+func (w *Writer) Collect() error {
+	families, err := w.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return err
+	}
+	return w.queue.push(Encode(families, w.opts.ExternalLabels, time.Now().UnixMilli()))
+}
+
+// send drains the queue until ctx is done. Recoverable failures are retried
+// with exponential backoff; batches the endpoint rejects are dropped.
+func (w *Writer) send(ctx context.Context) {
+	backoff := w.opts.MinBackoff
+	for {
+		b, ok := w.queue.peek()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.queue.notify:
+			}
+			continue
+		}
+		err := w.write(ctx, b.data)
+		var rerr recoverableError
+		switch {
+		case err == nil:
+			w.queue.pop(b)
+			backoff = w.opts.MinBackoff
+			continue
+		case errors.As(err, &rerr):
+			log.Printf("warning: remote_write failed, retrying in %s (%d batches queued): %v", backoff, w.queue.len(), err)
+		default:
+			log.Printf("ERROR: remote_write rejected a batch, dropping it: %v", err)
+			w.queue.pop(b)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, w.opts.MaxBackoff)
+	}
+}
+
+// write sends one batch.
+func (w *Writer) write(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.opts.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	w.mu.Lock()
+	password, bearerToken := w.password, w.bearerToken
+	w.mu.Unlock()
+	switch {
+	case bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
+	case w.opts.Username != "":
+		req.SetBasicAuth(w.opts.Username, password)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return recoverableError{err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	if resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests {
+		return recoverableError{err}
+	}
+	return err
+}
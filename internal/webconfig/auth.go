@@ -0,0 +1,63 @@
+package webconfig
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyHash is compared against when the username is unknown so a failed
+// login takes as long whether or not the user exists.
+const dummyHash = "$2a$10$wFHNFastWYbBXd8udGLo8uX6fTy9/vY4V1wxhJ9uHbCi/0HoCh4TG"
+
+// Handler wraps next with the configured response headers and, when
+// basic_auth_users is set, basic authentication.
+func (c *Config) Handler(next http.Handler) http.Handler {
+	a := &authenticator{users: c.Users, ok: map[[sha256.Size]byte]bool{}}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, value := range c.HTTPServerConfig.Headers {
+			w.Header().Set(name, value)
+		}
+		if len(a.users) > 0 {
+			user, pass, ok := r.BasicAuth()
+			if !ok || !a.check(user, pass) {
+				w.Header().Set("WWW-Authenticate", "Basic")
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticator checks basic auth credentials. bcrypt is deliberately slow,
+// so accepted credentials are remembered to keep frequent scrapes cheap.
+type authenticator struct {
+	users map[string]string
+
+	mu sync.Mutex
+	ok map[[sha256.Size]byte]bool
+}
+
+func (a *authenticator) check(user, pass string) bool {
+	hash, known := a.users[user]
+	if !known {
+		hash = dummyHash
+	}
+	key := sha256.Sum256([]byte(user + "\x00" + hash + "\x00" + pass))
+	a.mu.Lock()
+	cached := a.ok[key]
+	a.mu.Unlock()
+	if cached {
+		return true
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(pass)) != nil || !known {
+		return false
+	}
+	a.mu.Lock()
+	a.ok[key] = true
+	a.mu.Unlock()
+	return true
+}
@@ -0,0 +1,188 @@
+package webconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// clientAuthTypes maps client_auth_type values to tls.ClientAuthType.
+// RequireClientCert is kept as an alias of RequireAnyClientCert like in the
+// exporter-toolkit.
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                           tls.NoClientCert,
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"RequireClientCert":          tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+var tlsVersions = map[string]uint16{
+	"TLS10": tls.VersionTLS10,
+	"TLS11": tls.VersionTLS11,
+	"TLS12": tls.VersionTLS12,
+	"TLS13": tls.VersionTLS13,
+}
+
+var curves = map[string]tls.CurveID{
+	"CurveP256": tls.CurveP256,
+	"CurveP384": tls.CurveP384,
+	"CurveP521": tls.CurveP521,
+	"X25519":    tls.X25519,
+}
+
+// Enabled reports whether the section configures TLS.
+func (t *TLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != "" || t.Cert != "" || t.Key != ""
+}
+
+// TLS returns the tls.Config for the tls_server_config section, or nil when
+// TLS is not enabled. Certificate files are re-read on every handshake so a
+// renewed certificate is served without a restart.
+func (c *Config) TLS() (*tls.Config, error) {
+	t := &c.TLSServerConfig
+	if !t.Enabled() {
+		if t.ClientCAFile != "" || t.ClientAuth != "" {
+			return nil, errors.New("tls_server_config: client authentication needs cert_file and key_file")
+		}
+		return nil, nil
+	}
+	switch {
+	case t.CertFile != "" && t.Cert != "":
+		return nil, errors.New("tls_server_config: cert and cert_file are mutually exclusive")
+	case t.KeyFile != "" && t.Key != "":
+		return nil, errors.New("tls_server_config: key and key_file are mutually exclusive")
+	case t.CertFile == "" && t.Cert == "":
+		return nil, errors.New("tls_server_config: missing cert_file")
+	case t.KeyFile == "" && t.Key == "":
+		return nil, errors.New("tls_server_config: missing key_file")
+	}
+	if _, err := t.certificate(); err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return t.certificate()
+		},
+	}
+	if t.MinVersion != "" {
+		v, ok := tlsVersions[t.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("tls_server_config.min_version: unknown TLS version %q", t.MinVersion)
+		}
+		cfg.MinVersion = v
+	}
+	if t.MaxVersion != "" {
+		v, ok := tlsVersions[t.MaxVersion]
+		if !ok {
+			return nil, fmt.Errorf("tls_server_config.max_version: unknown TLS version %q", t.MaxVersion)
+		}
+		if v < cfg.MinVersion {
+			return nil, errors.New("tls_server_config: max_version is lower than min_version")
+		}
+		cfg.MaxVersion = v
+	}
+	for _, name := range t.CipherSuites {
+		id, ok := cipherSuite(name)
+		if !ok {
+			return nil, fmt.Errorf("tls_server_config.cipher_suites: unknown cipher suite %q", name)
+		}
+		cfg.CipherSuites = append(cfg.CipherSuites, id)
+	}
+	for _, name := range t.CurvePreferences {
+		id, ok := curves[name]
+		if !ok {
+			return nil, fmt.Errorf("tls_server_config.curve_preferences: unknown curve %q", name)
+		}
+		cfg.CurvePreferences = append(cfg.CurvePreferences, id)
+	}
+
+	auth, ok := clientAuthTypes[t.ClientAuth]
+	if !ok {
+		return nil, fmt.Errorf("tls_server_config.client_auth_type: unknown type %q", t.ClientAuth)
+	}
+	cfg.ClientAuth = auth
+	if t.ClientCAFile != "" {
+		if auth == tls.NoClientCert {
+			return nil, errors.New("tls_server_config: client_ca_file is set without a client_auth_type")
+		}
+		pem, err := os.ReadFile(t.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls_server_config.client_ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls_server_config.client_ca_file: no certificates in %s", t.ClientCAFile)
+		}
+		cfg.ClientCAs = pool
+	}
+	if len(t.ClientAllowedSans) > 0 {
+		if t.ClientCAFile == "" {
+			return nil, errors.New("tls_server_config: client_allowed_sans needs client_ca_file")
+		}
+		cfg.VerifyPeerCertificate = t.verifySANs
+	}
+	return cfg, nil
+}
+
+// certificate loads the server certificate from the files or the inline
+// PEM values.
+func (t *TLSConfig) certificate() (*tls.Certificate, error) {
+	certPEM, keyPEM := []byte(t.Cert), []byte(t.Key)
+	var err error
+	if t.CertFile != "" {
+		if certPEM, err = os.ReadFile(t.CertFile); err != nil {
+			return nil, fmt.Errorf("tls_server_config.cert_file: %w", err)
+		}
+	}
+	if t.KeyFile != "" {
+		if keyPEM, err = os.ReadFile(t.KeyFile); err != nil {
+			return nil, fmt.Errorf("tls_server_config.key_file: %w", err)
+		}
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("tls_server_config: invalid certificate or key: %w", err)
+	}
+	return &cert, nil
+}
+
+// verifySANs accepts a verified client certificate only when one of its
+// DNS, IP, email or URI SANs is in client_allowed_sans.
+func (t *TLSConfig) verifySANs(_ [][]byte, chains [][]*x509.Certificate) error {
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return errors.New("no verified client certificate")
+	}
+	cert := chains[0][0]
+	sans := slices.Clone(cert.DNSNames)
+	sans = append(sans, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, u := range cert.URIs {
+		sans = append(sans, u.String())
+	}
+	for _, san := range sans {
+		if slices.Contains(t.ClientAllowedSans, san) {
+			return nil
+		}
+	}
+	return fmt.Errorf("client certificate SANs %v are not in client_allowed_sans", sans)
+}
+
+// cipherSuite returns the ID of the cipher suite with the given Go name.
+func cipherSuite(name string) (uint16, bool) {
+	for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		if s.Name == name {
+			return s.ID, true
+		}
+	}
+	return 0, false
+}
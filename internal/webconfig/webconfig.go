@@ -0,0 +1,191 @@
+// Package webconfig serves the exporter's HTTP endpoints with TLS and basic
+// authentication configured by a web configuration file in the Prometheus
+// exporter-toolkit format:
+//
+//	tls_server_config:
+//	  cert_file: server.crt
+//	  key_file: server.key
+//	  client_auth_type: RequireAndVerifyClientCert
+//	  client_ca_file: ca.crt
+//	basic_auth_users:
+//	  prometheus: $2y$10$...
+package webconfig
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Config is a web configuration file.
+type Config struct {
+	TLSServerConfig  TLSConfig  `yaml:"tls_server_config"`
+	HTTPServerConfig HTTPConfig `yaml:"http_server_config"`
+	// Users maps basic auth usernames to bcrypt password hashes. Empty
+	// disables basic auth.
+	Users map[string]string `yaml:"basic_auth_users"`
+}
+
+// TLSConfig is the tls_server_config section. TLS is enabled when a
+// certificate and key are set.
+type TLSConfig struct {
+	CertFile          string   `yaml:"cert_file"`
+	KeyFile           string   `yaml:"key_file"`
+	Cert              string   `yaml:"cert"`
+	Key               string   `yaml:"key"`
+	ClientAuth        string   `yaml:"client_auth_type"`
+	ClientCAFile      string   `yaml:"client_ca_file"`
+	ClientAllowedSans []string `yaml:"client_allowed_sans"`
+	MinVersion        string   `yaml:"min_version"`
+	MaxVersion        string   `yaml:"max_version"`
+	CipherSuites      []string `yaml:"cipher_suites"`
+	CurvePreferences  []string `yaml:"curve_preferences"`
+	// PreferServerCipherSuites is accepted for compatibility; Go orders
+	// cipher suites itself.
+	PreferServerCipherSuites *bool `yaml:"prefer_server_cipher_suites"`
+}
+
+// HTTPConfig is the http_server_config section.
+type HTTPConfig struct {
+	// HTTP2 enables HTTP/2 over TLS; nil means enabled.
+	HTTP2 *bool `yaml:"http2"`
+	// Headers are added to every response.
+	Headers map[string]string `yaml:"headers"`
+}
+
+// allowedHeaders are the response headers http_server_config.headers may
+// set, with the values each accepts (nil accepts any value).
+var allowedHeaders = map[string][]string{
+	"Strict-Transport-Security": nil,
+	"X-Content-Type-Options":    {"nosniff"},
+	"X-Frame-Options":           {"deny", "sameorigin"},
+	"X-XSS-Protection":          nil,
+	"Content-Security-Policy":   nil,
+}
+
+// Load reads and validates the web configuration file at path. Relative
+// certificate paths are resolved against the file's directory.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Parse(data, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Parse decodes and validates a web configuration document, resolving
+// relative file paths against dir. Unknown keys are an error.
+func Parse(data []byte, dir string) (*Config, error) {
+	cfg := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	t := &cfg.TLSServerConfig
+	for _, p := range []*string{&t.CertFile, &t.KeyFile, &t.ClientCAFile} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// validate reports the first invalid setting. It builds the TLS settings
+// once so certificate and CA problems surface at startup.
+func (c *Config) validate() error {
+	for user, hash := range c.Users {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return fmt.Errorf("basic_auth_users.%s: invalid bcrypt hash: %w", user, err)
+		}
+	}
+	for name, value := range c.HTTPServerConfig.Headers {
+		values, ok := allowedHeaders[http.CanonicalHeaderKey(name)]
+		if !ok {
+			return fmt.Errorf("http_server_config.headers: header %q is not allowed", name)
+		}
+		if values != nil && !containsFold(values, value) {
+			return fmt.Errorf("http_server_config.headers: invalid value %q for %s (allowed: %s)", value, name, strings.Join(values, ", "))
+		}
+	}
+	_, err := c.TLS()
+	return err
+}
+
+// Server returns an http.Server for addr that serves handler behind the
+// configured basic auth and headers, with TLSConfig set when TLS is enabled.
+func (c *Config) Server(addr string, handler http.Handler) (*http.Server, error) {
+	tlsCfg, err := c.TLS()
+	if err != nil {
+		return nil, err
+	}
+	srv := &http.Server{Addr: addr, Handler: c.Handler(handler), TLSConfig: tlsCfg}
+	if tlsCfg != nil && c.HTTPServerConfig.HTTP2 != nil && !*c.HTTPServerConfig.HTTP2 {
+		// A non-nil, empty TLSNextProto disables HTTP/2.
+		srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+	return srv, nil
+}
+
+// Serve accepts connections on l for srv, wrapping l in TLS when
+// srv.TLSConfig is set.
+func Serve(l net.Listener, srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		return srv.ServeTLS(l, "", "")
+	}
+	return srv.Serve(l)
+}
+
+// ListenAndServe serves handler on addr using the web configuration file at
+// path. An empty path serves plain HTTP without authentication.
+func ListenAndServe(addr string, handler http.Handler, path string) error {
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+	cfg := &Config{}
+	if path != "" {
+		var err error
+		if cfg, err = Load(path); err != nil {
+			return err
+		}
+	}
+	srv, err := cfg.Server(addr, handler)
+	if err != nil {
+		return err
+	}
+	if addr == "" {
+		addr = ":http"
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return Serve(l, srv)
+}
+
+// containsFold reports whether values contains s, ignoring case.
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
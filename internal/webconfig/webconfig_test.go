@@ -0,0 +1,307 @@
+package webconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testPKI is a CA with a server certificate for 127.0.0.1 and a client
+// certificate, written as PEM files to dir.
+type testPKI struct {
+	dir                   string
+	caPool                *x509.CertPool
+	ca                    *x509.Certificate
+	caKey                 *ecdsa.PrivateKey
+	clientCert, otherCert tls.Certificate
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	p := &testPKI{dir: t.TempDir(), caPool: x509.NewCertPool()}
+	p.caKey = newKey(t)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &p.caKey.PublicKey, p.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ca, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	p.caPool.AddCert(p.ca)
+	p.write(t, "ca.crt", "CERTIFICATE", der)
+
+	server := p.issue(t, 2, &x509.Certificate{IPAddresses: []net.IP{net.ParseIP("127.0.0.1")}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}})
+	p.write(t, "server.crt", "CERTIFICATE", server.Certificate[0])
+	keyDER, err := x509.MarshalECPrivateKey(server.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.write(t, "server.key", "EC PRIVATE KEY", keyDER)
+
+	p.clientCert = p.issue(t, 3, &x509.Certificate{DNSNames: []string{"prometheus"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	p.otherCert = p.issue(t, 4, &x509.Certificate{DNSNames: []string{"intruder"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+	return p
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// issue signs tmpl with the CA.
+func (p *testPKI) issue(t *testing.T, serial int64, tmpl *x509.Certificate) tls.Certificate {
+	t.Helper()
+	key := newKey(t)
+	tmpl.SerialNumber = big.NewInt(serial)
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, p.ca, &key.PublicKey, p.caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func (p *testPKI) write(t *testing.T, name, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(p.dir, name), pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serve starts a server for the web configuration doc, with relative paths
+// resolved against the PKI directory, and returns its base URL.
+func serve(t *testing.T, p *testPKI, doc string) string {
+	t.Helper()
+	cfg, err := Parse([]byte(doc), p.dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	srv, err := cfg.Server("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("experia_v10_up 1\n"))
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheme := "http"
+	if srv.TLSConfig != nil {
+		scheme = "https"
+	}
+	go func() { _ = Serve(l, srv) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return scheme + "://" + l.Addr().String() + "/metrics"
+}
+
+// get requests url trusting the test CA and presenting certs.
+func get(p *testPKI, url string, certs ...tls.Certificate) (*http.Response, error) {
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: p.caPool, Certificates: certs}}}
+	return client.Get(url)
+}
+
+func TestServe_TLS(t *testing.T) {
+	p := newTestPKI(t)
+	url := serve(t, p, "tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n")
+	if !strings.HasPrefix(url, "https://") {
+		t.Fatalf("expected TLS to be enabled, got %s", url)
+	}
+	resp, err := get(p, url)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.TLS == nil || resp.TLS.Version != tls.VersionTLS13 {
+		t.Fatalf("expected a TLS 1.3 response, got %d %+v", resp.StatusCode, resp.TLS)
+	}
+	insecure := &http.Client{}
+	if _, err := insecure.Get(url); err == nil {
+		t.Fatalf("expected a client without the CA to reject the certificate")
+	}
+}
+
+func TestServe_MutualTLS(t *testing.T) {
+	p := newTestPKI(t)
+	url := serve(t, p, `
+tls_server_config:
+  cert_file: server.crt
+  key_file: server.key
+  client_auth_type: RequireAndVerifyClientCert
+  client_ca_file: ca.crt
+`)
+	if _, err := get(p, url); err == nil {
+		t.Fatalf("expected a request without a client certificate to fail")
+	}
+	resp, err := get(p, url, p.clientCert)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	// A certificate from another CA is rejected.
+	other := newTestPKI(t)
+	if _, err := get(p, url, other.clientCert); err == nil {
+		t.Fatalf("expected a certificate from an unknown CA to fail")
+	}
+}
+
+func TestServe_ClientAllowedSans(t *testing.T) {
+	p := newTestPKI(t)
+	url := serve(t, p, `
+tls_server_config:
+  cert_file: server.crt
+  key_file: server.key
+  client_auth_type: RequireAndVerifyClientCert
+  client_ca_file: ca.crt
+  client_allowed_sans: [prometheus]
+`)
+	resp, err := get(p, url, p.clientCert)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if _, err := get(p, url, p.otherCert); err == nil {
+		t.Fatalf("expected a certificate without an allowed SAN to fail")
+	}
+}
+
+func TestServe_BasicAuth(t *testing.T) {
+	p := newTestPKI(t)
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := serve(t, p, "basic_auth_users:\n  prometheus: "+string(hash)+"\nhttp_server_config:\n  headers:\n    X-Frame-Options: deny\n")
+	request := func(user, pass string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		if user != "" {
+			req.SetBasicAuth(user, pass)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_ = resp.Body.Close()
+		return resp
+	}
+	resp := request("", "")
+	if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") != "Basic" {
+		t.Fatalf("expected a basic auth challenge, got %d %v", resp.StatusCode, resp.Header)
+	}
+	for _, c := range [][2]string{{"prometheus", "wrong"}, {"nobody", "s3cret"}} {
+		if resp := request(c[0], c[1]); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected %s/%s to be rejected, got %d", c[0], c[1], resp.StatusCode)
+		}
+	}
+	// The second request is answered from the cache of accepted credentials.
+	for range 2 {
+		resp := request("prometheus", "s3cret")
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Frame-Options") != "deny" {
+			t.Fatalf("expected 200 with the configured header, got %d %v", resp.StatusCode, resp.Header)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	p := newTestPKI(t)
+	cases := map[string]string{
+		"unknown key":          "tls_server_config:\n  certfile: server.crt\n",
+		"missing key":          "tls_server_config:\n  cert_file: server.crt\n",
+		"missing cert":         "tls_server_config:\n  key_file: server.key\n",
+		"missing cert file":    "tls_server_config:\n  cert_file: none.crt\n  key_file: server.key\n",
+		"ca without auth type": "tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n  client_ca_file: ca.crt\n",
+		"ca without tls":       "tls_server_config:\n  client_ca_file: ca.crt\n",
+		"auth type":            "tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n  client_auth_type: Sometimes\n",
+		"sans without ca":      "tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n  client_allowed_sans: [a]\n",
+		"version":              "tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n  min_version: TLS14\n",
+		"max below min":        "tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n  min_version: TLS13\n  max_version: TLS12\n",
+		"cipher suite":         "tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n  cipher_suites: [TLS_NULL]\n",
+		"curve":                "tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n  curve_preferences: [P999]\n",
+		"bcrypt hash":          "basic_auth_users:\n  prometheus: plaintext\n",
+		"header":               "http_server_config:\n  headers:\n    Authorization: x\n",
+		"header value":         "http_server_config:\n  headers:\n    X-Content-Type-Options: sniff\n",
+	}
+	for name, doc := range cases {
+		if _, err := Parse([]byte(doc), p.dir); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	cfg, err := Parse([]byte(`
+tls_server_config:
+  cert_file: server.crt
+  key_file: server.key
+  min_version: TLS12
+  max_version: TLS13
+  cipher_suites: [TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256]
+  curve_preferences: [X25519, CurveP256]
+  prefer_server_cipher_suites: true
+http_server_config:
+  http2: false
+`), p.dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	srv, err := cfg.Server(":0", http.NotFoundHandler())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.TLSConfig.MinVersion != tls.VersionTLS12 || len(srv.TLSConfig.CipherSuites) != 1 || len(srv.TLSConfig.CurvePreferences) != 2 || srv.TLSNextProto == nil {
+		t.Fatalf("unexpected server settings: %+v", srv.TLSConfig)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	p := newTestPKI(t)
+	path := filepath.Join(p.dir, "web.yml")
+	if err := os.WriteFile(path, []byte("tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("expected relative paths to resolve against the file, got %v", err)
+	}
+	if cfg.TLSServerConfig.CertFile != filepath.Join(p.dir, "server.crt") {
+		t.Fatalf("unexpected cert path %q", cfg.TLSServerConfig.CertFile)
+	}
+	if err := os.WriteFile(path, []byte("tls_server_config:\n  cert_file: server.crt\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Fatalf("expected an error naming the file, got %v", err)
+	}
+}